@@ -30,6 +30,7 @@ type Config struct {
 	Mix         map[string]int `mapstructure:"mix"` // op -> percentage
 	Duration    time.Duration  `mapstructure:"duration"`
 	Operations  int64          `mapstructure:"operations"`
+	Seed        int64          `mapstructure:"seed"` // master seed of all random choices; 0 picks one
 
 	// Object Configuration
 	Size        string `mapstructure:"size"` // "fixed:1MiB", "dist:lognormal:mean=1MiB,std=0.6"
@@ -37,6 +38,15 @@ type Config struct {
 	Prefix      string `mapstructure:"prefix"`
 	KeyTemplate string `mapstructure:"key_template"`
 	RandomKeys  bool   `mapstructure:"random_keys"`
+	RunID       string `mapstructure:"run_id"` // fills {run} in key templates; generated when empty
+
+	Sizes map[string]string `mapstructure:"sizes"` // op -> size spec, overriding Size for that op
+
+	StorageClass string `mapstructure:"storage_class"` // "STANDARD" or weighted "STANDARD=70,COLD=30"
+
+	// Range GET Configuration
+	RangeSize   string `mapstructure:"range_size"`   // size spec, e.g. "fixed:64KiB"
+	RangeOffset string `mapstructure:"range_offset"` // "head", "tail", "random", "aligned"
 
 	// Multipart Upload Configuration
 	MultipartEnabled   bool   `mapstructure:"multipart_enabled"`
@@ -44,9 +54,26 @@ type Config struct {
 	MultipartPartSize  int64  `mapstructure:"multipart_part_size"` // Size of each part (bytes)
 	MultipartMaxParts  int    `mapstructure:"multipart_max_parts"` // Maximum number of parts to upload concurrently
 
+	// Server-Side Encryption
+	SSE         string `mapstructure:"sse"`            // "", "AES256", "aws:kms", "sse-c"
+	SSEKMSKeyID string `mapstructure:"sse_kms_key_id"` // KMS key for aws:kms; required with it
+	SSECKeys    string `mapstructure:"sse_c_keys"`     // "per-run", "per-key"
+	SSECSecret  string `mapstructure:"sse_c_secret"`   // secret SSE-C keys are derived from
+
+	// Checksums
+	ChecksumAlgorithm string `mapstructure:"checksum_algorithm"` // "", "CRC32C", "CRC64NVME", "SHA1", "SHA256"
+	ChecksumType      string `mapstructure:"checksum_type"`      // multipart: "composite", "full_object", or "" for the algorithm default
+	ChecksumValidate  bool   `mapstructure:"checksum_validate"`  // request checksums on GET and validate them
+
 	// Data Pattern & Verification
 	Pattern    string  `mapstructure:"pattern"`     // "random:42", "fixed:DEADBEEF"
 	VerifyRate float64 `mapstructure:"verify_rate"` // 0.0 - 1.0
+	VerifyMode string  `mapstructure:"verify_mode"` // "metadata", "regenerate", "both"
+
+	// Consistency Checking
+	ConsistencyCheck bool   `mapstructure:"consistency_check"` // check reads and lists against acknowledged writes
+	HistoryFile      string `mapstructure:"history_file"`      // per-key operation history for linearizability checking
+	AnalyzeHistory   string `mapstructure:"analyze_history"`   // history file to check offline
 
 	// Rate Limiting
 	RateType  string  `mapstructure:"rate_type"`  // "fixed", "poisson"
@@ -58,7 +85,26 @@ type Config struct {
 	RetryBackoff time.Duration `mapstructure:"retry_backoff"`
 
 	// Copy Operation
-	CopyDstBucket string `mapstructure:"copy_dst_bucket"`
+	CopyDstBucket        string `mapstructure:"copy_dst_bucket"`
+	MultipartCopySources int    `mapstructure:"multipart_copy_sources"` // objects composed per multipart_copy
+
+	// List Operation
+	ListPageSize     int    `mapstructure:"list_page_size"`     // keys per ListObjectsV2 page (1-1000)
+	ListMode         string `mapstructure:"list_mode"`          // "page" or "full"
+	ListDelimiter    string `mapstructure:"list_delimiter"`     // e.g. "/" for directory-style listings
+	ListRandomPrefix bool   `mapstructure:"list_random_prefix"` // list under a sub-prefix of a random key
+	ListStartAfter   string `mapstructure:"list_start_after"`   // "", "random", or a key
+
+	// Tagging & Metadata Operations
+	Tags              string `mapstructure:"tags"`                // tags set at PUT time, "k=v,k2=v2"
+	TagCount          int    `mapstructure:"tag_count"`           // tags per put_tagging
+	TagValueSize      int    `mapstructure:"tag_value_size"`      // characters per tag value
+	MetadataCount     int    `mapstructure:"metadata_count"`      // metadata entries per copy_replace_metadata
+	MetadataValueSize int    `mapstructure:"metadata_value_size"` // characters per metadata value
+
+	// Batch Delete Operation
+	MultiDeleteBatch int  `mapstructure:"multi_delete_batch"` // keys per DeleteObjects request (max 1000)
+	MultiDeleteQuiet bool `mapstructure:"multi_delete_quiet"` // only report failures in the response
 
 	// Safety & Cleanup
 	NamespaceTag string `mapstructure:"namespace_tag"`
@@ -66,11 +112,38 @@ type Config struct {
 	Cleanup      bool   `mapstructure:"cleanup"`
 	DryRun       bool   `mapstructure:"dry_run"`
 
+	CleanupMatch      string        `mapstructure:"cleanup_match"`       // "metadata", "manifest", "template"
+	CleanupVerifyRate float64       `mapstructure:"cleanup_verify_rate"` // fraction of matched keys checked with HEAD
+	CleanupCheckpoint string        `mapstructure:"cleanup_checkpoint"`  // file recording the last cleaned key
+	CleanupVersions   bool          `mapstructure:"cleanup_versions"`    // delete every version and delete marker
+	CleanupUploadAge  time.Duration `mapstructure:"cleanup_upload_age"`  // abort incomplete multipart uploads older than this
+
+	// Durability Audit
+	ManifestFile string `mapstructure:"manifest_file"` // JSONL log of successful writes and deletes
+	Audit        bool   `mapstructure:"audit"`
+	AuditVerify  string `mapstructure:"audit_verify"` // "get", "head"
+
+	// Trace Recording & Replay
+	RecordTrace   string  `mapstructure:"record_trace"`   // gzipped CSV trace of every issued operation
+	Replay        string  `mapstructure:"replay"`         // trace file to replay instead of the generated workload
+	ReplayFormat  string  `mapstructure:"replay_format"`  // "auto", "csv", "rgw", "s3log"
+	ReplaySpeed   float64 `mapstructure:"replay_speed"`   // 2 replays twice as fast
+	ReplayKeys    string  `mapstructure:"replay_keys"`    // "prefix" or "hash"
+	ReplayPrepare bool    `mapstructure:"replay_prepare"` // create objects read before the trace writes them
+
 	// Observability
 	MetricsPort int    `mapstructure:"metrics_port"`
 	HTTPBind    string `mapstructure:"http_bind"`
 	LogLevel    string `mapstructure:"log_level"`
 	PprofPort   int    `mapstructure:"pprof_port"`
+	SizeClasses string `mapstructure:"size_classes"` // upper bounds of size classes, e.g. "64KiB,1MiB"
+
+	TimeseriesFile     string        `mapstructure:"timeseries_file"`     // per-interval results
+	TimeseriesInterval time.Duration `mapstructure:"timeseries_interval"` // length of each interval
+	TimeseriesFormat   string        `mapstructure:"timeseries_format"`   // "auto", "csv", "jsonl"
+
+	Progress         bool          `mapstructure:"progress"`          // live panel on a terminal, log lines otherwise
+	ProgressInterval time.Duration `mapstructure:"progress_interval"` // between progress updates
 
 	// Internal
 	ConfigFile string `mapstructure:"config"`
@@ -88,6 +161,7 @@ func NewConfig() *Config {
 		Mix:         map[string]int{"put": 50, "get": 50},
 		Duration:    10 * time.Minute,
 		Operations:  0, // unlimited unless set
+		Seed:        0, // random unless set
 
 		Size:        "fixed:1MiB",
 		Keys:        10000,
@@ -95,13 +169,21 @@ func NewConfig() *Config {
 		KeyTemplate: "obj-{seq:08}.bin",
 		RandomKeys:  false,
 
+		RangeSize:   "fixed:64KiB",
+		RangeOffset: "random",
+
 		MultipartEnabled:   false,
 		MultipartThreshold: 100 * 1024 * 1024, // 100 MiB
 		MultipartPartSize:  10 * 1024 * 1024,  // 10 MiB (minimum is 5 MiB)
 		MultipartMaxParts:  4,                  // Concurrent part uploads
 
+		SSE:        "",
+		SSECKeys:   "per-run",
+		SSECSecret: "s3bench",
+
 		Pattern:    "random:42",
 		VerifyRate: 0.1,
+		VerifyMode: "metadata",
 
 		RateType:  "fixed",
 		RateLimit: 0, // unlimited
@@ -110,14 +192,43 @@ func NewConfig() *Config {
 		MaxRetries:   3,
 		RetryBackoff: 100 * time.Millisecond,
 
+		MultipartCopySources: 1,
+
+		ListPageSize: 1000,
+		ListMode:     "page",
+
+		TagCount:          5,
+		TagValueSize:      16,
+		MetadataCount:     8,
+		MetadataValueSize: 32,
+
+		MultiDeleteBatch: 100,
+		MultiDeleteQuiet: false,
+
 		KeepData: false,
 		Cleanup:  false,
 		DryRun:   false,
 
+		CleanupMatch:      "metadata",
+		CleanupVerifyRate: 0.01,
+		CleanupUploadAge:  24 * time.Hour,
+
+		AuditVerify: "get",
+
+		ReplayFormat: "auto",
+		ReplaySpeed:  1.0,
+		ReplayKeys:   "prefix",
+
 		MetricsPort: 9090,
 		HTTPBind:    "0.0.0.0",
 		LogLevel:    "info",
 		PprofPort:   0, // disabled
+		SizeClasses: "64KiB,1MiB,16MiB,128MiB",
+
+		TimeseriesInterval: 10 * time.Second,
+		TimeseriesFormat:   "auto",
+
+		ProgressInterval: time.Second,
 	}
 }
 
@@ -141,13 +252,21 @@ func (c *Config) BindFlags(flags *pflag.FlagSet) error {
 	flags.StringToInt("mix", c.Mix, "Operation mix (e.g., put=40,get=40,delete=10,copy=5,list=5)")
 	flags.Duration("duration", c.Duration, "Workload duration (0 for unlimited)")
 	flags.Int64("operations", c.Operations, "Total operations (0 for unlimited)")
+	flags.Int64("seed", c.Seed, "Master seed for reproducible runs (0 picks a random seed)")
 
 	// Object Configuration
 	flags.String("size", c.Size, "Object size: fixed:1MiB or dist:lognormal:mean=1MiB,std=0.6")
+	flags.StringToString("sizes", c.Sizes, "Per-operation object sizes for put, multipart_put and overwrite (e.g., put=fixed:4KiB,multipart_put=fixed:1GiB)")
 	flags.Int("keys", c.Keys, "Number of unique keys in keyspace")
 	flags.String("prefix", c.Prefix, "Key prefix")
-	flags.String("key-template", c.KeyTemplate, "Key template with {seq} placeholder")
+	flags.String("key-template", c.KeyTemplate, "Key template with placeholders: {seq}, {seq:NN}, {hash:N}, {mod:N}, {worker}, {rand:N}, {date:LAYOUT}, {run}")
+	flags.String("run-id", c.RunID, "Run ID for {run} in key templates (default: start time, e.g. 20060102-150405)")
 	flags.Bool("random-keys", c.RandomKeys, "Use random key selection")
+	flags.String("storage-class", c.StorageClass, "Storage class for PUT, multipart and COPY, or a weighted mix (e.g., STANDARD=70,COLD=30)")
+
+	// Range GET Configuration
+	flags.String("range-size", c.RangeSize, "Range GET length: fixed:64KiB or any size distribution")
+	flags.String("range-offset", c.RangeOffset, "Range GET offset policy: head, tail, random, aligned")
 
 	// Multipart Upload Configuration
 	flags.Bool("multipart-enabled", c.MultipartEnabled, "Enable multipart upload for large objects")
@@ -155,9 +274,26 @@ func (c *Config) BindFlags(flags *pflag.FlagSet) error {
 	flags.Int64("multipart-part-size", c.MultipartPartSize, "Size of each multipart upload part (bytes, min 5MiB)")
 	flags.Int("multipart-max-parts", c.MultipartMaxParts, "Maximum concurrent part uploads")
 
+	// Server-Side Encryption
+	flags.String("sse", c.SSE, "Server-side encryption: AES256, aws:kms, or sse-c (default none)")
+	flags.String("sse-kms-key-id", c.SSEKMSKeyID, "KMS key ID for aws:kms (required with it)")
+	flags.String("sse-c-keys", c.SSECKeys, "SSE-C key derivation: per-run or per-key")
+	flags.String("sse-c-secret", c.SSECSecret, "Secret SSE-C customer keys are derived from")
+
+	// Checksums
+	flags.String("checksum-algorithm", c.ChecksumAlgorithm, "Checksum sent with PUTs and parts: CRC32C, CRC64NVME, SHA1, or SHA256 (default none)")
+	flags.String("checksum-type", c.ChecksumType, "Multipart checksum type: composite or full_object (default full_object for CRC64NVME, else composite)")
+	flags.Bool("checksum-validate", c.ChecksumValidate, "Request checksums on GET and validate them")
+
 	// Data Pattern & Verification
 	flags.String("pattern", c.Pattern, "Data pattern: random:<seed> or fixed:<hex>")
 	flags.Float64("verify-rate", c.VerifyRate, "Fraction of GETs to verify (0.0-1.0)")
+	flags.String("verify-mode", c.VerifyMode, "Verify against: metadata, regenerate, or both")
+
+	// Consistency Checking
+	flags.Bool("consistency-check", c.ConsistencyCheck, "Check GETs and LISTs against acknowledged writes")
+	flags.String("history-file", c.HistoryFile, "Record every operation's invoke/complete time and value hash to this file")
+	flags.String("analyze-history", c.AnalyzeHistory, "Analyze mode: check a recorded history for linearizability and exit")
 
 	// Rate Limiting
 	flags.String("rate-type", c.RateType, "Rate limiter type: fixed or poisson")
@@ -170,18 +306,61 @@ func (c *Config) BindFlags(flags *pflag.FlagSet) error {
 
 	// Copy Operation
 	flags.String("copy-dst-bucket", c.CopyDstBucket, "Destination bucket for COPY operations")
+	flags.Int("multipart-copy-sources", c.MultipartCopySources, "Source objects composed into each multipart_copy destination (1-10)")
+
+	// List Operation
+	flags.Int("list-page-size", c.ListPageSize, "Keys per LIST page (1-1000)")
+	flags.String("list-mode", c.ListMode, "LIST mode: page (one page) or full (follow continuation tokens to the end)")
+	flags.String("list-delimiter", c.ListDelimiter, "Delimiter for directory-style LIST (e.g., /)")
+	flags.Bool("list-random-prefix", c.ListRandomPrefix, "LIST under a random sub-prefix of a key in the keyspace")
+	flags.String("list-start-after", c.ListStartAfter, "Start LIST after this key, or 'random' for a random key in the keyspace")
+
+	// Tagging & Metadata Operations
+	flags.String("tags", c.Tags, "Tags to set on every PUT (e.g., team=storage,cost-center=42)")
+	flags.Int("tag-count", c.TagCount, "Tags per put_tagging (1-9, plus a checksum tag)")
+	flags.Int("tag-value-size", c.TagValueSize, "Characters per generated tag value (1-256)")
+	flags.Int("metadata-count", c.MetadataCount, "Metadata entries per copy_replace_metadata")
+	flags.Int("metadata-value-size", c.MetadataValueSize, "Characters per generated metadata value")
+
+	// Batch Delete Operation
+	flags.Int("multi-delete-batch", c.MultiDeleteBatch, "Keys per multi_delete request (1-1000)")
+	flags.Bool("multi-delete-quiet", c.MultiDeleteQuiet, "Use quiet mode for multi_delete (response lists failures only)")
 
 	// Safety & Cleanup
 	flags.String("namespace-tag", c.NamespaceTag, "Namespace tag for object metadata (e.g., env=perf)")
 	flags.Bool("keep-data", c.KeepData, "Keep data (skip cleanup deletes)")
 	flags.Bool("cleanup", c.Cleanup, "Cleanup mode: delete only tool-created objects")
 	flags.Bool("dry-run", c.DryRun, "Dry run: print config and exit")
+	flags.String("cleanup-match", c.CleanupMatch, "How cleanup recognizes tool-created objects: metadata (HEAD every key), manifest, or template")
+	flags.Float64("cleanup-verify-rate", c.CleanupVerifyRate, "Fraction of manifest or template matches to confirm with a HEAD (0.0-1.0)")
+	flags.String("cleanup-checkpoint", c.CleanupCheckpoint, "Checkpoint file to resume an interrupted cleanup")
+	flags.Bool("cleanup-versions", c.CleanupVersions, "Delete all versions and delete markers (implied by --versioning on)")
+	flags.Duration("cleanup-upload-age", c.CleanupUploadAge, "Abort incomplete multipart uploads older than this during cleanup (0 disables)")
+
+	// Durability Audit
+	flags.String("manifest-file", c.ManifestFile, "Append every successful write and delete to this manifest file")
+	flags.Bool("audit", c.Audit, "Audit mode: verify every object recorded in the manifest")
+	flags.String("audit-verify", c.AuditVerify, "Audit check per live object: get (full hash) or head (size only)")
+
+	// Trace Recording & Replay
+	flags.String("record-trace", c.RecordTrace, "Record every issued operation to this gzipped CSV trace, replayable with --replay")
+	flags.String("replay", c.Replay, "Replay mode: issue the operations of this trace file instead of the generated workload")
+	flags.String("replay-format", c.ReplayFormat, "Trace format: auto, csv, rgw (RGW ops log) or s3log (S3 server access log)")
+	flags.Float64("replay-speed", c.ReplaySpeed, "Replay time scale (2 replays twice as fast)")
+	flags.String("replay-keys", c.ReplayKeys, "Map traced keys under --prefix: prefix (keep names) or hash (hashed names)")
+	flags.Bool("replay-prepare", c.ReplayPrepare, "Before replaying, create objects the trace reads before writing them")
 
 	// Observability
 	flags.Int("metrics-port", c.MetricsPort, "Prometheus metrics port")
 	flags.String("http-bind", c.HTTPBind, "HTTP bind address")
 	flags.String("log-level", c.LogLevel, "Log level: debug, info, warn, error")
 	flags.Int("pprof-port", c.PprofPort, "Pprof port (0 to disable)")
+	flags.String("size-classes", c.SizeClasses, "Upper bounds of the size classes transfers are reported by (empty to disable)")
+	flags.String("timeseries-file", c.TimeseriesFile, "Write per-interval throughput, latency percentiles and errors to this file")
+	flags.Duration("timeseries-interval", c.TimeseriesInterval, "Length of each --timeseries-file interval")
+	flags.String("timeseries-format", c.TimeseriesFormat, "Time-series format: auto (by file extension), csv or jsonl")
+	flags.Bool("progress", c.Progress, "Show live throughput, latency and errors: a panel on a terminal, log lines otherwise")
+	flags.Duration("progress-interval", c.ProgressInterval, "Time between --progress updates")
 
 	// Config File
 	flags.String("config", c.ConfigFile, "Config file path")
@@ -240,11 +419,14 @@ func Load(flags *pflag.FlagSet) (*Config, error) {
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
-	if c.Endpoint == "" {
-		return fmt.Errorf("endpoint is required")
-	}
-	if c.Bucket == "" {
-		return fmt.Errorf("bucket is required")
+	// Offline history analysis needs no S3 connection
+	if c.AnalyzeHistory == "" {
+		if c.Endpoint == "" {
+			return fmt.Errorf("endpoint is required")
+		}
+		if c.Bucket == "" {
+			return fmt.Errorf("bucket is required")
+		}
 	}
 	if c.Concurrency < 1 {
 		return fmt.Errorf("concurrency must be >= 1")
@@ -252,6 +434,10 @@ func (c *Config) Validate() error {
 	if c.Keys < 1 {
 		return fmt.Errorf("keys must be >= 1")
 	}
+	runIDGenerated := c.RunID == ""
+	if runIDGenerated {
+		c.RunID = time.Now().UTC().Format("20060102-150405")
+	}
 	if c.VerifyRate < 0 || c.VerifyRate > 1 {
 		return fmt.Errorf("verify-rate must be between 0.0 and 1.0")
 	}
@@ -279,11 +465,185 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate per-operation sizes; the specs are parsed by the runner
+	for op, spec := range c.Sizes {
+		if op != "put" && op != "multipart_put" && op != "overwrite" {
+			return fmt.Errorf("sizes: operation %q does not write objects; use put, multipart_put or overwrite", op)
+		}
+		if spec == "" {
+			return fmt.Errorf("sizes: empty size for operation %s", op)
+		}
+	}
+
+	// Transitions pick their target from the storage class mix
+	if c.Mix["transition"] > 0 && c.StorageClass == "" {
+		return fmt.Errorf("transition operation requires --storage-class")
+	}
+
+	// Validate multipart copy. Its parts are cut at the multipart part size
+	// whether or not multipart uploads are enabled.
+	if c.MultipartCopySources < 1 || c.MultipartCopySources > 10 {
+		return fmt.Errorf("multipart-copy-sources must be between 1 and 10")
+	}
+	if c.Mix["multipart_copy"] > 0 && c.MultipartPartSize < 5*1024*1024 {
+		return fmt.Errorf("multipart_copy requires a multipart part size of at least 5 MiB, got %d bytes", c.MultipartPartSize)
+	}
+
+	// Validate list options
+	if c.ListPageSize < 1 || c.ListPageSize > 1000 {
+		return fmt.Errorf("list-page-size must be between 1 and 1000")
+	}
+	if c.ListMode != "page" && c.ListMode != "full" {
+		return fmt.Errorf("list-mode must be 'page' or 'full'")
+	}
+
 	// Validate versioning
 	if c.Versioning != "on" && c.Versioning != "off" && c.Versioning != "keep" {
 		return fmt.Errorf("versioning must be 'on', 'off', or 'keep'")
 	}
 
+	// Validate tagging and metadata sizes. S3 allows 10 tags per object and
+	// 2 KiB of user metadata, some of which the tool's own entries use.
+	if c.TagCount < 1 || c.TagCount > 9 {
+		return fmt.Errorf("tag-count must be between 1 and 9")
+	}
+	if c.TagValueSize < 1 || c.TagValueSize > 256 {
+		return fmt.Errorf("tag-value-size must be between 1 and 256")
+	}
+	if c.MetadataCount < 0 || c.MetadataValueSize < 1 {
+		return fmt.Errorf("metadata-count must not be negative and metadata-value-size must be positive")
+	}
+	if c.MetadataCount*(c.MetadataValueSize+len("bench-meta-00")) > 1536 {
+		return fmt.Errorf("metadata-count × metadata-value-size exceeds the 2 KiB user metadata limit")
+	}
+
+	// Validate batch delete size
+	if c.MultiDeleteBatch < 1 || c.MultiDeleteBatch > 1000 {
+		return fmt.Errorf("multi-delete-batch must be between 1 and 1000")
+	}
+
+	// Validate range offset policy
+	switch c.RangeOffset {
+	case "head", "tail", "random", "aligned":
+	default:
+		return fmt.Errorf("range-offset must be 'head', 'tail', 'random', or 'aligned'")
+	}
+
+	// Validate encryption
+	switch c.SSE {
+	case "", "AES256":
+	case "aws:kms":
+		if c.SSEKMSKeyID == "" {
+			return fmt.Errorf("sse aws:kms requires --sse-kms-key-id")
+		}
+	case "sse-c":
+		if c.SSECKeys != "per-run" && c.SSECKeys != "per-key" {
+			return fmt.Errorf("sse-c-keys must be 'per-run' or 'per-key'")
+		}
+		if c.SSECSecret == "" {
+			return fmt.Errorf("sse-c requires a non-empty sse-c-secret")
+		}
+	default:
+		return fmt.Errorf("sse must be 'AES256', 'aws:kms', or 'sse-c'")
+	}
+	if c.SSEKMSKeyID != "" && c.SSE != "aws:kms" {
+		return fmt.Errorf("sse-kms-key-id requires --sse aws:kms")
+	}
+
+	// Validate checksums
+	c.ChecksumAlgorithm = strings.ToUpper(c.ChecksumAlgorithm)
+	c.ChecksumType = strings.ToUpper(c.ChecksumType)
+	switch c.ChecksumAlgorithm {
+	case "", "CRC32C", "CRC64NVME", "SHA1", "SHA256":
+	default:
+		return fmt.Errorf("checksum-algorithm must be 'CRC32C', 'CRC64NVME', 'SHA1', or 'SHA256'")
+	}
+	switch c.ChecksumType {
+	case "":
+	case "COMPOSITE":
+		if c.ChecksumAlgorithm == "CRC64NVME" {
+			return fmt.Errorf("CRC64NVME only supports checksum-type 'full_object'")
+		}
+	case "FULL_OBJECT":
+		if c.ChecksumAlgorithm != "CRC32C" && c.ChecksumAlgorithm != "CRC64NVME" {
+			return fmt.Errorf("checksum-type 'full_object' requires a CRC checksum-algorithm")
+		}
+	default:
+		return fmt.Errorf("checksum-type must be 'composite' or 'full_object'")
+	}
+
+	// Validate verify mode
+	if c.VerifyMode != "metadata" && c.VerifyMode != "regenerate" && c.VerifyMode != "both" {
+		return fmt.Errorf("verify-mode must be 'metadata', 'regenerate', or 'both'")
+	}
+
+	// Validate cleanup configuration
+	switch c.CleanupMatch {
+	case "metadata":
+	case "template":
+		// Only {run} ties keys to this tool's runs; other templates can fit
+		// other tools' keys, so then every match must be confirmed
+		if !strings.Contains(c.KeyTemplate, "{run}") {
+			if c.CleanupVerifyRate < 1 {
+				return fmt.Errorf("cleanup-match 'template' requires {run} in key-template, or cleanup-verify-rate 1")
+			}
+		} else if c.Cleanup && runIDGenerated {
+			return fmt.Errorf("cleanup-match 'template' requires the run-id of the run to clean up")
+		}
+	case "manifest":
+		if c.ManifestFile == "" {
+			return fmt.Errorf("cleanup-match 'manifest' requires a manifest file")
+		}
+	default:
+		return fmt.Errorf("cleanup-match must be 'metadata', 'manifest', or 'template'")
+	}
+	if c.CleanupVerifyRate < 0 || c.CleanupVerifyRate > 1 {
+		return fmt.Errorf("cleanup-verify-rate must be between 0.0 and 1.0")
+	}
+	if c.CleanupUploadAge < 0 {
+		return fmt.Errorf("cleanup-upload-age must not be negative")
+	}
+
+	// Validate audit configuration
+	if c.Audit && c.ManifestFile == "" {
+		return fmt.Errorf("audit mode requires a manifest file")
+	}
+	if c.AuditVerify != "get" && c.AuditVerify != "head" {
+		return fmt.Errorf("audit-verify must be 'get' or 'head'")
+	}
+
+	// Validate trace replay
+	if c.Replay != "" {
+		switch c.ReplayFormat {
+		case "auto", "csv", "rgw", "s3log":
+		default:
+			return fmt.Errorf("replay-format must be 'auto', 'csv', 'rgw' or 's3log'")
+		}
+		if c.ReplaySpeed <= 0 {
+			return fmt.Errorf("replay-speed must be positive")
+		}
+		if c.ReplayKeys != "prefix" && c.ReplayKeys != "hash" {
+			return fmt.Errorf("replay-keys must be 'prefix' or 'hash'")
+		}
+	}
+
+	// Validate time-series export
+	if c.TimeseriesFile != "" {
+		if c.TimeseriesInterval <= 0 {
+			return fmt.Errorf("timeseries-interval must be positive")
+		}
+		switch c.TimeseriesFormat {
+		case "auto", "csv", "jsonl":
+		default:
+			return fmt.Errorf("timeseries-format must be 'auto', 'csv' or 'jsonl'")
+		}
+	}
+
+	// Validate progress display
+	if c.Progress && c.ProgressInterval <= 0 {
+		return fmt.Errorf("progress-interval must be positive")
+	}
+
 	// Validate log level
 	validLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
 	if !validLevels[c.LogLevel] {
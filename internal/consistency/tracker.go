@@ -0,0 +1,318 @@
+package consistency
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Anomaly is a class of consistency violation
+type Anomaly string
+
+const (
+	// AnomalyStaleRead is a GET returning data older than the latest acknowledged write
+	AnomalyStaleRead Anomaly = "stale_read"
+
+	// AnomalyLostWrite is an acknowledged write missing from a GET or LIST
+	AnomalyLostWrite Anomaly = "lost_write"
+
+	// AnomalyPhantomList is a LIST returning a key whose delete was acknowledged
+	AnomalyPhantomList Anomaly = "phantom_list_entry"
+
+	// AnomalyResurrectedDelete is a GET returning data for a key whose delete was acknowledged
+	AnomalyResurrectedDelete Anomaly = "resurrected_delete"
+)
+
+// defaultHistory is the number of completed writes kept per key
+const defaultHistory = 16
+
+// write is a single PUT or DELETE of a key
+type write struct {
+	id      uint64
+	hash    string // empty for deletes
+	deleted bool
+	start   time.Time
+	end     time.Time // zero while in flight
+	ok      bool      // acknowledged by the server
+}
+
+// done reports whether the write has completed, successfully or not
+func (w *write) done() bool {
+	return !w.end.IsZero()
+}
+
+type keyState struct {
+	writes []*write // in start order
+}
+
+// Tracker records the writes issued by the workload and checks reads and
+// listings against them. A read may return the value of the latest write
+// acknowledged before it started, or of any write that overlapped it or that
+// baseline write. Failed writes have an unknown outcome and stay acceptable
+// until a write started after them is acknowledged.
+type Tracker struct {
+	mu      sync.Mutex
+	keys    map[string]*keyState
+	nextID  uint64
+	history int
+	now     func() time.Time
+}
+
+// NewTracker creates a new consistency tracker
+func NewTracker() *Tracker {
+	return &Tracker{
+		keys:    make(map[string]*keyState),
+		history: defaultHistory,
+		now:     time.Now,
+	}
+}
+
+// Write is a handle on an in-flight write
+type Write struct {
+	tracker *Tracker
+	key     string
+	id      uint64
+}
+
+// BeginPut records the start of a PUT of data with the given hash
+func (t *Tracker) BeginPut(key, hash string) *Write {
+	return t.begin(key, hash, false)
+}
+
+// BeginDelete records the start of a DELETE
+func (t *Tracker) BeginDelete(key string) *Write {
+	return t.begin(key, "", true)
+}
+
+func (t *Tracker) begin(key, hash string, deleted bool) *Write {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.nextID++
+	ks, ok := t.keys[key]
+	if !ok {
+		ks = &keyState{}
+		t.keys[key] = ks
+	}
+	ks.writes = append(ks.writes, &write{
+		id:      t.nextID,
+		hash:    hash,
+		deleted: deleted,
+		start:   t.now(),
+	})
+
+	return &Write{tracker: t, key: key, id: t.nextID}
+}
+
+// Done records the completion of a write. ok is false when the server did
+// not acknowledge it.
+func (w *Write) Done(ok bool) {
+	t := w.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ks, exists := t.keys[w.key]
+	if !exists {
+		return
+	}
+	for _, wr := range ks.writes {
+		if wr.id == w.id {
+			wr.end = t.now()
+			wr.ok = ok
+			break
+		}
+	}
+	t.prune(ks)
+}
+
+// Invalidate forgets everything known about a key, for writes whose
+// content cannot be tracked such as server-side copies
+func (t *Tracker) Invalidate(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.keys, key)
+}
+
+// prune drops completed writes beyond the history limit, never dropping
+// in-flight writes
+func (t *Tracker) prune(ks *keyState) {
+	excess := len(ks.writes) - t.history
+	if excess <= 0 {
+		return
+	}
+
+	kept := ks.writes[:0]
+	for _, wr := range ks.writes {
+		if excess > 0 && wr.done() {
+			excess--
+			continue
+		}
+		kept = append(kept, wr)
+	}
+	ks.writes = kept
+}
+
+// Violation describes a detected anomaly
+type Violation struct {
+	Anomaly     Anomaly
+	Key         string
+	Observed    string // observed hash, empty if not found
+	Expected    string // hash of the baseline write, empty if deleted
+	BaselineAck time.Time
+	ReadStart   time.Time
+	ReadEnd     time.Time
+}
+
+// baseline returns the latest acknowledged write that ended before t
+func (ks *keyState) baseline(t time.Time) *write {
+	var base *write
+	for _, wr := range ks.writes {
+		if wr.ok && wr.end.Before(t) && (base == nil || wr.end.After(base.end)) {
+			base = wr
+		}
+	}
+	return base
+}
+
+// acceptable reports whether wr's value may be observed by an operation
+// spanning [start, end] given the baseline write base
+func (ks *keyState) acceptable(wr, base *write, start, end time.Time) bool {
+	if wr == base {
+		return true
+	}
+	if wr.start.After(end) {
+		return false
+	}
+
+	// Overlaps the read
+	if !wr.done() || !wr.end.Before(start) {
+		return true
+	}
+
+	// Overlaps the baseline, so may have been applied after it
+	if wr.ok && wr.end.After(base.start) {
+		return true
+	}
+
+	// Failed writes stay possible until a later write that started after
+	// them is acknowledged
+	if !wr.ok {
+		for _, other := range ks.writes {
+			if other.ok && other.start.After(wr.end) && other.end.Before(start) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// CheckRead checks a GET that ran over [start, end]. found is false when the
+// server returned not found; hash is the hash of the returned body.
+func (t *Tracker) CheckRead(key string, start, end time.Time, found bool, hash string) *Violation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ks, ok := t.keys[key]
+	if !ok {
+		return nil
+	}
+	base := ks.baseline(start)
+	if base == nil {
+		// Nothing acknowledged yet, so the pre-run state is also valid
+		return nil
+	}
+
+	for _, wr := range ks.writes {
+		if !ks.acceptable(wr, base, start, end) {
+			continue
+		}
+		if wr.deleted && !found {
+			return nil
+		}
+		if !wr.deleted && found && wr.hash == hash {
+			return nil
+		}
+	}
+
+	v := &Violation{
+		Key:         key,
+		Expected:    base.hash,
+		BaselineAck: base.end,
+		ReadStart:   start,
+		ReadEnd:     end,
+	}
+	if found {
+		v.Observed = hash
+	}
+
+	switch {
+	case !found:
+		v.Anomaly = AnomalyLostWrite
+	case base.deleted:
+		v.Anomaly = AnomalyResurrectedDelete
+	default:
+		v.Anomaly = AnomalyStaleRead
+	}
+
+	return v
+}
+
+// CheckList checks a complete listing of prefix that ran over [start, end]
+// and returned keys. Only keys under prefix are checked.
+func (t *Tracker) CheckList(prefix string, start, end time.Time, keys []string) []*Violation {
+	listed := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		listed[k] = true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var violations []*Violation
+	for key, ks := range t.keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		base := ks.baseline(start)
+		if base == nil {
+			continue
+		}
+
+		// Either state is possible while an ambiguous write is in play
+		ambiguous := false
+		for _, wr := range ks.writes {
+			if ks.acceptable(wr, base, start, end) && wr.deleted != base.deleted {
+				ambiguous = true
+				break
+			}
+		}
+		if ambiguous {
+			continue
+		}
+
+		if base.deleted && listed[key] {
+			violations = append(violations, &Violation{
+				Anomaly:     AnomalyPhantomList,
+				Key:         key,
+				BaselineAck: base.end,
+				ReadStart:   start,
+				ReadEnd:     end,
+			})
+		} else if !base.deleted && !listed[key] {
+			violations = append(violations, &Violation{
+				Anomaly:     AnomalyLostWrite,
+				Key:         key,
+				Expected:    base.hash,
+				BaselineAck: base.end,
+				ReadStart:   start,
+				ReadEnd:     end,
+			})
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,114 @@
+package consistency
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock returns a clock that advances one second per call
+func fakeClock() func() time.Time {
+	t := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		t = t.Add(time.Second)
+		return t
+	}
+}
+
+func newTestTracker() (*Tracker, func() time.Time) {
+	tr := NewTracker()
+	clock := fakeClock()
+	tr.now = clock
+	return tr, clock
+}
+
+func TestCheckRead(t *testing.T) {
+	tr, clock := newTestTracker()
+
+	tr.BeginPut("k", "v1").Done(true)
+	tr.BeginPut("k", "v2").Done(true)
+
+	start, end := clock(), clock()
+
+	if v := tr.CheckRead("k", start, end, true, "v2"); v != nil {
+		t.Errorf("latest value flagged as %s", v.Anomaly)
+	}
+
+	v := tr.CheckRead("k", start, end, true, "v1")
+	if v == nil || v.Anomaly != AnomalyStaleRead {
+		t.Errorf("old value: got %+v, want stale read", v)
+	}
+
+	v = tr.CheckRead("k", start, end, false, "")
+	if v == nil || v.Anomaly != AnomalyLostWrite {
+		t.Errorf("not found: got %+v, want lost write", v)
+	}
+
+	tr.BeginDelete("k").Done(true)
+	start, end = clock(), clock()
+
+	v = tr.CheckRead("k", start, end, true, "v2")
+	if v == nil || v.Anomaly != AnomalyResurrectedDelete {
+		t.Errorf("deleted key: got %+v, want resurrected delete", v)
+	}
+
+	if v := tr.CheckRead("k", start, end, false, ""); v != nil {
+		t.Errorf("deleted key not found flagged as %s", v.Anomaly)
+	}
+
+	if v := tr.CheckRead("unknown", start, end, true, "x"); v != nil {
+		t.Errorf("untracked key flagged as %s", v.Anomaly)
+	}
+}
+
+func TestCheckReadConcurrentWrite(t *testing.T) {
+	tr, clock := newTestTracker()
+
+	tr.BeginPut("k", "v1").Done(true)
+	inflight := tr.BeginPut("k", "v2")
+
+	start, end := clock(), clock()
+
+	for _, hash := range []string{"v1", "v2"} {
+		if v := tr.CheckRead("k", start, end, true, hash); v != nil {
+			t.Errorf("value %s during in-flight write flagged as %s", hash, v.Anomaly)
+		}
+	}
+
+	// A failed write may or may not have been applied
+	inflight.Done(false)
+	start, end = clock(), clock()
+	if v := tr.CheckRead("k", start, end, true, "v2"); v != nil {
+		t.Errorf("failed write value flagged as %s", v.Anomaly)
+	}
+
+	// Until a later write is acknowledged
+	tr.BeginPut("k", "v3").Done(true)
+	start, end = clock(), clock()
+	if v := tr.CheckRead("k", start, end, true, "v2"); v == nil {
+		t.Error("superseded failed write value should be stale")
+	}
+}
+
+func TestCheckList(t *testing.T) {
+	tr, clock := newTestTracker()
+
+	tr.BeginPut("p/a", "v1").Done(true)
+	tr.BeginPut("p/b", "v1").Done(true)
+	tr.BeginDelete("p/b").Done(true)
+	tr.BeginPut("other/c", "v1").Done(true)
+
+	start, end := clock(), clock()
+
+	if v := tr.CheckList("p/", start, end, []string{"p/a"}); len(v) != 0 {
+		t.Errorf("consistent listing flagged %d violations", len(v))
+	}
+
+	v := tr.CheckList("p/", start, end, []string{"p/b"})
+	counts := make(map[Anomaly]int)
+	for _, violation := range v {
+		counts[violation.Anomaly]++
+	}
+	if counts[AnomalyLostWrite] != 1 || counts[AnomalyPhantomList] != 1 || len(v) != 2 {
+		t.Errorf("violations = %v, want one lost write and one phantom entry", counts)
+	}
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+	"strings"
+)
+
+// Checksum algorithms S3 accepts in x-amz-checksum-* headers
+const (
+	ChecksumCRC32C    = "CRC32C"
+	ChecksumCRC64NVME = "CRC64NVME"
+	ChecksumSHA1      = "SHA1"
+	ChecksumSHA256    = "SHA256"
+)
+
+var (
+	crc32cTable    = crc32.MakeTable(crc32.Castagnoli)
+	crc64NVMETable = crc64.MakeTable(0x9a6c9329ac4bc9b5) // reflected CRC-64/NVME polynomial
+)
+
+// ParseChecksumAlgorithm parses a checksum algorithm name, ignoring case
+func ParseChecksumAlgorithm(s string) (string, error) {
+	switch alg := strings.ToUpper(s); alg {
+	case ChecksumCRC32C, ChecksumCRC64NVME, ChecksumSHA1, ChecksumSHA256:
+		return alg, nil
+	default:
+		return "", fmt.Errorf("unknown checksum algorithm: %s", s)
+	}
+}
+
+// IsCRCChecksum reports whether the algorithm is a CRC, which S3 can
+// combine into a full-object checksum for multipart uploads
+func IsCRCChecksum(algorithm string) bool {
+	return algorithm == ChecksumCRC32C || algorithm == ChecksumCRC64NVME
+}
+
+// NewChecksumHash returns a hash for the checksum algorithm
+func NewChecksumHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case ChecksumCRC32C:
+		return crc32.New(crc32cTable), nil
+	case ChecksumCRC64NVME:
+		return crc64.New(crc64NVMETable), nil
+	case ChecksumSHA1:
+		return sha1.New(), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown checksum algorithm: %s", algorithm)
+	}
+}
+
+// ComputeChecksum returns the base64 checksum of a reader, as sent in
+// x-amz-checksum-* headers
+func ComputeChecksum(r io.Reader, algorithm string) (string, error) {
+	h, err := NewChecksumHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to compute checksum: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// PartChecksums reads size bytes from r in a single pass and returns the
+// checksum of each partSize part along with the checksum of the whole object
+func PartChecksums(r io.Reader, algorithm string, size, partSize int64) ([]string, string, error) {
+	if partSize <= 0 {
+		return nil, "", fmt.Errorf("invalid part size: %d", partSize)
+	}
+
+	full, err := NewChecksumHash(algorithm)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var parts []string
+	for offset := int64(0); offset < size; offset += partSize {
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+
+		part, _ := NewChecksumHash(algorithm)
+		if _, err := io.CopyN(io.MultiWriter(part, full), r, length); err != nil {
+			return nil, "", fmt.Errorf("failed to checksum part %d: %w", len(parts)+1, err)
+		}
+		parts = append(parts, base64.StdEncoding.EncodeToString(part.Sum(nil)))
+	}
+
+	return parts, base64.StdEncoding.EncodeToString(full.Sum(nil)), nil
+}
+
+// CompositeChecksum returns the checksum S3 reports for a multipart upload
+// with composite checksums: the checksum of the concatenated binary part
+// checksums, suffixed with the part count
+func CompositeChecksum(algorithm string, parts []string) (string, error) {
+	h, err := NewChecksumHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	for i, part := range parts {
+		raw, err := base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			return "", fmt.Errorf("invalid checksum for part %d: %w", i+1, err)
+		}
+		h.Write(raw)
+	}
+	return fmt.Sprintf("%s-%d", base64.StdEncoding.EncodeToString(h.Sum(nil)), len(parts)), nil
+}
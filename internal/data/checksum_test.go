@@ -0,0 +1,100 @@
+package data
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestComputeChecksum(t *testing.T) {
+	// Standard check values over "123456789"
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{ChecksumCRC32C, "e3069283"},
+		{ChecksumCRC64NVME, "ae8b14860a799888"},
+		{ChecksumSHA1, "f7c3bc1d808e04732adf679965ccc34ca7ae3441"},
+		{ChecksumSHA256, "15e2b0d3c33891ebb0f1ef609ec419420c20e320ce94c65fbc8c3312448eb225"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			got, err := ComputeChecksum(strings.NewReader("123456789"), tt.algorithm)
+			if err != nil {
+				t.Fatalf("ComputeChecksum() failed: %v", err)
+			}
+			raw, err := base64.StdEncoding.DecodeString(got)
+			if err != nil {
+				t.Fatalf("checksum %q is not base64: %v", got, err)
+			}
+			if hex.EncodeToString(raw) != tt.want {
+				t.Errorf("ComputeChecksum() = %x, want %s", raw, tt.want)
+			}
+		})
+	}
+
+	if _, err := ComputeChecksum(strings.NewReader(""), "MD5"); err == nil {
+		t.Error("ComputeChecksum() with unknown algorithm succeeded")
+	}
+}
+
+func TestParseChecksumAlgorithm(t *testing.T) {
+	if got, err := ParseChecksumAlgorithm("crc64nvme"); err != nil || got != ChecksumCRC64NVME {
+		t.Errorf("ParseChecksumAlgorithm(crc64nvme) = %q, %v", got, err)
+	}
+	if _, err := ParseChecksumAlgorithm("crc32"); err == nil {
+		t.Error("ParseChecksumAlgorithm(crc32) succeeded")
+	}
+}
+
+func TestPartChecksums(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 25) // 250 bytes
+
+	parts, full, err := PartChecksums(bytes.NewReader(data), ChecksumCRC32C, int64(len(data)), 100)
+	if err != nil {
+		t.Fatalf("PartChecksums() failed: %v", err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("PartChecksums() returned %d parts, want 3", len(parts))
+	}
+
+	wantFull, _ := ComputeChecksum(bytes.NewReader(data), ChecksumCRC32C)
+	if full != wantFull {
+		t.Errorf("full checksum = %s, want %s", full, wantFull)
+	}
+	for i, part := range parts {
+		end := (i + 1) * 100
+		if end > len(data) {
+			end = len(data)
+		}
+		want, _ := ComputeChecksum(bytes.NewReader(data[i*100:end]), ChecksumCRC32C)
+		if part != want {
+			t.Errorf("part %d checksum = %s, want %s", i+1, part, want)
+		}
+	}
+
+	if _, _, err := PartChecksums(bytes.NewReader(data[:50]), ChecksumCRC32C, int64(len(data)), 100); err == nil {
+		t.Error("PartChecksums() on short reader succeeded")
+	}
+}
+
+func TestCompositeChecksum(t *testing.T) {
+	parts := []string{"AAAAAQ==", "AAAAAg=="}
+
+	got, err := CompositeChecksum(ChecksumCRC32C, parts)
+	if err != nil {
+		t.Fatalf("CompositeChecksum() failed: %v", err)
+	}
+
+	want, _ := ComputeChecksum(bytes.NewReader([]byte{0, 0, 0, 1, 0, 0, 0, 2}), ChecksumCRC32C)
+	if got != want+"-2" {
+		t.Errorf("CompositeChecksum() = %s, want %s-2", got, want)
+	}
+
+	if _, err := CompositeChecksum(ChecksumCRC32C, []string{"not base64!"}); err == nil {
+		t.Error("CompositeChecksum() with invalid part succeeded")
+	}
+}
@@ -0,0 +1,153 @@
+package data
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MetadataKeySegments records the layout of an object composed from
+	// byte ranges of other objects, so its data can be regenerated
+	MetadataKeySegments = "segments"
+
+	// MaxSegmentsLen bounds the encoded layout to leave room in the 2 KiB
+	// of user metadata for everything else
+	MaxSegmentsLen = 1024
+)
+
+// Segment is a byte range of the data generated for one object
+type Segment struct {
+	DataKey string // key the data was generated from
+	Size    int64  // size of the object the data was generated for
+	Offset  int64
+	Length  int64
+}
+
+// ObjectSegments returns the layout of an object's data: the recorded
+// segments if it was composed, or a single segment of the data generated
+// for its data key otherwise
+func ObjectSegments(key string, size int64, metadata map[string]string) ([]Segment, error) {
+	spec, ok := metadata[MetadataKeySegments]
+	if !ok {
+		return []Segment{{DataKey: DataKey(key, metadata), Size: size, Length: size}}, nil
+	}
+
+	segs, err := ParseSegments(spec)
+	if err != nil {
+		return nil, err
+	}
+	if total := SegmentsLength(segs); total != size {
+		return nil, fmt.Errorf("object size %d does not match composed length %d", size, total)
+	}
+	return segs, nil
+}
+
+// SegmentsLength returns the total length of segments
+func SegmentsLength(segs []Segment) int64 {
+	var total int64
+	for _, s := range segs {
+		total += s.Length
+	}
+	return total
+}
+
+// SliceSegments returns the segments covering length bytes at offset of
+// the concatenation of segs
+func SliceSegments(segs []Segment, offset, length int64) ([]Segment, error) {
+	if offset < 0 || length < 0 || offset+length > SegmentsLength(segs) {
+		return nil, fmt.Errorf("range %d+%d outside composed length %d", offset, length, SegmentsLength(segs))
+	}
+
+	var out []Segment
+	pos := int64(0)
+	for _, s := range segs {
+		if length == 0 {
+			break
+		}
+		if offset >= pos+s.Length {
+			pos += s.Length
+			continue
+		}
+
+		skip := offset - pos
+		n := s.Length - skip
+		if n > length {
+			n = length
+		}
+		out = AppendSegment(out, Segment{DataKey: s.DataKey, Size: s.Size, Offset: s.Offset + skip, Length: n})
+
+		offset += n
+		length -= n
+		pos += s.Length
+	}
+	return out, nil
+}
+
+// AppendSegment appends a segment, merging it into the last one when it
+// continues the same data
+func AppendSegment(segs []Segment, s Segment) []Segment {
+	if n := len(segs); n > 0 {
+		last := &segs[n-1]
+		if last.DataKey == s.DataKey && last.Size == s.Size && last.Offset+last.Length == s.Offset {
+			last.Length += s.Length
+			return segs
+		}
+	}
+	return append(segs, s)
+}
+
+// EncodeSegments encodes segments for object metadata as comma-separated
+// "key:size:offset:length" entries with escaped keys
+func EncodeSegments(segs []Segment) string {
+	parts := make([]string, len(segs))
+	for i, s := range segs {
+		parts[i] = fmt.Sprintf("%s:%d:%d:%d", url.QueryEscape(s.DataKey), s.Size, s.Offset, s.Length)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseSegments parses segments encoded by EncodeSegments
+func ParseSegments(spec string) ([]Segment, error) {
+	var segs []Segment
+	for _, part := range strings.Split(spec, ",") {
+		fields := strings.Split(part, ":")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid segment %q", part)
+		}
+
+		key, err := url.QueryUnescape(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid segment key %q: %w", fields[0], err)
+		}
+
+		var nums [3]int64
+		for i, f := range fields[1:] {
+			if nums[i], err = strconv.ParseInt(f, 10, 64); err != nil || nums[i] < 0 {
+				return nil, fmt.Errorf("invalid segment %q", part)
+			}
+		}
+
+		s := Segment{DataKey: key, Size: nums[0], Offset: nums[1], Length: nums[2]}
+		if s.Offset+s.Length > s.Size {
+			return nil, fmt.Errorf("segment %q extends past its object", part)
+		}
+		segs = append(segs, s)
+	}
+	return segs, nil
+}
+
+// GenerateSegments returns the concatenated data of segments
+func (g *Generator) GenerateSegments(segs []Segment) (io.Reader, error) {
+	readers := make([]io.Reader, 0, len(segs))
+	for _, s := range segs {
+		r, err := g.GenerateRange(s.DataKey, s.Size, s.Offset, s.Length)
+		if err != nil {
+			return nil, err
+		}
+		readers = append(readers, r)
+	}
+	return io.MultiReader(readers...), nil
+}
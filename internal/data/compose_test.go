@@ -0,0 +1,127 @@
+package data
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestSegmentsRoundTrip(t *testing.T) {
+	segs := []Segment{
+		{DataKey: "dir/obj:1,b.bin", Size: 100, Offset: 10, Length: 50},
+		{DataKey: "obj-2", Size: 20, Offset: 0, Length: 20},
+	}
+
+	got, err := ParseSegments(EncodeSegments(segs))
+	if err != nil {
+		t.Fatalf("ParseSegments() failed: %v", err)
+	}
+	if len(got) != len(segs) {
+		t.Fatalf("ParseSegments() = %v, want %v", got, segs)
+	}
+	for i := range segs {
+		if got[i] != segs[i] {
+			t.Errorf("segment %d = %+v, want %+v", i, got[i], segs[i])
+		}
+	}
+
+	for _, bad := range []string{"", "k:1:2", "k:10:5:6", "k:x:0:1", "k:10:-1:1"} {
+		if _, err := ParseSegments(bad); err == nil {
+			t.Errorf("ParseSegments(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSliceSegments(t *testing.T) {
+	segs := []Segment{
+		{DataKey: "a", Size: 100, Offset: 0, Length: 40},
+		{DataKey: "b", Size: 100, Offset: 50, Length: 30},
+		{DataKey: "a", Size: 100, Offset: 40, Length: 10},
+	}
+
+	tests := []struct {
+		name           string
+		offset, length int64
+		want           []Segment
+		wantErr        bool
+	}{
+		{"within first", 5, 10, []Segment{{"a", 100, 5, 10}}, false},
+		{"spanning", 30, 20, []Segment{{"a", 100, 30, 10}, {"b", 100, 50, 10}}, false},
+		{"last segment", 70, 10, []Segment{{"a", 100, 40, 10}}, false},
+		{"inside last", 75, 5, []Segment{{"a", 100, 45, 5}}, false},
+		{"empty", 0, 0, nil, false},
+		{"past end", 75, 10, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SliceSegments(segs, tt.offset, tt.length)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SliceSegments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("SliceSegments() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("segment %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAppendSegmentMerges(t *testing.T) {
+	segs := AppendSegment(nil, Segment{"a", 100, 0, 10})
+	segs = AppendSegment(segs, Segment{"a", 100, 10, 5})
+	segs = AppendSegment(segs, Segment{"a", 100, 20, 5})
+	if len(segs) != 2 || segs[0].Length != 15 {
+		t.Errorf("AppendSegment() = %+v, want contiguous ranges merged", segs)
+	}
+}
+
+func TestVerifyComposedObject(t *testing.T) {
+	gen, err := NewGenerator("random:42")
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	verifier := NewVerifier(gen)
+
+	segs := []Segment{
+		{DataKey: "src-a", Size: 4096, Offset: 1024, Length: 2048},
+		{DataKey: "src-b", Size: 1000, Offset: 0, Length: 1000},
+	}
+
+	var composed bytes.Buffer
+	for _, s := range segs {
+		r, err := gen.GenerateRange(s.DataKey, s.Size, s.Offset, s.Length)
+		if err != nil {
+			t.Fatalf("GenerateRange() failed: %v", err)
+		}
+		io.Copy(&composed, r)
+	}
+	hash, _ := ComputeHash(bytes.NewReader(composed.Bytes()))
+	size := int64(composed.Len())
+
+	metadata := map[string]string{
+		MetadataKeySHA256:   hash,
+		MetadataKeySegments: EncodeSegments(segs),
+	}
+
+	if err := verifier.VerifyObject(bytes.NewReader(composed.Bytes()), "dst", size, metadata, VerifyModeBoth); err != nil {
+		t.Errorf("VerifyObject() failed: %v", err)
+	}
+	if err := verifier.VerifyHead("dst", size, metadata, VerifyModeRegenerate); err != nil {
+		t.Errorf("VerifyHead() failed: %v", err)
+	}
+	if err := verifier.VerifyHead("dst", size-1, metadata, VerifyModeRegenerate); err == nil {
+		t.Error("VerifyHead() with wrong size succeeded")
+	}
+
+	// A range spanning both segments
+	offset, length := int64(2000), int64(100)
+	rangeData := composed.Bytes()[offset : offset+length]
+	if err := verifier.VerifyRange(bytes.NewReader(rangeData), "dst", size, offset, length, metadata); err != nil {
+		t.Errorf("VerifyRange() failed: %v", err)
+	}
+}
@@ -71,6 +71,22 @@ func (g *Generator) Generate(key string, size int64) io.ReadSeeker {
 	}
 }
 
+// GenerateRange returns length bytes of the data for key starting at offset.
+// Random data is a single stream, so the preceding bytes are regenerated and
+// discarded.
+func (g *Generator) GenerateRange(key string, size, offset, length int64) (io.Reader, error) {
+	if offset < 0 || length < 0 || offset+length > size {
+		return nil, fmt.Errorf("range %d+%d outside object of size %d", offset, length, size)
+	}
+
+	reader := g.Generate(key, size)
+	if _, err := io.CopyN(io.Discard, reader, offset); err != nil {
+		return nil, fmt.Errorf("failed to skip to offset %d: %w", offset, err)
+	}
+
+	return io.LimitReader(reader, length), nil
+}
+
 // GenerateAndHash generates data and computes its SHA-256 hash
 func (g *Generator) GenerateAndHash(key string, size int64) (io.ReadSeeker, string, error) {
 	reader := g.Generate(key, size)
@@ -244,11 +260,30 @@ func (r *fixedReader) Seek(offset int64, whence int) (int64, error) {
 	return r.position, nil
 }
 
-// SizeDistribution generates object sizes based on a distribution
+// SizeDistribution generates object sizes based on a distribution.
+// Distributions that draw random sizes are not safe for concurrent use;
+// each worker creates its own from a shared SizeSpec.
 type SizeDistribution interface {
 	Next() int64
 }
 
+// SizeSpec is a parsed size distribution that creates independent
+// generators of it
+type SizeSpec struct {
+	spec    string
+	newDist func(seed int64) SizeDistribution
+}
+
+// New returns a generator of the distribution seeded with seed
+func (s *SizeSpec) New(seed int64) SizeDistribution {
+	return s.newDist(seed)
+}
+
+// String returns the spec the distribution was parsed from
+func (s *SizeSpec) String() string {
+	return s.spec
+}
+
 // FixedSize always returns the same size
 type FixedSize struct {
 	size int64
@@ -267,7 +302,6 @@ type LogNormalSize struct {
 	mean   float64
 	stddev float64
 	rng    *rand.Rand
-	mu     sync.Mutex
 }
 
 func NewLogNormalSize(mean, stddev float64, seed int64) *LogNormalSize {
@@ -279,9 +313,6 @@ func NewLogNormalSize(mean, stddev float64, seed int64) *LogNormalSize {
 }
 
 func (l *LogNormalSize) Next() int64 {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
 	// Generate log-normal random variable
 	// log(X) ~ N(mu, sigma^2)
 	// We want mean and stddev in linear space, so convert
@@ -304,7 +335,6 @@ type UniformSize struct {
 	min int64
 	max int64
 	rng *rand.Rand
-	mu  sync.Mutex
 }
 
 func NewUniformSize(min, max int64, seed int64) *UniformSize {
@@ -316,9 +346,6 @@ func NewUniformSize(min, max int64, seed int64) *UniformSize {
 }
 
 func (u *UniformSize) Next() int64 {
-	u.mu.Lock()
-	defer u.mu.Unlock()
-
 	if u.min == u.max {
 		return u.min
 	}
@@ -388,24 +415,45 @@ func ParseSize(s string) (int64, error) {
 	return int64(value * float64(multiplier)), nil
 }
 
-// ParseSizeDistribution parses a size distribution string
+// ParseSizeDistribution parses a size distribution string and returns a
+// generator of it seeded with seed
+func ParseSizeDistribution(s string, seed int64) (SizeDistribution, error) {
+	spec, err := ParseSizeSpec(s)
+	if err != nil {
+		return nil, err
+	}
+	return spec.New(seed), nil
+}
+
+// ParseSizeSpec parses a size distribution string
 // Examples:
 //   - "fixed:1MiB"
 //   - "dist:lognormal:mean=1MiB,std=0.5"
+//   - "dist:pareto:scale=64KiB,shape=1.5,max=1GiB"
+//   - "dist:exponential:mean=256KiB"
 //   - "uniform:min=1KB,max=10MB"
-func ParseSizeDistribution(s string, seed int64) (SizeDistribution, error) {
+//   - "hist:4KiB=60,1MiB=30,64MiB=10"
+//   - "file:sizes.txt"
+//
+// Every distribution except fixed and uniform accepts min and max
+// parameters that clamp the sizes it draws.
+func ParseSizeSpec(s string) (*SizeSpec, error) {
 	parts := strings.SplitN(s, ":", 2)
 	if len(parts) < 2 {
 		return nil, fmt.Errorf("invalid size distribution format")
 	}
 
+	var newDist func(seed int64) SizeDistribution
+	var params map[string]string
+
 	switch parts[0] {
 	case "fixed":
 		size, err := ParseSize(parts[1])
 		if err != nil {
 			return nil, fmt.Errorf("invalid fixed size: %w", err)
 		}
-		return NewFixedSize(size), nil
+		fixed := NewFixedSize(size)
+		return &SizeSpec{spec: s, newDist: func(int64) SizeDistribution { return fixed }}, nil
 
 	case "dist":
 		// Parse dist:lognormal:mean=1MiB,std=0.5
@@ -415,7 +463,7 @@ func ParseSizeDistribution(s string, seed int64) (SizeDistribution, error) {
 		}
 
 		distType := subParts[0]
-		params := parseParams(subParts[1])
+		params = parseParams(subParts[1])
 
 		switch distType {
 		case "lognormal":
@@ -437,7 +485,46 @@ func ParseSizeDistribution(s string, seed int64) (SizeDistribution, error) {
 				return nil, fmt.Errorf("invalid std: %w", err)
 			}
 
-			return NewLogNormalSize(float64(mean), std*float64(mean), seed), nil
+			newDist = func(seed int64) SizeDistribution {
+				return NewLogNormalSize(float64(mean), std*float64(mean), seed)
+			}
+
+		case "pareto":
+			scaleStr, ok := params["scale"]
+			if !ok {
+				return nil, fmt.Errorf("pareto distribution requires 'scale' parameter")
+			}
+			scale, err := ParseSize(scaleStr)
+			if err != nil || scale < 1 {
+				return nil, fmt.Errorf("invalid scale %q", scaleStr)
+			}
+
+			shapeStr, ok := params["shape"]
+			if !ok {
+				shapeStr = "1.5" // default
+			}
+			shape, err := strconv.ParseFloat(shapeStr, 64)
+			if err != nil || shape <= 0 {
+				return nil, fmt.Errorf("invalid shape %q", shapeStr)
+			}
+
+			newDist = func(seed int64) SizeDistribution {
+				return NewParetoSize(float64(scale), shape, seed)
+			}
+
+		case "exponential":
+			meanStr, ok := params["mean"]
+			if !ok {
+				return nil, fmt.Errorf("exponential distribution requires 'mean' parameter")
+			}
+			mean, err := ParseSize(meanStr)
+			if err != nil || mean < 1 {
+				return nil, fmt.Errorf("invalid mean %q", meanStr)
+			}
+
+			newDist = func(seed int64) SizeDistribution {
+				return NewExponentialSize(float64(mean), seed)
+			}
 
 		default:
 			return nil, fmt.Errorf("unknown distribution type: %s", distType)
@@ -464,11 +551,48 @@ func ParseSizeDistribution(s string, seed int64) (SizeDistribution, error) {
 			return nil, fmt.Errorf("invalid max: %w", err)
 		}
 
-		return NewUniformSize(min, max, seed), nil
+		return &SizeSpec{spec: s, newDist: func(seed int64) SizeDistribution {
+			return NewUniformSize(min, max, seed)
+		}}, nil
+
+	case "hist":
+		sizes, weights, histParams, err := parseHistogram(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		params = histParams
+		newDist = func(seed int64) SizeDistribution {
+			return NewHistogramSize(sizes, weights, seed)
+		}
+
+	case "file":
+		// The path may be followed by clamp parameters: file:sizes.txt,max=1GiB
+		path, rest, _ := strings.Cut(parts[1], ",")
+		params = parseParams(rest)
+		sizes, err := loadSizes(path)
+		if err != nil {
+			return nil, err
+		}
+		newDist = func(seed int64) SizeDistribution {
+			return NewEmpiricalSize(sizes, seed)
+		}
 
 	default:
 		return nil, fmt.Errorf("unknown size type: %s", parts[0])
 	}
+
+	min, max, err := parseClamp(params)
+	if err != nil {
+		return nil, err
+	}
+	if min > 0 || max > 0 {
+		unclamped := newDist
+		newDist = func(seed int64) SizeDistribution {
+			return &clampedSize{dist: unclamped(seed), min: min, max: max}
+		}
+	}
+
+	return &SizeSpec{spec: s, newDist: newDist}, nil
 }
 
 func parseParams(s string) map[string]string {
@@ -2,6 +2,7 @@ package data
 
 import (
 	"io"
+	"sync/atomic"
 	"testing"
 )
 
@@ -138,6 +139,14 @@ func TestParseSizeDistribution(t *testing.T) {
 		{"fixed size", "fixed:1MiB", false},
 		{"lognormal dist", "dist:lognormal:mean=1MiB,std=0.5", false},
 		{"uniform dist", "uniform:min=1KB,max=10MB", false},
+		{"pareto dist", "dist:pareto:scale=64KiB,shape=1.2,max=1GiB", false},
+		{"exponential dist", "dist:exponential:mean=1MiB", false},
+		{"histogram", "hist:4KiB=60,1MiB=30,64MiB=10", false},
+		{"clamped lognormal", "dist:lognormal:mean=1MiB,std=2,min=4KiB,max=16MiB", false},
+		{"min above max", "dist:exponential:mean=1MiB,min=2MiB,max=1MiB", true},
+		{"pareto without scale", "dist:pareto:shape=1.2", true},
+		{"histogram without weights", "hist:4KiB=0", true},
+		{"missing size file", "file:/nonexistent/sizes.txt", true},
 		{"invalid format", "invalid", true},
 		{"unknown type", "unknown:value", true},
 	}
@@ -152,6 +161,28 @@ func TestParseSizeDistribution(t *testing.T) {
 	}
 }
 
+func TestSizeSpecNew(t *testing.T) {
+	spec, err := ParseSizeSpec("dist:lognormal:mean=1MiB,std=0.5")
+	if err != nil {
+		t.Fatalf("ParseSizeSpec() failed: %v", err)
+	}
+	if spec.String() != "dist:lognormal:mean=1MiB,std=0.5" {
+		t.Errorf("String() = %q", spec.String())
+	}
+
+	// Distributions of a spec draw independently
+	a, b := spec.New(9), spec.New(9)
+	for i := 0; i < 10; i++ {
+		a.Next()
+	}
+	ref := spec.New(9)
+	for i := 0; i < 100; i++ {
+		if got, want := b.Next(), ref.Next(); got != want {
+			t.Fatalf("draw %d: Next() = %d, want %d", i, got, want)
+		}
+	}
+}
+
 func TestFixedSizeDistribution(t *testing.T) {
 	dist := NewFixedSize(1024)
 
@@ -183,3 +214,21 @@ func TestLogNormalSizeDistribution(t *testing.T) {
 		t.Logf("Warning: average size %d is far from mean 1024 (acceptable for small sample)", avg)
 	}
 }
+
+// BenchmarkSizeDistributionParallel draws sizes from a distribution per
+// goroutine. Run with -cpu 1,2,4,8: throughput scales with the number of
+// cores since the distributions share no lock.
+func BenchmarkSizeDistributionParallel(b *testing.B) {
+	spec, err := ParseSizeSpec("dist:lognormal:mean=1MiB,std=0.6")
+	if err != nil {
+		b.Fatalf("ParseSizeSpec() failed: %v", err)
+	}
+
+	var seeds int64
+	b.RunParallel(func(pb *testing.PB) {
+		dist := spec.New(atomic.AddInt64(&seeds, 1))
+		for pb.Next() {
+			dist.Next()
+		}
+	})
+}
@@ -0,0 +1,229 @@
+package data
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HistogramSize picks one of a set of sizes by weight
+type HistogramSize struct {
+	sizes   []int64
+	weights []int // cumulative weights
+	rng     *rand.Rand
+}
+
+// NewHistogramSize creates a histogram of sizes with the given weights
+func NewHistogramSize(sizes []int64, weights []int, seed int64) *HistogramSize {
+	h := &HistogramSize{
+		sizes: sizes,
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+	cumulative := 0
+	for _, w := range weights {
+		cumulative += w
+		h.weights = append(h.weights, cumulative)
+	}
+	return h
+}
+
+func (h *HistogramSize) Next() int64 {
+	r := h.rng.Intn(h.weights[len(h.weights)-1])
+	i := sort.SearchInts(h.weights, r+1)
+	return h.sizes[i]
+}
+
+// ParetoSize generates sizes from a Pareto distribution: sizes of at least
+// scale bytes with a heavy tail that is longer the smaller shape is
+type ParetoSize struct {
+	scale float64
+	shape float64
+	rng   *rand.Rand
+}
+
+func NewParetoSize(scale, shape float64, seed int64) *ParetoSize {
+	return &ParetoSize{
+		scale: scale,
+		shape: shape,
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (p *ParetoSize) Next() int64 {
+	// Inverse transform: X = scale / U^(1/shape), U in (0, 1]
+	u := 1 - p.rng.Float64()
+	return boundSize(p.scale / math.Pow(u, 1/p.shape))
+}
+
+// ExponentialSize generates sizes from an exponential distribution
+type ExponentialSize struct {
+	mean float64
+	rng  *rand.Rand
+}
+
+func NewExponentialSize(mean float64, seed int64) *ExponentialSize {
+	return &ExponentialSize{
+		mean: mean,
+		rng:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (e *ExponentialSize) Next() int64 {
+	return boundSize(e.rng.ExpFloat64() * e.mean)
+}
+
+// EmpiricalSize resamples sizes observed elsewhere, such as those listed
+// from a production bucket
+type EmpiricalSize struct {
+	sizes []int64
+	rng   *rand.Rand
+}
+
+func NewEmpiricalSize(sizes []int64, seed int64) *EmpiricalSize {
+	return &EmpiricalSize{
+		sizes: sizes,
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (e *EmpiricalSize) Next() int64 {
+	return e.sizes[e.rng.Intn(len(e.sizes))]
+}
+
+// clampedSize limits the sizes of another distribution to [min, max]
+type clampedSize struct {
+	dist SizeDistribution
+	min  int64
+	max  int64
+}
+
+func (c *clampedSize) Next() int64 {
+	size := c.dist.Next()
+	if c.min > 0 && size < c.min {
+		return c.min
+	}
+	if c.max > 0 && size > c.max {
+		return c.max
+	}
+	return size
+}
+
+// boundSize converts a drawn size to bytes, at least 1 and without
+// overflowing
+func boundSize(f float64) int64 {
+	if f >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	if f < 1 {
+		return 1
+	}
+	return int64(f)
+}
+
+// parseClamp parses the optional min and max parameters that limit the
+// sizes of a distribution. Zero means no limit.
+func parseClamp(params map[string]string) (int64, int64, error) {
+	var min, max int64
+	var err error
+	if s, ok := params["min"]; ok {
+		if min, err = ParseSize(s); err != nil {
+			return 0, 0, fmt.Errorf("invalid min: %w", err)
+		}
+	}
+	if s, ok := params["max"]; ok {
+		if max, err = ParseSize(s); err != nil {
+			return 0, 0, fmt.Errorf("invalid max: %w", err)
+		}
+	}
+	if max > 0 && min > max {
+		return 0, 0, fmt.Errorf("min %d is larger than max %d", min, max)
+	}
+	return min, max, nil
+}
+
+// parseHistogram parses "4KiB=60,1MiB=30,64MiB=10" into sizes and their
+// weights, ordered by size. min and max entries are returned as params.
+func parseHistogram(s string) ([]int64, []int, map[string]string, error) {
+	weights := make(map[int64]int)
+	params := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		sizeStr, weightStr, ok := strings.Cut(pair, "=")
+		sizeStr, weightStr = strings.TrimSpace(sizeStr), strings.TrimSpace(weightStr)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("invalid histogram bucket %q, want SIZE=WEIGHT", pair)
+		}
+		if sizeStr == "min" || sizeStr == "max" {
+			params[sizeStr] = weightStr
+			continue
+		}
+
+		size, err := ParseSize(sizeStr)
+		if err != nil || size < 1 {
+			return nil, nil, nil, fmt.Errorf("invalid histogram size %q", sizeStr)
+		}
+		weight, err := strconv.Atoi(weightStr)
+		if err != nil || weight < 0 {
+			return nil, nil, nil, fmt.Errorf("invalid weight for histogram size %q", sizeStr)
+		}
+		weights[size] += weight
+	}
+
+	var sizes []int64
+	for size, weight := range weights {
+		if weight > 0 {
+			sizes = append(sizes, size)
+		}
+	}
+	if len(sizes) == 0 {
+		return nil, nil, nil, fmt.Errorf("histogram weights sum to zero")
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+
+	ordered := make([]int, len(sizes))
+	for i, size := range sizes {
+		ordered[i] = weights[size]
+	}
+	return sizes, ordered, params, nil
+}
+
+// loadSizes reads one size per line, as bytes or with a unit. Blank lines
+// and lines starting with # are skipped.
+func loadSizes(path string) ([]int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open size file: %w", err)
+	}
+	defer f.Close()
+
+	var sizes []int64
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		size, err := ParseSize(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size on line %d of %s: %w", lineNum, path, err)
+		}
+		if size < 1 {
+			size = 1
+		}
+		sizes = append(sizes, size)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read size file: %w", err)
+	}
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("size file %s lists no sizes", path)
+	}
+	return sizes, nil
+}
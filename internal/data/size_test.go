@@ -0,0 +1,130 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistogramSize(t *testing.T) {
+	spec, err := ParseSizeSpec("hist:1MiB=30,4KiB=60,64MiB=10")
+	if err != nil {
+		t.Fatalf("ParseSizeSpec() failed: %v", err)
+	}
+
+	dist := spec.New(42)
+	counts := make(map[int64]int)
+	samples := 10000
+	for i := 0; i < samples; i++ {
+		counts[dist.Next()]++
+	}
+
+	want := map[int64]float64{4096: 60, 1 << 20: 30, 64 << 20: 10}
+	for size, pct := range want {
+		got := float64(counts[size]) / float64(samples) * 100
+		if got < pct-3 || got > pct+3 {
+			t.Errorf("size %d drawn %.1f%% of the time, want ~%.0f%%", size, got, pct)
+		}
+	}
+	if len(counts) != len(want) {
+		t.Errorf("drew sizes %v, want only %v", counts, want)
+	}
+}
+
+func TestParseHistogram(t *testing.T) {
+	sizes, weights, params, err := parseHistogram("1MiB=30, 4KiB=60,4096=10,max=512KiB")
+	if err != nil {
+		t.Fatalf("parseHistogram() failed: %v", err)
+	}
+
+	// Equal sizes are merged and buckets ordered by size
+	if len(sizes) != 2 || sizes[0] != 4096 || sizes[1] != 1<<20 {
+		t.Errorf("sizes = %v, want [4096 1048576]", sizes)
+	}
+	if len(weights) != 2 || weights[0] != 70 || weights[1] != 30 {
+		t.Errorf("weights = %v, want [70 30]", weights)
+	}
+	if params["max"] != "512KiB" {
+		t.Errorf("params = %v, want max=512KiB", params)
+	}
+}
+
+func TestClampedSize(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		min  int64
+		max  int64
+	}{
+		{"pareto", "dist:pareto:scale=1KiB,shape=0.8,max=1MiB", 1024, 1 << 20},
+		{"exponential", "dist:exponential:mean=64KiB,min=16KiB,max=128KiB", 16 << 10, 128 << 10},
+		{"lognormal", "dist:lognormal:mean=1MiB,std=3,min=4KiB,max=8MiB", 4 << 10, 8 << 20},
+		{"histogram", "hist:1KiB=50,1GiB=50,max=1MiB", 1024, 1 << 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ParseSizeSpec(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSizeSpec() failed: %v", err)
+			}
+
+			dist := spec.New(42)
+			for i := 0; i < 5000; i++ {
+				if size := dist.Next(); size < tt.min || size > tt.max {
+					t.Fatalf("Next() = %d, want within [%d, %d]", size, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestExponentialSizeMean(t *testing.T) {
+	dist := NewExponentialSize(1<<20, 42)
+
+	var sum int64
+	samples := 20000
+	for i := 0; i < samples; i++ {
+		sum += dist.Next()
+	}
+
+	avg := float64(sum) / float64(samples)
+	if avg < 0.9*(1<<20) || avg > 1.1*(1<<20) {
+		t.Errorf("average size %.0f, want ~%d", avg, 1<<20)
+	}
+}
+
+func TestEmpiricalSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sizes.txt")
+	content := "# sizes listed from a bucket\n4096\n\n1MiB\n  512KiB  \n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := ParseSizeSpec("file:" + path + ",max=768KiB")
+	if err != nil {
+		t.Fatalf("ParseSizeSpec() failed: %v", err)
+	}
+
+	want := map[int64]bool{4096: true, 512 << 10: true, 768 << 10: true}
+	dist := spec.New(42)
+	seen := make(map[int64]bool)
+	for i := 0; i < 1000; i++ {
+		size := dist.Next()
+		if !want[size] {
+			t.Fatalf("Next() = %d, want one of %v", size, want)
+		}
+		seen[size] = true
+	}
+	if len(seen) != len(want) {
+		t.Errorf("drew %v, want all of %v", seen, want)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.txt")
+	if err := os.WriteFile(bad, []byte("4096\nlarge\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseSizeSpec("file:" + bad); err == nil {
+		t.Error("ParseSizeSpec() accepted a file with an invalid size")
+	}
+}
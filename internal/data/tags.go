@@ -0,0 +1,124 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+const (
+	// MaxTags is the maximum number of tags on an object
+	MaxTags = 10
+
+	// MaxTagKeyLen and MaxTagValueLen are the S3 tag length limits
+	MaxTagKeyLen   = 128
+	MaxTagValueLen = 256
+
+	// TagKeyChecksum is the tag holding a hash of an object's other tags,
+	// which makes generated tag sets self-verifying
+	TagKeyChecksum = "s3bench-sum"
+
+	// MetadataKeyGeneratedPrefix prefixes generated user metadata keys
+	MetadataKeyGeneratedPrefix = "bench-meta-"
+)
+
+const tagAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// ParseTags parses a tag spec such as "team=storage,cost-center=42"
+func ParseTags(spec string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, pair := range splitCommaSeparated(spec) {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid tag %q: expected key=value", pair)
+		}
+		kv[0], kv[1] = strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if len(kv[0]) > MaxTagKeyLen || len(kv[1]) > MaxTagValueLen {
+			return nil, fmt.Errorf("tag %q exceeds %d-character key or %d-character value limit", pair, MaxTagKeyLen, MaxTagValueLen)
+		}
+		if kv[0] == TagKeyChecksum {
+			return nil, fmt.Errorf("tag key %q is reserved", TagKeyChecksum)
+		}
+		tags[kv[0]] = kv[1]
+	}
+	if len(tags) > MaxTags {
+		return nil, fmt.Errorf("too many tags: %d > %d", len(tags), MaxTags)
+	}
+	return tags, nil
+}
+
+// GenerateTags returns count random tags with values of valueSize
+// characters, plus a checksum tag over them
+func GenerateTags(count, valueSize int, rng *rand.Rand) map[string]string {
+	tags := make(map[string]string, count+1)
+	for i := 0; i < count; i++ {
+		tags[fmt.Sprintf("tag-%02d", i)] = randomString(valueSize, rng)
+	}
+	tags[TagKeyChecksum] = TagsChecksum(tags)
+	return tags
+}
+
+// TagsChecksum hashes every tag except the checksum tag
+func TagsChecksum(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		if k != TagKeyChecksum {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, tags[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// VerifyTags checks tags read back from an object. A generated tag set must
+// match its checksum, other non-empty sets must equal the tags set at PUT
+// time, and an empty set is always valid.
+func VerifyTags(tags, putTags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if sum, ok := tags[TagKeyChecksum]; ok {
+		if expected := TagsChecksum(tags); sum != expected {
+			return fmt.Errorf("tag checksum mismatch: expected %s, got %s", expected, sum)
+		}
+		return nil
+	}
+
+	if len(tags) != len(putTags) {
+		return fmt.Errorf("tag count mismatch: expected %d, got %d", len(putTags), len(tags))
+	}
+	for k, v := range putTags {
+		if tags[k] != v {
+			return fmt.Errorf("tag %q mismatch: expected %q, got %q", k, v, tags[k])
+		}
+	}
+	return nil
+}
+
+// GenerateMetadata returns count random user metadata entries with values
+// of valueSize characters
+func GenerateMetadata(count, valueSize int, rng *rand.Rand) map[string]string {
+	metadata := make(map[string]string, count)
+	for i := 0; i < count; i++ {
+		metadata[fmt.Sprintf("%s%02d", MetadataKeyGeneratedPrefix, i)] = randomString(valueSize, rng)
+	}
+	return metadata
+}
+
+func randomString(n int, rng *rand.Rand) string {
+	var b strings.Builder
+	b.Grow(n)
+	for i := 0; i < n; i++ {
+		b.WriteByte(tagAlphabet[rng.Intn(len(tagAlphabet))])
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package data
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"empty", "", map[string]string{}, false},
+		{"single", "team=storage", map[string]string{"team": "storage"}, false},
+		{"multiple", "team=storage, cost-center=42", map[string]string{"team": "storage", "cost-center": "42"}, false},
+		{"empty value", "flag=", map[string]string{"flag": ""}, false},
+		{"missing value", "team", nil, true},
+		{"empty key", "=x", nil, true},
+		{"reserved key", TagKeyChecksum + "=x", nil, true},
+		{"long value", "k=" + strings.Repeat("v", MaxTagValueLen+1), nil, true},
+		{"too many", "a=1,b=2,c=3,d=4,e=5,f=6,g=7,h=8,i=9,j=10,k=11", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTags(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseTags() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("ParseTags()[%q] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyTags(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	generated := GenerateTags(5, 16, rng)
+	if len(generated) != 6 {
+		t.Fatalf("GenerateTags() returned %d tags, want 6", len(generated))
+	}
+
+	tampered := make(map[string]string)
+	for k, v := range generated {
+		tampered[k] = v
+	}
+	tampered["tag-00"] = "changed"
+
+	putTags := map[string]string{"team": "storage"}
+
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		wantErr bool
+	}{
+		{"no tags", nil, false},
+		{"generated", generated, false},
+		{"tampered", tampered, true},
+		{"put tags", map[string]string{"team": "storage"}, false},
+		{"wrong put tag", map[string]string{"team": "compute"}, true},
+		{"extra put tag", map[string]string{"team": "storage", "x": "y"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyTags(tt.tags, putTags)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"bufio"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -16,8 +17,46 @@ const (
 
 	// MetadataValueCreatedBy is the value for created-by metadata
 	MetadataValueCreatedBy = "s3-workload"
+
+	// MetadataKeyDataKey records the key the object's bytes were generated from.
+	// It differs from the object key for server-side copies.
+	MetadataKeyDataKey = "data-key"
 )
 
+// VerifyMode selects what object data is verified against
+type VerifyMode string
+
+const (
+	// VerifyModeMetadata compares against the sha256 stored in object metadata
+	VerifyModeMetadata VerifyMode = "metadata"
+
+	// VerifyModeRegenerate compares against bytes regenerated from the key and pattern
+	VerifyModeRegenerate VerifyMode = "regenerate"
+
+	// VerifyModeBoth requires both the metadata and the regenerated hash to match
+	VerifyModeBoth VerifyMode = "both"
+)
+
+// ParseVerifyMode parses a verify mode string
+func ParseVerifyMode(s string) (VerifyMode, error) {
+	switch VerifyMode(s) {
+	case VerifyModeMetadata, VerifyModeRegenerate, VerifyModeBoth:
+		return VerifyMode(s), nil
+	default:
+		return "", fmt.Errorf("unknown verify mode: %s", s)
+	}
+}
+
+// usesMetadata reports whether the mode checks the metadata hash
+func (m VerifyMode) usesMetadata() bool {
+	return m == VerifyModeMetadata || m == VerifyModeBoth
+}
+
+// usesRegenerate reports whether the mode checks regenerated data
+func (m VerifyMode) usesRegenerate() bool {
+	return m == VerifyModeRegenerate || m == VerifyModeBoth
+}
+
 // Verifier verifies data integrity
 type Verifier struct {
 	generator *Generator
@@ -64,6 +103,134 @@ func (v *Verifier) VerifyKey(r io.Reader, key string, size int64) error {
 	return v.Verify(r, expectedHash)
 }
 
+// ExpectedHash regenerates the data for a key and returns its SHA-256 hash
+func (v *Verifier) ExpectedHash(key string, size int64) (string, error) {
+	return ComputeHash(v.generator.Generate(key, size))
+}
+
+// expectedObjectHash regenerates an object's data from its metadata, which
+// names its data key or, for composed objects, its segments
+func (v *Verifier) expectedObjectHash(key string, size int64, metadata map[string]string) (string, error) {
+	segs, err := ObjectSegments(key, size, metadata)
+	if err != nil {
+		return "", err
+	}
+	r, err := v.generator.GenerateSegments(segs)
+	if err != nil {
+		return "", err
+	}
+	return ComputeHash(r)
+}
+
+// VerifyObject verifies object data according to mode. size is the object
+// length reported by the server; the data key or segments are taken from
+// metadata when present so that copied objects regenerate their source's
+// bytes.
+// Regeneration cannot detect truncation on its own since generated data is
+// prefix-stable; use VerifyModeBoth to also check the stored hash.
+func (v *Verifier) VerifyObject(r io.Reader, key string, size int64, metadata map[string]string, mode VerifyMode) error {
+	actualHash, err := ComputeHash(r)
+	if err != nil {
+		return fmt.Errorf("failed to compute hash: %w", err)
+	}
+
+	if mode.usesMetadata() {
+		expectedHash, ok := metadata[MetadataKeySHA256]
+		if !ok {
+			return fmt.Errorf("no hash found in metadata")
+		}
+		if actualHash != expectedHash {
+			return fmt.Errorf("metadata hash mismatch: expected %s, got %s", expectedHash, actualHash)
+		}
+	}
+
+	if mode.usesRegenerate() {
+		expectedHash, err := v.expectedObjectHash(key, size, metadata)
+		if err != nil {
+			return fmt.Errorf("failed to compute expected hash: %w", err)
+		}
+		if actualHash != expectedHash {
+			return fmt.Errorf("regenerated hash mismatch: expected %s, got %s", expectedHash, actualHash)
+		}
+	}
+
+	return nil
+}
+
+// VerifyHead checks that the size reported by a HEAD request is consistent
+// with the stored hash by regenerating the data at that size from the data
+// key or segments in metadata. A HEAD returns no data, so unlike VerifyObject
+// this relies on the stored hash even in regenerate mode: it only shows that
+// the size and metadata agree, and fails when the hash is missing. It is a
+// no-op in metadata mode.
+func (v *Verifier) VerifyHead(key string, size int64, metadata map[string]string, mode VerifyMode) error {
+	if !mode.usesRegenerate() {
+		return nil
+	}
+
+	storedHash, ok := metadata[MetadataKeySHA256]
+	if !ok {
+		return fmt.Errorf("no hash found in metadata to check size %d against", size)
+	}
+
+	expectedHash, err := v.expectedObjectHash(key, size, metadata)
+	if err != nil {
+		return fmt.Errorf("failed to compute expected hash: %w", err)
+	}
+	if storedHash != expectedHash {
+		return fmt.Errorf("size %d inconsistent with stored hash %s", size, storedHash)
+	}
+
+	return nil
+}
+
+// VerifyRange verifies a byte range of an object against regenerated data.
+// offset and size are the range start and total object size reported by the
+// server. Returns the offset of the first mismatching byte on failure.
+func (v *Verifier) VerifyRange(r io.Reader, key string, size, offset, length int64, metadata map[string]string) error {
+	segs, err := ObjectSegments(key, size, metadata)
+	if err == nil {
+		segs, err = SliceSegments(segs, offset, length)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate expected range: %w", err)
+	}
+	expected, err := v.generator.GenerateSegments(segs)
+	if err != nil {
+		return fmt.Errorf("failed to generate expected range: %w", err)
+	}
+
+	actual := bufio.NewReader(r)
+	want := bufio.NewReader(expected)
+	for pos := offset; pos < offset+length; pos++ {
+		a, err := actual.ReadByte()
+		if err != nil {
+			return fmt.Errorf("range truncated at offset %d: %w", pos, err)
+		}
+		b, err := want.ReadByte()
+		if err != nil {
+			return fmt.Errorf("failed to generate expected range: %w", err)
+		}
+		if a != b {
+			return fmt.Errorf("range mismatch at offset %d", pos)
+		}
+	}
+
+	if _, err := actual.ReadByte(); err != io.EOF {
+		return fmt.Errorf("range longer than %d bytes", length)
+	}
+
+	return nil
+}
+
+// DataKey returns the key an object's data was generated from
+func DataKey(key string, metadata map[string]string) string {
+	if dataKey, ok := metadata[MetadataKeyDataKey]; ok && dataKey != "" {
+		return dataKey
+	}
+	return key
+}
+
 // VerifyWithMetadata verifies data using hash from metadata
 func (v *Verifier) VerifyWithMetadata(r io.Reader, metadata map[string]string) error {
 	expectedHash, ok := metadata[MetadataKeySHA256]
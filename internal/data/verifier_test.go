@@ -2,6 +2,7 @@ package data
 
 import (
 	"bytes"
+	"io"
 	"testing"
 )
 
@@ -81,3 +82,157 @@ func TestPrepareMetadata(t *testing.T) {
 		t.Errorf("metadata team = %s, want platform", metadata["team"])
 	}
 }
+
+func TestVerifyObject(t *testing.T) {
+	gen, err := NewGenerator("random:42")
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+
+	verifier := NewVerifier(gen)
+
+	key := "test-key"
+	size := int64(4096)
+
+	payload, err := io.ReadAll(gen.Generate(key, size))
+	if err != nil {
+		t.Fatalf("failed to generate data: %v", err)
+	}
+	hash, err := ComputeHash(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("ComputeHash() failed: %v", err)
+	}
+
+	corrupted := append([]byte(nil), payload...)
+	corrupted[100] ^= 0xFF
+	corruptedHash, err := ComputeHash(bytes.NewReader(corrupted))
+	if err != nil {
+		t.Fatalf("ComputeHash() failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		metadata map[string]string
+		mode     VerifyMode
+		wantErr  bool
+	}{
+		{"metadata ok", payload, map[string]string{MetadataKeySHA256: hash}, VerifyModeMetadata, false},
+		{"metadata missing", payload, map[string]string{}, VerifyModeMetadata, true},
+		{"regenerate without metadata", payload, map[string]string{}, VerifyModeRegenerate, false},
+		{"regenerate ignores metadata", corrupted, map[string]string{MetadataKeySHA256: corruptedHash}, VerifyModeRegenerate, true},
+		{"metadata trusts corrupted metadata", corrupted, map[string]string{MetadataKeySHA256: corruptedHash}, VerifyModeMetadata, false},
+		{"both ok", payload, map[string]string{MetadataKeySHA256: hash}, VerifyModeBoth, false},
+		{"both missing metadata", payload, map[string]string{}, VerifyModeBoth, true},
+		{"copied object uses data key", payload, map[string]string{MetadataKeyDataKey: key}, VerifyModeRegenerate, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objectKey := key
+			if _, ok := tt.metadata[MetadataKeyDataKey]; ok {
+				objectKey = "copy-destination"
+			}
+			err := verifier.VerifyObject(bytes.NewReader(tt.data), objectKey, int64(len(tt.data)), tt.metadata, tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyObject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyHead(t *testing.T) {
+	gen, err := NewGenerator("random:42")
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+
+	verifier := NewVerifier(gen)
+
+	key := "test-key"
+	size := int64(2048)
+
+	_, hash, err := gen.GenerateAndHash(key, size)
+	if err != nil {
+		t.Fatalf("failed to generate data: %v", err)
+	}
+	metadata := map[string]string{MetadataKeySHA256: hash}
+
+	if err := verifier.VerifyHead(key, size, metadata, VerifyModeRegenerate); err != nil {
+		t.Errorf("VerifyHead() failed: %v", err)
+	}
+
+	if err := verifier.VerifyHead(key, size-1, metadata, VerifyModeRegenerate); err == nil {
+		t.Error("VerifyHead() should have failed with truncated size")
+	}
+
+	if err := verifier.VerifyHead(key, size-1, metadata, VerifyModeMetadata); err != nil {
+		t.Errorf("VerifyHead() in metadata mode should be a no-op: %v", err)
+	}
+
+	// Without a stored hash there is nothing to check the size against
+	for _, mode := range []VerifyMode{VerifyModeRegenerate, VerifyModeBoth} {
+		if err := verifier.VerifyHead(key, size, map[string]string{}, mode); err == nil {
+			t.Errorf("VerifyHead() without a hash in %s mode should have failed", mode)
+		}
+	}
+
+	// A copy is checked against the data of its source
+	copied := map[string]string{MetadataKeySHA256: hash, MetadataKeyDataKey: key}
+	if err := verifier.VerifyHead("copy-key", size, copied, VerifyModeRegenerate); err != nil {
+		t.Errorf("VerifyHead() of a copy failed: %v", err)
+	}
+	if err := verifier.VerifyHead("copy-key", size, map[string]string{MetadataKeySHA256: hash}, VerifyModeRegenerate); err == nil {
+		t.Error("VerifyHead() of a copy without its data key should have failed")
+	}
+}
+
+func TestParseVerifyMode(t *testing.T) {
+	for _, s := range []string{"metadata", "regenerate", "both"} {
+		if _, err := ParseVerifyMode(s); err != nil {
+			t.Errorf("ParseVerifyMode(%q) failed: %v", s, err)
+		}
+	}
+
+	if _, err := ParseVerifyMode("bogus"); err == nil {
+		t.Error("ParseVerifyMode() should have failed for unknown mode")
+	}
+}
+
+func TestVerifyRange(t *testing.T) {
+	gen, err := NewGenerator("random:42")
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+
+	verifier := NewVerifier(gen)
+
+	key := "test-key"
+	size := int64(100000)
+
+	full, err := io.ReadAll(gen.Generate(key, size))
+	if err != nil {
+		t.Fatalf("failed to generate data: %v", err)
+	}
+
+	offset, length := int64(54321), int64(4096)
+	slice := full[offset : offset+length]
+
+	if err := verifier.VerifyRange(bytes.NewReader(slice), key, size, offset, length, nil); err != nil {
+		t.Errorf("VerifyRange() failed: %v", err)
+	}
+
+	// Data from the wrong offset must not verify
+	shifted := full[offset+1 : offset+1+length]
+	if err := verifier.VerifyRange(bytes.NewReader(shifted), key, size, offset, length, nil); err == nil {
+		t.Error("VerifyRange() should have failed for shifted data")
+	}
+
+	if err := verifier.VerifyRange(bytes.NewReader(slice[:100]), key, size, offset, length, nil); err == nil {
+		t.Error("VerifyRange() should have failed for truncated data")
+	}
+
+	if _, err := gen.GenerateRange(key, size, size-10, 20); err == nil {
+		t.Error("GenerateRange() should reject ranges past the end")
+	}
+}
@@ -0,0 +1,329 @@
+package history
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultBudget is the default number of search steps per check
+const DefaultBudget = 1_000_000
+
+// KeyResult is the outcome of checking the history of one key
+type KeyResult struct {
+	Key          string
+	Ops          int
+	Linearizable bool
+	Unknown      bool        // search budget exhausted before a verdict
+	Violation    []Operation // minimal non-linearizable sub-history, by invoke time
+}
+
+// Check runs a register-model linearizability check on every key in the
+// history and returns the results sorted by key
+func Check(ops []Operation, budget int) []KeyResult {
+	byKey := Partition(ops)
+
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make([]KeyResult, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, CheckKey(key, byKey[key], budget))
+	}
+	return results
+}
+
+// CheckKey checks the history of a single key. Reads that failed are
+// dropped; writes that failed may have taken effect at any point after they
+// were invoked, so they are given an infinite completion time. The state
+// before the first write is unknown, and the first read pins it.
+//
+// When the history is not linearizable, it is shrunk to a 1-minimal
+// violating sub-history: removing any single operation from it (together
+// with reads of a value only that operation wrote) makes it linearizable.
+func CheckKey(key string, ops []Operation, budget int) KeyResult {
+	cops := prepare(ops)
+	result := KeyResult{Key: key, Ops: len(cops)}
+
+	ok, exhausted := linearizable(cops, budget)
+	switch {
+	case exhausted:
+		result.Unknown = true
+		return result
+	case ok:
+		result.Linearizable = true
+		return result
+	}
+
+	minimal := minimize(cops, budget)
+	result.Violation = make([]Operation, 0, len(minimal))
+	for _, op := range minimal {
+		result.Violation = append(result.Violation, ops[op.orig])
+	}
+	sort.Slice(result.Violation, func(i, j int) bool {
+		return result.Violation[i].Invoke < result.Violation[j].Invoke
+	})
+
+	return result
+}
+
+// checkOp is an operation prepared for the search
+type checkOp struct {
+	kind  Kind
+	value string
+	call  int64
+	ret   int64
+	orig  int // index into the input operations
+}
+
+func prepare(ops []Operation) []checkOp {
+	cops := make([]checkOp, 0, len(ops))
+	for i, op := range ops {
+		c := checkOp{kind: op.Kind, value: op.Value, call: op.Invoke, ret: op.Complete, orig: i}
+		if op.Result != ResultOK {
+			if op.Kind == KindRead {
+				continue
+			}
+			c.ret = math.MaxInt64
+		}
+		cops = append(cops, c)
+	}
+	return cops
+}
+
+// regState is the state of the register model
+type regState struct {
+	value   string // empty when the object does not exist
+	unknown bool   // any value may be read next
+}
+
+// step applies op to the model, reporting whether op is legal in state s
+func step(s regState, op checkOp) (regState, bool) {
+	switch op.kind {
+	case KindWrite:
+		return regState{value: op.value}, true
+	case KindDelete:
+		return regState{}, true
+	case KindCopy:
+		return regState{unknown: true}, true
+	case KindRead:
+		if s.unknown {
+			return regState{value: op.value}, true
+		}
+		return s, s.value == op.value
+	}
+	return s, false
+}
+
+// node is a call or return event in the search's doubly linked list
+type node struct {
+	id         int
+	call       bool
+	time       int64
+	match      *node // return event of a call
+	prev, next *node
+}
+
+func buildList(ops []checkOp) *node {
+	events := make([]*node, 0, 2*len(ops))
+	for i, op := range ops {
+		ret := &node{id: i, time: op.ret}
+		call := &node{id: i, call: true, time: op.call, match: ret}
+		events = append(events, call, ret)
+	}
+
+	// Calls sort before returns at the same instant, treating the
+	// operations as concurrent
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].time != events[j].time {
+			return events[i].time < events[j].time
+		}
+		return events[i].call && !events[j].call
+	})
+
+	head := &node{}
+	prev := head
+	for _, e := range events {
+		prev.next = e
+		e.prev = prev
+		prev = e
+	}
+	return head
+}
+
+func lift(n *node) {
+	n.prev.next = n.next
+	if n.next != nil {
+		n.next.prev = n.prev
+	}
+	m := n.match
+	m.prev.next = m.next
+	if m.next != nil {
+		m.next.prev = m.prev
+	}
+}
+
+func unlift(n *node) {
+	m := n.match
+	m.prev.next = m
+	if m.next != nil {
+		m.next.prev = m
+	}
+	n.prev.next = n
+	if n.next != nil {
+		n.next.prev = n
+	}
+}
+
+// bitset tracks which operations have been linearized
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) set(i int)   { b[i/64] |= 1 << uint(i%64) }
+func (b bitset) clear(i int) { b[i/64] &^= 1 << uint(i%64) }
+
+func (b bitset) clone() bitset {
+	c := make(bitset, len(b))
+	copy(c, b)
+	return c
+}
+
+func (b bitset) equals(o bitset) bool {
+	for i := range b {
+		if b[i] != o[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b bitset) hash() uint64 {
+	h := uint64(14695981039346656037)
+	for _, w := range b {
+		h ^= w
+		h *= 1099511628211
+	}
+	return h
+}
+
+type cacheEntry struct {
+	linearized bitset
+	state      regState
+}
+
+type frame struct {
+	n     *node
+	state regState
+}
+
+// linearizable runs a Wing & Gong search with state memoization in the
+// style of Lowe and Porcupine. exhausted is true if the budget ran out.
+func linearizable(ops []checkOp, budget int) (ok bool, exhausted bool) {
+	if len(ops) == 0 {
+		return true, false
+	}
+
+	head := buildList(ops)
+	linearized := newBitset(len(ops))
+	cache := make(map[uint64][]cacheEntry)
+	state := regState{unknown: true}
+	var stack []frame
+
+	cacheAdd := func(b bitset, s regState) bool {
+		h := b.hash()
+		for _, e := range cache[h] {
+			if e.state == s && e.linearized.equals(b) {
+				return false
+			}
+		}
+		cache[h] = append(cache[h], cacheEntry{linearized: b, state: s})
+		return true
+	}
+
+	steps := 0
+	entry := head.next
+	for head.next != nil {
+		steps++
+		if steps > budget {
+			return false, true
+		}
+
+		if entry.call {
+			if newState, legal := step(state, ops[entry.id]); legal {
+				next := linearized.clone()
+				next.set(entry.id)
+				if cacheAdd(next, newState) {
+					stack = append(stack, frame{n: entry, state: state})
+					state = newState
+					linearized.set(entry.id)
+					lift(entry)
+					entry = head.next
+					continue
+				}
+			}
+			entry = entry.next
+			continue
+		}
+
+		// Reached the return of an operation that cannot be linearized
+		// yet, so undo the most recent choice
+		if len(stack) == 0 {
+			return false, false
+		}
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		state = top.state
+		linearized.clear(top.n.id)
+		unlift(top.n)
+		entry = top.n.next
+	}
+
+	return true, false
+}
+
+// minimize greedily removes operations while the history stays
+// non-linearizable
+func minimize(ops []checkOp, budget int) []checkOp {
+	cur := ops
+	for i := 0; i < len(cur); {
+		candidate := removeWithDependents(cur, i)
+		if ok, exhausted := linearizable(candidate, budget); !ok && !exhausted {
+			cur = candidate
+			continue
+		}
+		i++
+	}
+	return cur
+}
+
+// removeWithDependents removes ops[i] and, if it was the only write of its
+// value, the reads that returned that value
+func removeWithDependents(ops []checkOp, i int) []checkOp {
+	removed := ops[i]
+	dropReads := removed.kind == KindWrite
+	if dropReads {
+		for j, op := range ops {
+			if j != i && op.kind == KindWrite && op.value == removed.value {
+				dropReads = false
+				break
+			}
+		}
+	}
+
+	out := make([]checkOp, 0, len(ops)-1)
+	for j, op := range ops {
+		if j == i {
+			continue
+		}
+		if dropReads && op.kind == KindRead && op.value == removed.value {
+			continue
+		}
+		out = append(out, op)
+	}
+	return out
+}
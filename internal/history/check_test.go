@@ -0,0 +1,123 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func op(kind Kind, invoke, complete int64, value string) Operation {
+	return Operation{Key: "k", Kind: kind, Invoke: invoke, Complete: complete, Value: value, Result: ResultOK}
+}
+
+func TestCheckKeyLinearizable(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []Operation
+	}{
+		{"sequential", []Operation{
+			op(KindWrite, 0, 10, "a"),
+			op(KindRead, 20, 30, "a"),
+			op(KindDelete, 40, 50, ""),
+			op(KindRead, 60, 70, ""),
+		}},
+		{"concurrent read sees new value", []Operation{
+			op(KindWrite, 0, 10, "a"),
+			op(KindWrite, 20, 50, "b"),
+			op(KindRead, 30, 40, "b"),
+			op(KindRead, 60, 70, "b"),
+		}},
+		{"concurrent read sees old value", []Operation{
+			op(KindWrite, 0, 10, "a"),
+			op(KindWrite, 20, 50, "b"),
+			op(KindRead, 30, 40, "a"),
+		}},
+		{"pre-existing value", []Operation{
+			op(KindRead, 0, 10, "old"),
+			op(KindWrite, 20, 30, "a"),
+		}},
+		{"failed write applied late", []Operation{
+			op(KindWrite, 0, 10, "a"),
+			{Key: "k", Kind: KindWrite, Invoke: 20, Complete: 30, Value: "b", Result: ResultFail},
+			op(KindRead, 40, 50, "a"),
+			op(KindRead, 60, 70, "b"),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CheckKey("k", tt.ops, DefaultBudget)
+			if !result.Linearizable {
+				t.Errorf("CheckKey() = %+v, want linearizable", result)
+			}
+		})
+	}
+}
+
+func TestCheckKeyViolation(t *testing.T) {
+	ops := []Operation{
+		op(KindWrite, 0, 10, "a"),
+		op(KindRead, 5, 15, "a"),
+		op(KindWrite, 20, 30, "b"),
+		op(KindRead, 25, 35, "b"),
+		op(KindRead, 40, 50, "a"), // stale: b was acknowledged at 30
+		op(KindWrite, 60, 70, "c"),
+		op(KindRead, 80, 90, "c"),
+	}
+
+	result := CheckKey("k", ops, DefaultBudget)
+	if result.Linearizable || result.Unknown {
+		t.Fatalf("CheckKey() = %+v, want violation", result)
+	}
+
+	// The stale read, the write it read and the write that overwrote it
+	if len(result.Violation) != 3 {
+		t.Fatalf("minimal violation has %d ops, want 3: %+v", len(result.Violation), result.Violation)
+	}
+	want := []Operation{ops[0], ops[2], ops[4]}
+	for i, op := range result.Violation {
+		if op != want[i] {
+			t.Errorf("violation[%d] = %+v, want %+v", i, op, want[i])
+		}
+	}
+}
+
+func TestCheckKeyBudget(t *testing.T) {
+	var ops []Operation
+	for i := int64(0); i < 12; i++ {
+		ops = append(ops, op(KindWrite, 0, 1000, string(rune('a'+i))))
+	}
+	ops = append(ops, op(KindRead, 2000, 2010, "zz"))
+
+	result := CheckKey("k", ops, 10)
+	if !result.Unknown {
+		t.Errorf("CheckKey() = %+v, want unknown with tiny budget", result)
+	}
+}
+
+func TestRecorderRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	rec, err := NewRecorder(path)
+	if err != nil {
+		t.Fatalf("NewRecorder() failed: %v", err)
+	}
+	rec.Record("k", KindWrite, time.Now(), "a", nil)
+	rec.Record("k", KindRead, time.Now(), "a", nil)
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	ops, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(ops) != 2 {
+		t.Fatalf("Load() returned %d ops, want 2", len(ops))
+	}
+
+	results := Check(ops, DefaultBudget)
+	if len(results) != 1 || !results[0].Linearizable {
+		t.Errorf("Check() = %+v, want one linearizable key", results)
+	}
+}
@@ -0,0 +1,153 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Kind is the kind of operation in a history
+type Kind string
+
+const (
+	KindRead   Kind = "read"
+	KindWrite  Kind = "write"
+	KindDelete Kind = "delete"
+
+	// KindCopy is a write of a value the recorder could not observe
+	KindCopy Kind = "copy"
+)
+
+// Result is the outcome of an operation as seen by the client
+type Result string
+
+const (
+	ResultOK   Result = "ok"
+	ResultFail Result = "fail" // outcome unknown
+)
+
+// Operation is one completed client operation on a key
+type Operation struct {
+	Key      string `json:"key"`
+	Kind     Kind   `json:"op"`
+	Invoke   int64  `json:"invoke"`          // unix nanoseconds
+	Complete int64  `json:"complete"`        // unix nanoseconds
+	Value    string `json:"value,omitempty"` // hash written or read; empty for deletes and 404 reads
+	Result   Result `json:"result"`
+}
+
+// Recorder appends operations to a JSONL history file
+type Recorder struct {
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+	mu   sync.Mutex
+}
+
+// NewRecorder creates a history file, truncating any existing one
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create history file: %w", err)
+	}
+
+	w := bufio.NewWriterSize(f, 256*1024)
+	return &Recorder{
+		file: f,
+		w:    w,
+		enc:  json.NewEncoder(w),
+	}, nil
+}
+
+// Record appends an operation that ran from invoke until now
+func (r *Recorder) Record(key string, kind Kind, invoke time.Time, value string, err error) {
+	op := Operation{
+		Key:      key,
+		Kind:     kind,
+		Invoke:   invoke.UnixNano(),
+		Complete: time.Now().UnixNano(),
+		Value:    value,
+		Result:   ResultOK,
+	}
+	if err != nil {
+		op.Result = ResultFail
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Encoding errors surface on Close through the buffered writer
+	_ = r.enc.Encode(op)
+}
+
+// Close flushes and closes the history file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.w.Flush(); err != nil {
+		r.file.Close()
+		return fmt.Errorf("failed to flush history: %w", err)
+	}
+	return r.file.Close()
+}
+
+// Read parses a JSONL history. A truncated final line is ignored.
+func Read(rd io.Reader) ([]Operation, error) {
+	var ops []Operation
+
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	lineNum := 0
+	var pending error
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		// Only the last line may be torn
+		if pending != nil {
+			return nil, pending
+		}
+
+		var op Operation
+		if err := json.Unmarshal(line, &op); err != nil {
+			pending = fmt.Errorf("invalid history record at line %d: %w", lineNum, err)
+			continue
+		}
+		ops = append(ops, op)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	return ops, nil
+}
+
+// Load reads the history file at path
+func Load(path string) ([]Operation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Partition groups operations by key
+func Partition(ops []Operation) map[string][]Operation {
+	byKey := make(map[string][]Operation)
+	for _, op := range ops {
+		byKey[op.Key] = append(byKey[op.Key], op)
+	}
+	return byKey
+}
@@ -0,0 +1,291 @@
+package manifest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Op is the kind of write recorded in the manifest
+type Op string
+
+const (
+	OpPut    Op = "put"
+	OpCopy   Op = "copy"
+	OpDelete Op = "delete"
+)
+
+// Record is a single manifest line. Timestamp is when the write completed and
+// Invoked when it was issued; records are appended in completion order.
+type Record struct {
+	Op        Op        `json:"op"`
+	Key       string    `json:"key"`
+	SrcKey    string    `json:"src_key,omitempty"`
+	VersionID string    `json:"version_id,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	SHA256    string    `json:"sha256,omitempty"`
+	Pattern   string    `json:"pattern,omitempty"`
+	Invoked   time.Time `json:"invoked"`
+	Timestamp time.Time `json:"ts"`
+}
+
+// Writer appends records to a JSONL manifest file
+type Writer struct {
+	file *os.File
+	enc  *json.Encoder
+	mu   sync.Mutex
+}
+
+// NewWriter opens a manifest file for appending, creating it if needed
+func NewWriter(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+
+	return &Writer{
+		file: f,
+		enc:  json.NewEncoder(f),
+	}, nil
+}
+
+// Append writes a record. Each record is written with a single write call
+// so that the manifest stays line-consistent if the process is killed.
+func (w *Writer) Append(rec Record) error {
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+	if rec.Invoked.IsZero() {
+		rec.Invoked = rec.Timestamp
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.enc.Encode(rec); err != nil {
+		return fmt.Errorf("failed to append manifest record: %w", err)
+	}
+	return nil
+}
+
+// Close syncs and closes the manifest file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to sync manifest: %w", err)
+	}
+	return w.file.Close()
+}
+
+// Entry is the expected state of a key after replaying the manifest: the
+// value of the last write to complete, and those of earlier writes that
+// overlapped it in time and so may have landed after it
+type Entry struct {
+	Key       string
+	VersionID string
+	Size      int64
+	SHA256    string
+	Deleted   bool
+	Invoked   time.Time
+	Timestamp time.Time
+
+	Overlapping []*Entry
+}
+
+// Values returns every value the key may hold, the last completed first
+func (e *Entry) Values() []*Entry {
+	return append([]*Entry{e}, e.Overlapping...)
+}
+
+// supersededValue is a value no longer possible for its key, but still
+// visible to copies issued before the write that replaced it completed
+type supersededValue struct {
+	entry *Entry
+	until time.Time
+}
+
+// State is the expected bucket contents derived from a manifest
+type State struct {
+	entries    map[string]*Entry
+	superseded map[string][]supersededValue
+}
+
+// Replay reads manifest records in order and returns the final expected
+// state. Records are applied in file order, which is completion order. A
+// write replaces the values of writes that completed before it was issued;
+// writes that overlapped it in time may have landed in either order, so
+// their values remain possible too. Copies take any value the source may
+// have held while the copy ran, unless the source was rewritten more than
+// once during the copy, and copies of keys with no known content are
+// dropped. Records without an invoke time, as written by older
+// versions, are taken to have been instantaneous. A truncated final line,
+// as left by a killed process, is ignored.
+func Replay(r io.Reader) (*State, error) {
+	s := &State{
+		entries:    make(map[string]*Entry),
+		superseded: make(map[string][]supersededValue),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	lineNum := 0
+	var pending error
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		// Only the last line may be torn
+		if pending != nil {
+			return nil, pending
+		}
+
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			pending = fmt.Errorf("invalid manifest record at line %d: %w", lineNum, err)
+			continue
+		}
+
+		s.apply(rec)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	return s, nil
+}
+
+// Load replays the manifest file at path
+func Load(path string) (*State, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer f.Close()
+
+	return Replay(f)
+}
+
+func (s *State) apply(rec Record) {
+	invoked := rec.Invoked
+	if invoked.IsZero() || invoked.After(rec.Timestamp) {
+		invoked = rec.Timestamp
+	}
+	value := func(size int64, hash string, deleted bool) *Entry {
+		return &Entry{
+			Key:       rec.Key,
+			VersionID: rec.VersionID,
+			Size:      size,
+			SHA256:    hash,
+			Deleted:   deleted,
+			Invoked:   invoked,
+			Timestamp: rec.Timestamp,
+		}
+	}
+
+	var values []*Entry
+	switch rec.Op {
+	case OpPut:
+		values = []*Entry{value(rec.Size, rec.SHA256, false)}
+
+	case OpCopy:
+		for _, src := range s.visible(rec.SrcKey, invoked) {
+			if !src.Deleted {
+				values = append(values, value(src.Size, src.SHA256, false))
+			}
+		}
+		if len(values) == 0 {
+			delete(s.entries, rec.Key)
+			delete(s.superseded, rec.Key)
+			return
+		}
+
+	case OpDelete:
+		values = []*Entry{value(0, "", true)}
+
+	default:
+		return
+	}
+
+	s.write(rec.Key, invoked, rec.Timestamp, values)
+}
+
+// write records a write to key issued at invoked and completed at done that
+// left one of values. Earlier values stay possible if their write completed
+// after this one was issued.
+func (s *State) write(key string, invoked, done time.Time, values []*Entry) {
+	var superseded []supersededValue
+	for _, v := range s.superseded[key] {
+		if !v.until.Before(invoked) {
+			superseded = append(superseded, v)
+		}
+	}
+
+	if prev, ok := s.entries[key]; ok {
+		for _, v := range prev.Values() {
+			if v.Timestamp.Before(invoked) {
+				superseded = append(superseded, supersededValue{entry: v, until: done})
+			} else {
+				values = append(values, v)
+			}
+		}
+	}
+
+	for _, v := range values {
+		v.Overlapping = nil
+	}
+	entry := values[0]
+	entry.Overlapping = values[1:]
+	s.entries[key] = entry
+
+	if len(superseded) > 0 {
+		s.superseded[key] = superseded
+	} else {
+		delete(s.superseded, key)
+	}
+}
+
+// visible returns the values key may have held for a read issued at from
+func (s *State) visible(key string, from time.Time) []*Entry {
+	var values []*Entry
+	if e, ok := s.entries[key]; ok {
+		values = e.Values()
+	}
+	for _, v := range s.superseded[key] {
+		if !v.until.Before(from) {
+			values = append(values, v.entry)
+		}
+	}
+	return values
+}
+
+// Entries returns all keys with a known expected state
+func (s *State) Entries() []*Entry {
+	entries := make([]*Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Get returns the expected state of a key
+func (s *State) Get(key string) (*Entry, bool) {
+	e, ok := s.entries[key]
+	return e, ok
+}
+
+// Len returns the number of keys with a known expected state
+func (s *State) Len() int {
+	return len(s.entries)
+}
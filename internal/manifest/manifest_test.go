@@ -0,0 +1,160 @@
+package manifest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	input := strings.Join([]string{
+		`{"op":"put","key":"a","size":10,"sha256":"aaa","ts":"2024-01-01T00:00:00Z"}`,
+		`{"op":"put","key":"b","size":20,"sha256":"bbb","ts":"2024-01-01T00:00:01Z"}`,
+		`{"op":"copy","key":"c","src_key":"a","ts":"2024-01-01T00:00:02Z"}`,
+		`{"op":"put","key":"a","size":11,"sha256":"aaa2","ts":"2024-01-01T00:00:03Z"}`,
+		`{"op":"delete","key":"b","ts":"2024-01-01T00:00:04Z"}`,
+		`{"op":"copy","key":"d","src_key":"missing","ts":"2024-01-01T00:00:05Z"}`,
+	}, "\n")
+
+	state, err := Replay(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Replay() failed: %v", err)
+	}
+
+	if state.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", state.Len())
+	}
+
+	a, ok := state.Get("a")
+	if !ok || a.SHA256 != "aaa2" || a.Size != 11 {
+		t.Errorf("entry a = %+v, want latest put", a)
+	}
+
+	b, ok := state.Get("b")
+	if !ok || !b.Deleted {
+		t.Errorf("entry b = %+v, want deleted", b)
+	}
+
+	c, ok := state.Get("c")
+	if !ok || c.SHA256 != "aaa" || c.Size != 10 {
+		t.Errorf("entry c = %+v, want source state at copy time", c)
+	}
+
+	if _, ok := state.Get("d"); ok {
+		t.Error("copy of unknown source should be dropped")
+	}
+}
+
+func TestReplayOverlapping(t *testing.T) {
+	input := strings.Join([]string{
+		// a: a put and a delete overlapped, then a later put overlapped only
+		// the delete
+		`{"op":"put","key":"a","size":1,"sha256":"a1","invoked":"2024-01-01T00:00:00Z","ts":"2024-01-01T00:00:02Z"}`,
+		`{"op":"delete","key":"a","invoked":"2024-01-01T00:00:01Z","ts":"2024-01-01T00:00:05Z"}`,
+		`{"op":"put","key":"a","size":3,"sha256":"a3","invoked":"2024-01-01T00:00:04Z","ts":"2024-01-01T00:00:06Z"}`,
+		// b: sequential writes
+		`{"op":"put","key":"b","size":1,"sha256":"b1","invoked":"2024-01-01T00:00:00Z","ts":"2024-01-01T00:00:01Z"}`,
+		`{"op":"delete","key":"b","invoked":"2024-01-01T00:00:02Z","ts":"2024-01-01T00:00:03Z"}`,
+		// c: copied from b while b was being deleted
+		`{"op":"put","key":"b","size":2,"sha256":"b2","invoked":"2024-01-01T00:00:04Z","ts":"2024-01-01T00:00:05Z"}`,
+		`{"op":"delete","key":"b","invoked":"2024-01-01T00:00:06Z","ts":"2024-01-01T00:00:08Z"}`,
+		`{"op":"copy","key":"c","src_key":"b","invoked":"2024-01-01T00:00:07Z","ts":"2024-01-01T00:00:09Z"}`,
+		// d: copied from b after the delete completed
+		`{"op":"copy","key":"d","src_key":"b","invoked":"2024-01-01T00:00:10Z","ts":"2024-01-01T00:00:11Z"}`,
+	}, "\n")
+
+	state, err := Replay(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Replay() failed: %v", err)
+	}
+
+	describe := func(e *Entry) []string {
+		var values []string
+		for _, v := range e.Values() {
+			if v.Deleted {
+				values = append(values, "deleted")
+			} else {
+				values = append(values, v.SHA256)
+			}
+		}
+		return values
+	}
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		// The first put completed before the last was issued
+		{"a", []string{"a3", "deleted"}},
+		{"b", []string{"deleted"}},
+		{"c", []string{"b2"}},
+	}
+
+	for _, tt := range tests {
+		e, ok := state.Get(tt.key)
+		if !ok {
+			t.Errorf("entry %s missing", tt.key)
+			continue
+		}
+		if got := describe(e); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("entry %s values = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+
+	if _, ok := state.Get("d"); ok {
+		t.Error("copy of a deleted source should be dropped")
+	}
+}
+
+func TestReplayTornLine(t *testing.T) {
+	input := `{"op":"put","key":"a","size":10,"sha256":"aaa","ts":"2024-01-01T00:00:00Z"}` + "\n" + `{"op":"put","ke`
+
+	state, err := Replay(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Replay() should ignore a torn final line: %v", err)
+	}
+	if state.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", state.Len())
+	}
+
+	corrupt := `{"op":"put","ke` + "\n" + `{"op":"put","key":"a","ts":"2024-01-01T00:00:00Z"}`
+	if _, err := Replay(strings.NewReader(corrupt)); err == nil {
+		t.Error("Replay() should fail on a corrupt line that is not last")
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.jsonl")
+
+	w, err := NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+	if err := w.Append(Record{Op: OpPut, Key: "k", Size: 5, SHA256: "h"}); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	// Reopening appends rather than truncating
+	w, err = NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+	if err := w.Append(Record{Op: OpDelete, Key: "k"}); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	state, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	e, ok := state.Get("k")
+	if !ok || !e.Deleted {
+		t.Errorf("entry k = %+v, want deleted", e)
+	}
+}
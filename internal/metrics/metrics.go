@@ -2,6 +2,8 @@ package metrics
 
 import (
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -18,13 +20,27 @@ type Metrics struct {
 	BytesWritten prometheus.Counter
 	BytesRead    prometheus.Counter
 
+	// Successful transfers by size class
+	SizeClassOps     *prometheus.CounterVec
+	SizeClassBytes   *prometheus.CounterVec
+	SizeClassLatency *prometheus.HistogramVec
+
+	// Batch deletes
+	BatchDeleteObjects *prometheus.CounterVec
+
 	// Verification
 	VerifyFailures prometheus.Counter
 	VerifyTotal    prometheus.Counter
 
+	// Checksum validation of downloads and completed uploads
+	ChecksumValidations *prometheus.CounterVec
+
 	// Retries
 	Retries *prometheus.CounterVec
 
+	// Consistency checking
+	ConsistencyViolations *prometheus.CounterVec
+
 	// Workers
 	ActiveWorkers prometheus.Gauge
 
@@ -34,7 +50,16 @@ type Metrics struct {
 	// Circuit breaker
 	CircuitBreakerOpen prometheus.Gauge
 
-	registry *prometheus.Registry
+	// Multipart uploads started by this run and not yet completed or aborted
+	MultipartUploadsOpen prometheus.Gauge
+
+	sizeClasses *sizeClassRecorder
+	registry    *prometheus.Registry
+
+	// Interval windows of exporters, and the worker count they report
+	windowsMu     sync.RWMutex
+	windows       []*Window
+	activeWorkers int64
 }
 
 // NewMetrics creates and registers all metrics
@@ -45,9 +70,9 @@ func NewMetrics() *Metrics {
 		OpsTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "s3_ops_total",
-				Help: "Total number of S3 operations by type and status",
+				Help: "Total number of S3 operations by type, status, encryption mode, checksum algorithm and storage class",
 			},
-			[]string{"op", "status"},
+			[]string{"op", "status", "sse", "checksum", "storage_class"},
 		),
 
 		OpLatency: prometheus.NewHistogramVec(
@@ -58,7 +83,7 @@ func NewMetrics() *Metrics {
 					0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0,
 				},
 			},
-			[]string{"op"},
+			[]string{"op", "sse", "checksum", "storage_class"},
 		),
 
 		BytesWritten: prometheus.NewCounter(
@@ -75,6 +100,41 @@ func NewMetrics() *Metrics {
 			},
 		),
 
+		SizeClassOps: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "s3_size_class_ops_total",
+				Help: "Total number of successful transfers by operation and size class",
+			},
+			[]string{"op", "size_class"},
+		),
+
+		SizeClassBytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "s3_size_class_bytes_total",
+				Help: "Total bytes of successful transfers by operation and size class",
+			},
+			[]string{"op", "size_class"},
+		),
+
+		SizeClassLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name: "s3_size_class_latency_seconds",
+				Help: "Latency of successful transfers by operation and size class in seconds",
+				Buckets: []float64{
+					0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0, 60.0, 120.0,
+				},
+			},
+			[]string{"op", "size_class"},
+		),
+
+		BatchDeleteObjects: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "s3_batch_delete_objects_total",
+				Help: "Total number of objects in batch deletes by result",
+			},
+			[]string{"result"},
+		),
+
 		VerifyFailures: prometheus.NewCounter(
 			prometheus.CounterOpts{
 				Name: "s3_verify_failures_total",
@@ -89,6 +149,14 @@ func NewMetrics() *Metrics {
 			},
 		),
 
+		ChecksumValidations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "s3_checksum_validations_total",
+				Help: "Total number of checksum validations by algorithm and result",
+			},
+			[]string{"algorithm", "result"},
+		),
+
 		Retries: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "s3_retries_total",
@@ -97,6 +165,14 @@ func NewMetrics() *Metrics {
 			[]string{"op"},
 		),
 
+		ConsistencyViolations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "s3_consistency_violations_total",
+				Help: "Total number of consistency violations by anomaly type",
+			},
+			[]string{"anomaly"},
+		),
+
 		ActiveWorkers: prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Name: "s3_active_workers",
@@ -118,6 +194,13 @@ func NewMetrics() *Metrics {
 			},
 		),
 
+		MultipartUploadsOpen: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "s3_multipart_uploads_open",
+				Help: "Number of multipart uploads started and not yet completed or aborted",
+			},
+		),
+
 		registry: reg,
 	}
 
@@ -127,12 +210,19 @@ func NewMetrics() *Metrics {
 		m.OpLatency,
 		m.BytesWritten,
 		m.BytesRead,
+		m.SizeClassOps,
+		m.SizeClassBytes,
+		m.SizeClassLatency,
+		m.BatchDeleteObjects,
 		m.VerifyFailures,
 		m.VerifyTotal,
+		m.ChecksumValidations,
 		m.Retries,
+		m.ConsistencyViolations,
 		m.ActiveWorkers,
 		m.RateLimiterTokens,
 		m.CircuitBreakerOpen,
+		m.MultipartUploadsOpen,
 	)
 
 	return m
@@ -143,10 +233,21 @@ func (m *Metrics) Handler() http.Handler {
 	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
 }
 
-// RecordOp records an operation with its status and latency
-func (m *Metrics) RecordOp(op string, status string, duration time.Duration) {
-	m.OpsTotal.WithLabelValues(op, status).Inc()
-	m.OpLatency.WithLabelValues(op).Observe(duration.Seconds())
+// OpLabels are the request settings operations are broken down by
+type OpLabels struct {
+	SSE      string
+	Checksum string
+
+	// StorageClass is the class written, or the class reported for reads.
+	// It is empty for the bucket default and for ops without an object.
+	StorageClass string
+}
+
+// RecordOp records an operation with its status, labels and latency
+func (m *Metrics) RecordOp(op string, status string, labels OpLabels, duration time.Duration) {
+	m.OpsTotal.WithLabelValues(op, status, labels.SSE, labels.Checksum, labels.StorageClass).Inc()
+	m.OpLatency.WithLabelValues(op, labels.SSE, labels.Checksum, labels.StorageClass).Observe(duration.Seconds())
+	m.recordWindows(op, status == string(StatusSuccess), duration)
 }
 
 // RecordBytesWritten records bytes written
@@ -159,10 +260,47 @@ func (m *Metrics) RecordBytesRead(bytes int64) {
 	m.BytesRead.Add(float64(bytes))
 }
 
+// SetSizeClasses sets the size classes transfers are recorded by. It must
+// be called before any transfer is recorded; without it none are.
+func (m *Metrics) SetSizeClasses(classes *SizeClasses) {
+	m.sizeClasses = &sizeClassRecorder{classes: classes}
+}
+
+// RecordTransfer records a successful operation that transferred size
+// bytes, in its size class when size classes are set
+func (m *Metrics) RecordTransfer(op string, size int64, duration time.Duration) {
+	m.addWindowBytes(op, size)
+	if m.sizeClasses == nil {
+		return
+	}
+
+	class := m.sizeClasses.classes.Class(size)
+	m.SizeClassOps.WithLabelValues(op, class).Inc()
+	m.SizeClassBytes.WithLabelValues(op, class).Add(float64(size))
+	m.SizeClassLatency.WithLabelValues(op, class).Observe(duration.Seconds())
+	m.sizeClasses.add(op, class, size, duration)
+}
+
+// SizeClassStats returns the totals of transfers by operation and size
+// class, or nil without size classes
+func (m *Metrics) SizeClassStats() []SizeClassStats {
+	if m.sizeClasses == nil {
+		return nil
+	}
+	return m.sizeClasses.stats()
+}
+
+// RecordBatchDelete records the per-object outcome of a batch delete
+func (m *Metrics) RecordBatchDelete(deleted, failed int) {
+	m.BatchDeleteObjects.WithLabelValues("deleted").Add(float64(deleted))
+	m.BatchDeleteObjects.WithLabelValues("failed").Add(float64(failed))
+}
+
 // RecordVerifyFailure records a verification failure
 func (m *Metrics) RecordVerifyFailure() {
 	m.VerifyFailures.Inc()
 	m.VerifyTotal.Inc()
+	m.addWindowVerifyFailure()
 }
 
 // RecordVerifySuccess records a successful verification
@@ -170,14 +308,39 @@ func (m *Metrics) RecordVerifySuccess() {
 	m.VerifyTotal.Inc()
 }
 
+// RecordChecksumValidation records the result of a checksum validation:
+// "success", "mismatch" or "skipped"
+func (m *Metrics) RecordChecksumValidation(algorithm, result string) {
+	m.ChecksumValidations.WithLabelValues(algorithm, result).Inc()
+}
+
 // RecordRetry records a retry
 func (m *Metrics) RecordRetry(op string) {
 	m.Retries.WithLabelValues(op).Inc()
+	m.addWindowRetry(op)
+}
+
+// RecordConsistencyViolation records a consistency violation
+func (m *Metrics) RecordConsistencyViolation(anomaly string) {
+	m.ConsistencyViolations.WithLabelValues(anomaly).Inc()
 }
 
 // SetActiveWorkers sets the number of active workers
 func (m *Metrics) SetActiveWorkers(count int) {
 	m.ActiveWorkers.Set(float64(count))
+	atomic.StoreInt64(&m.activeWorkers, int64(count))
+}
+
+// WorkerStarted counts a worker that started issuing operations
+func (m *Metrics) WorkerStarted() {
+	m.ActiveWorkers.Inc()
+	atomic.AddInt64(&m.activeWorkers, 1)
+}
+
+// WorkerStopped counts a worker that stopped
+func (m *Metrics) WorkerStopped() {
+	m.ActiveWorkers.Dec()
+	atomic.AddInt64(&m.activeWorkers, -1)
 }
 
 // SetRateLimiterTokens sets the rate limiter token count
@@ -194,6 +357,11 @@ func (m *Metrics) SetCircuitBreakerOpen(open bool) {
 	}
 }
 
+// SetMultipartUploadsOpen sets the number of open multipart uploads
+func (m *Metrics) SetMultipartUploadsOpen(count int) {
+	m.MultipartUploadsOpen.Set(float64(count))
+}
+
 // OpStatus represents operation status
 type OpStatus string
 
@@ -202,6 +370,10 @@ const (
 	StatusError   OpStatus = "error"
 	StatusTimeout OpStatus = "timeout"
 	StatusRetry   OpStatus = "retry"
+
+	// StatusChecksumMismatch marks requests the server rejected because
+	// the payload did not match the checksum sent with it
+	StatusChecksumMismatch OpStatus = "checksum_mismatch"
 )
 
 // OpType represents operation type
@@ -215,4 +387,35 @@ const (
 	OpList         OpType = "list"
 	OpHead         OpType = "head"
 	OpMultipartPut OpType = "multipart_put"
+	OpRangeGet     OpType = "range_get"
+	OpMultiDelete  OpType = "multi_delete"
+
+	// OpListFull is a whole listing walked page by page; each page is
+	// also recorded as a list
+	OpListFull OpType = "list_full"
+
+	// OpMultipartCopy builds an object from ranges of others with UploadPartCopy
+	OpMultipartCopy OpType = "multipart_copy"
+
+	// Versioning operations
+	OpOverwrite     OpType = "overwrite"
+	OpGetVersion    OpType = "get_version"
+	OpListVersions  OpType = "list_versions"
+	OpDeleteVersion OpType = "delete_version"
+
+	// Tagging and metadata operations
+	OpPutTagging          OpType = "put_tagging"
+	OpGetTagging          OpType = "get_tagging"
+	OpDeleteTagging       OpType = "delete_tagging"
+	OpCopyReplaceMetadata OpType = "copy_replace_metadata"
+
+	// OpTransition changes an object's storage class with an in-place copy
+	OpTransition OpType = "transition"
+
+	// OpAbortMultipart is only issued by cleanup and shutdown
+	OpAbortMultipart OpType = "abort_multipart"
+
+	// OpListUploads is a page of incomplete multipart uploads, listed by
+	// cleanup
+	OpListUploads OpType = "list_uploads"
 )
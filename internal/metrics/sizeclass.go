@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SizeClasses groups operations by the number of bytes they transfer, so
+// that the latency of small and large objects is not averaged together
+type SizeClasses struct {
+	bounds []int64  // exclusive upper bounds, ascending
+	labels []string // one per bound, plus one for larger sizes
+}
+
+// NewSizeClasses creates size classes from ascending upper bounds and the
+// names to show for them, such as "64KiB". A size falls in the first class
+// whose bound it is below, labeled "<64KiB"; sizes of at least the last
+// bound are labeled ">=128MiB".
+func NewSizeClasses(bounds []int64, names []string) *SizeClasses {
+	s := &SizeClasses{bounds: bounds}
+	for _, name := range names {
+		s.labels = append(s.labels, "<"+name)
+	}
+	s.labels = append(s.labels, ">="+names[len(names)-1])
+	return s
+}
+
+// Class returns the label of the class size falls in
+func (s *SizeClasses) Class(size int64) string {
+	i := sort.Search(len(s.bounds), func(i int) bool { return size < s.bounds[i] })
+	return s.labels[i]
+}
+
+// Labels returns the class labels from smallest to largest
+func (s *SizeClasses) Labels() []string {
+	return s.labels
+}
+
+// sizeClassKey identifies the totals of one operation in one size class
+type sizeClassKey struct {
+	op    string
+	class string
+}
+
+// sizeClassTotals accumulates the successful operations of one size class
+// for the run report
+type sizeClassTotals struct {
+	ops     int64
+	bytes   int64
+	latency int64 // summed nanoseconds
+}
+
+// SizeClassStats summarizes an operation's successful requests in one size
+// class
+type SizeClassStats struct {
+	Op          string
+	Class       string
+	Ops         int64
+	Bytes       int64
+	MeanLatency time.Duration
+}
+
+// OpsPerSec returns the operation rate over elapsed
+func (s SizeClassStats) OpsPerSec(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Ops) / elapsed.Seconds()
+}
+
+// MBPerSec returns the throughput in megabytes (10^6 bytes) per second
+// over elapsed
+func (s SizeClassStats) MBPerSec(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / 1e6 / elapsed.Seconds()
+}
+
+// sizeClassRecorder records sized operations by class for Prometheus and
+// the run report. Totals are created once per operation and class and then
+// updated without locking.
+type sizeClassRecorder struct {
+	classes *SizeClasses
+	totals  sync.Map // sizeClassKey -> *sizeClassTotals
+}
+
+func (r *sizeClassRecorder) add(op, class string, size int64, duration time.Duration) {
+	key := sizeClassKey{op: op, class: class}
+	v, ok := r.totals.Load(key)
+	if !ok {
+		v, _ = r.totals.LoadOrStore(key, &sizeClassTotals{})
+	}
+	t := v.(*sizeClassTotals)
+	atomic.AddInt64(&t.ops, 1)
+	atomic.AddInt64(&t.bytes, size)
+	atomic.AddInt64(&t.latency, int64(duration))
+}
+
+// stats returns the totals ordered by operation and size class
+func (r *sizeClassRecorder) stats() []SizeClassStats {
+	order := make(map[string]int)
+	for i, label := range r.classes.Labels() {
+		order[label] = i
+	}
+
+	var stats []SizeClassStats
+	r.totals.Range(func(k, v any) bool {
+		key := k.(sizeClassKey)
+		t := v.(*sizeClassTotals)
+		s := SizeClassStats{
+			Op:    key.op,
+			Class: key.class,
+			Ops:   atomic.LoadInt64(&t.ops),
+			Bytes: atomic.LoadInt64(&t.bytes),
+		}
+		if s.Ops > 0 {
+			s.MeanLatency = time.Duration(atomic.LoadInt64(&t.latency) / s.Ops)
+		}
+		stats = append(stats, s)
+		return true
+	})
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Op != stats[j].Op {
+			return stats[i].Op < stats[j].Op
+		}
+		return order[stats[i].Class] < order[stats[j].Class]
+	})
+	return stats
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSizeClassesClass(t *testing.T) {
+	classes := NewSizeClasses([]int64{64 << 10, 1 << 20, 16 << 20}, []string{"64KiB", "1MiB", "16MiB"})
+
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "<64KiB"},
+		{64<<10 - 1, "<64KiB"},
+		{64 << 10, "<1MiB"},
+		{1 << 20, "<16MiB"},
+		{16<<20 - 1, "<16MiB"},
+		{16 << 20, ">=16MiB"},
+		{1 << 40, ">=16MiB"},
+	}
+
+	for _, tt := range tests {
+		if got := classes.Class(tt.size); got != tt.want {
+			t.Errorf("Class(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestRecordTransfer(t *testing.T) {
+	m := NewMetrics()
+	m.RecordTransfer("put", 1024, time.Millisecond) // before size classes: ignored
+	if stats := m.SizeClassStats(); stats != nil {
+		t.Fatalf("SizeClassStats() = %v without size classes", stats)
+	}
+
+	m.SetSizeClasses(NewSizeClasses([]int64{1 << 20}, []string{"1MiB"}))
+	m.RecordTransfer("put", 4<<20, 30*time.Millisecond)
+	m.RecordTransfer("put", 1024, 10*time.Millisecond)
+	m.RecordTransfer("put", 3072, 20*time.Millisecond)
+	m.RecordTransfer("get", 2048, 5*time.Millisecond)
+
+	want := []SizeClassStats{
+		{Op: "get", Class: "<1MiB", Ops: 1, Bytes: 2048, MeanLatency: 5 * time.Millisecond},
+		{Op: "put", Class: "<1MiB", Ops: 2, Bytes: 4096, MeanLatency: 15 * time.Millisecond},
+		{Op: "put", Class: ">=1MiB", Ops: 1, Bytes: 4 << 20, MeanLatency: 30 * time.Millisecond},
+	}
+
+	got := m.SizeClassStats()
+	if len(got) != len(want) {
+		t.Fatalf("SizeClassStats() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("SizeClassStats()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if rate := got[1].OpsPerSec(2 * time.Second); rate != 1 {
+		t.Errorf("OpsPerSec() = %v, want 1", rate)
+	}
+	if rate := got[2].MBPerSec(time.Second); rate != float64(4<<20)/1e6 {
+		t.Errorf("MBPerSec() = %v, want %v", rate, float64(4<<20)/1e6)
+	}
+}
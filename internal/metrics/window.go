@@ -0,0 +1,349 @@
+package metrics
+
+import (
+	"math/bits"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Latencies are kept in log-linear buckets: each power of two from about a
+// microsecond up is split into latencySubBuckets, so quantiles are exact to
+// within 1/latencySubBuckets of their value
+const (
+	latencySubBits    = 4
+	latencySubBuckets = 1 << latencySubBits
+	latencyMinExp     = 10 // 1024ns
+	latencyMaxExp     = 40 // about 18 minutes
+	latencyBuckets    = (latencyMaxExp - latencyMinExp + 1) * latencySubBuckets
+)
+
+// latencyBucket returns the bucket d falls in
+func latencyBucket(d time.Duration) int {
+	ns := uint64(d)
+	if ns < 1<<latencyMinExp {
+		return 0
+	}
+	exp := bits.Len64(ns) - 1
+	if exp > latencyMaxExp {
+		return latencyBuckets - 1
+	}
+	sub := int(ns>>(exp-latencySubBits)) & (latencySubBuckets - 1)
+	return (exp-latencyMinExp)*latencySubBuckets + sub
+}
+
+// latencyBucketUpper returns the exclusive upper bound of bucket i
+func latencyBucketUpper(i int) time.Duration {
+	exp := i/latencySubBuckets + latencyMinExp
+	sub := i % latencySubBuckets
+	return time.Duration(uint64(latencySubBuckets+sub+1) << (exp - latencySubBits))
+}
+
+// opWindow accumulates one operation's requests during a window interval
+type opWindow struct {
+	ops     int64
+	errors  int64
+	retries int64
+	bytes   int64
+	max     int64 // nanoseconds
+	latency [latencyBuckets]int64
+}
+
+func (w *opWindow) observe(d time.Duration) {
+	atomic.AddInt64(&w.latency[latencyBucket(d)], 1)
+	for {
+		max := atomic.LoadInt64(&w.max)
+		if int64(d) <= max || atomic.CompareAndSwapInt64(&w.max, max, int64(d)) {
+			return
+		}
+	}
+}
+
+// windowData is what a window accumulates between two samples
+type windowData struct {
+	start          time.Time
+	ops            sync.Map // op -> *opWindow
+	verifyFailures int64
+	writers        int64 // recorders still adding to this interval
+}
+
+func (d *windowData) op(op string) *opWindow {
+	v, ok := d.ops.Load(op)
+	if !ok {
+		v, _ = d.ops.LoadOrStore(op, &opWindow{})
+	}
+	return v.(*opWindow)
+}
+
+// Window accumulates the requests recorded between samples, so that
+// exporters can report what happened in each interval of a run. Every
+// window sees every request; each consumer takes its own.
+type Window struct {
+	cur     atomic.Pointer[windowData]
+	metrics *Metrics
+}
+
+// OpWindowStats summarizes an operation's requests in one interval
+type OpWindowStats struct {
+	Ops     int64 // completed requests, including failed ones
+	Errors  int64
+	Retries int64
+	Bytes   int64 // transferred by successful requests
+	P50     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+
+	latency *[latencyBuckets]int64 // for merging intervals
+}
+
+// OpsPerSec returns the request rate over interval
+func (s OpWindowStats) OpsPerSec(interval time.Duration) float64 {
+	if interval <= 0 {
+		return 0
+	}
+	return float64(s.Ops) / interval.Seconds()
+}
+
+// MiBPerSec returns the throughput in MiB per second over interval
+func (s OpWindowStats) MiBPerSec(interval time.Duration) float64 {
+	if interval <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / (1 << 20) / interval.Seconds()
+}
+
+// WindowStats summarizes one interval of a window
+type WindowStats struct {
+	Start time.Time
+	End   time.Time
+
+	Ops   map[string]OpWindowStats // by operation
+	Total OpWindowStats            // all operations together
+
+	VerifyFailures int64
+	ActiveWorkers  int // at the end of the interval
+}
+
+// Interval returns the length of the interval
+func (s *WindowStats) Interval() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// OpNames returns the operations with requests in the interval, sorted
+func (s *WindowStats) OpNames() []string {
+	names := make([]string, 0, len(s.Ops))
+	for op := range s.Ops {
+		names = append(names, op)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewWindow starts a window that accumulates requests from now on
+func (m *Metrics) NewWindow() *Window {
+	w := &Window{metrics: m}
+	w.cur.Store(&windowData{start: time.Now()})
+
+	m.windowsMu.Lock()
+	m.windows = append(m.windows, w)
+	m.windowsMu.Unlock()
+	return w
+}
+
+// Sample returns the requests recorded since the previous sample, or since
+// the window started, and starts a new interval
+//
+// Recorders that loaded the old interval before the swap may still be
+// adding to it, so Sample waits for them to finish first; see acquire. No
+// update is lost, but one operation is recorded in several calls, such as
+// RecordOp and then RecordTransfer, and those can fall on either side of a
+// sample: its latency may count in one interval and its bytes in the next.
+// That is accepted, since it evens out over the intervals of a run.
+func (w *Window) Sample() WindowStats {
+	now := time.Now()
+	d := w.cur.Swap(&windowData{start: now})
+	for atomic.LoadInt64(&d.writers) > 0 {
+		runtime.Gosched()
+	}
+
+	stats := WindowStats{
+		Start:          d.start,
+		End:            now,
+		Ops:            make(map[string]OpWindowStats),
+		VerifyFailures: atomic.LoadInt64(&d.verifyFailures),
+		ActiveWorkers:  int(atomic.LoadInt64(&w.metrics.activeWorkers)),
+	}
+
+	var total opWindow
+	d.ops.Range(func(k, v any) bool {
+		s := summarize(v.(*opWindow))
+		stats.Ops[k.(string)] = s
+		total.add(s)
+		return true
+	})
+	stats.Total = summarize(&total)
+
+	return stats
+}
+
+// MergeWindows combines consecutive intervals, oldest first, into one
+// spanning them all, such as the last few seconds of a run for a rolling
+// view
+func MergeWindows(samples []WindowStats) WindowStats {
+	merged := WindowStats{Ops: make(map[string]OpWindowStats)}
+	if len(samples) == 0 {
+		return merged
+	}
+	merged.Start = samples[0].Start
+	merged.End = samples[len(samples)-1].End
+	merged.ActiveWorkers = samples[len(samples)-1].ActiveWorkers
+
+	ops := make(map[string]*opWindow)
+	var total opWindow
+	for _, s := range samples {
+		merged.VerifyFailures += s.VerifyFailures
+		for name, o := range s.Ops {
+			if ops[name] == nil {
+				ops[name] = &opWindow{}
+			}
+			ops[name].add(o)
+		}
+		total.add(s.Total)
+	}
+
+	for name, ow := range ops {
+		merged.Ops[name] = summarize(ow)
+	}
+	merged.Total = summarize(&total)
+	return merged
+}
+
+// add accumulates a summarized interval. It is not safe for concurrent use.
+func (w *opWindow) add(s OpWindowStats) {
+	w.ops += s.Ops
+	w.errors += s.Errors
+	w.retries += s.Retries
+	w.bytes += s.Bytes
+	if int64(s.Max) > w.max {
+		w.max = int64(s.Max)
+	}
+	if s.latency != nil {
+		for i, c := range s.latency {
+			w.latency[i] += c
+		}
+	}
+}
+
+// summarize computes the stats of an operation's interval
+func summarize(w *opWindow) OpWindowStats {
+	s := OpWindowStats{
+		Ops:     atomic.LoadInt64(&w.ops),
+		Errors:  atomic.LoadInt64(&w.errors),
+		Retries: atomic.LoadInt64(&w.retries),
+		Bytes:   atomic.LoadInt64(&w.bytes),
+		Max:     time.Duration(atomic.LoadInt64(&w.max)),
+	}
+
+	counts := new([latencyBuckets]int64)
+	var n int64
+	for i := range w.latency {
+		counts[i] = atomic.LoadInt64(&w.latency[i])
+		n += counts[i]
+	}
+	s.P50 = quantile(counts, n, 0.50, s.Max)
+	s.P99 = quantile(counts, n, 0.99, s.Max)
+	s.latency = counts
+	return s
+}
+
+// quantile returns the upper bound of the bucket holding quantile q of n
+// latencies, at most max
+func quantile(counts *[latencyBuckets]int64, n int64, q float64, max time.Duration) time.Duration {
+	if n == 0 {
+		return 0
+	}
+	rank := int64(q*float64(n) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			if upper := latencyBucketUpper(i); upper < max {
+				return upper
+			}
+			return max
+		}
+	}
+	return max
+}
+
+// recordWindows adds a completed request to every window
+func (m *Metrics) recordWindows(op string, success bool, duration time.Duration) {
+	for _, w := range m.loadWindows() {
+		d := w.acquire()
+		ow := d.op(op)
+		atomic.AddInt64(&ow.ops, 1)
+		if !success {
+			atomic.AddInt64(&ow.errors, 1)
+		}
+		ow.observe(duration)
+		d.release()
+	}
+}
+
+// addWindowBytes adds the bytes of a successful transfer to every window
+func (m *Metrics) addWindowBytes(op string, size int64) {
+	for _, w := range m.loadWindows() {
+		d := w.acquire()
+		atomic.AddInt64(&d.op(op).bytes, size)
+		d.release()
+	}
+}
+
+// addWindowRetry counts a retried operation in every window
+func (m *Metrics) addWindowRetry(op string) {
+	for _, w := range m.loadWindows() {
+		d := w.acquire()
+		atomic.AddInt64(&d.op(op).retries, 1)
+		d.release()
+	}
+}
+
+// addWindowVerifyFailure counts a verification failure in every window
+func (m *Metrics) addWindowVerifyFailure() {
+	for _, w := range m.loadWindows() {
+		d := w.acquire()
+		atomic.AddInt64(&d.verifyFailures, 1)
+		d.release()
+	}
+}
+
+// acquire returns the window's current interval, counted as a writer until
+// released so that Sample does not summarise it under the caller. A
+// recorder that loses a race with Sample adds to the new interval instead.
+func (w *Window) acquire() *windowData {
+	for {
+		d := w.cur.Load()
+		atomic.AddInt64(&d.writers, 1)
+		if w.cur.Load() == d {
+			return d
+		}
+		atomic.AddInt64(&d.writers, -1)
+	}
+}
+
+// release ends an update started with acquire
+func (d *windowData) release() {
+	atomic.AddInt64(&d.writers, -1)
+}
+
+func (m *Metrics) loadWindows() []*Window {
+	m.windowsMu.RLock()
+	defer m.windowsMu.RUnlock()
+	return m.windows
+}
@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLatencyBucket(t *testing.T) {
+	tests := []time.Duration{
+		2 * time.Microsecond,
+		3 * time.Microsecond,
+		time.Millisecond,
+		1500 * time.Microsecond,
+		37 * time.Millisecond,
+		time.Second,
+		90 * time.Second,
+	}
+
+	for _, d := range tests {
+		i := latencyBucket(d)
+		upper := latencyBucketUpper(i)
+		if d >= upper {
+			t.Errorf("latencyBucket(%v) = %d with upper bound %v", d, i, upper)
+		}
+		if i > 0 && d < latencyBucketUpper(i-1) {
+			t.Errorf("latencyBucket(%v) = %d, but it is below bucket %d's bound", d, i, i-1)
+		}
+		if float64(upper-d) > float64(d)/latencySubBuckets {
+			t.Errorf("bucket bound %v of %v is off by more than 1/%d", upper, d, latencySubBuckets)
+		}
+	}
+
+	if i := latencyBucket(0); i != 0 {
+		t.Errorf("latencyBucket(0) = %d, want 0", i)
+	}
+	if i := latencyBucket(time.Hour); i != latencyBuckets-1 {
+		t.Errorf("latencyBucket(1h) = %d, want %d", i, latencyBuckets-1)
+	}
+}
+
+func TestWindowSample(t *testing.T) {
+	m := NewMetrics()
+	m.RecordOp("put", "success", OpLabels{}, time.Millisecond) // before the window: ignored
+	w := m.NewWindow()
+
+	m.SetActiveWorkers(4)
+	for i := 1; i <= 100; i++ {
+		m.RecordOp("get", "success", OpLabels{}, time.Duration(i)*time.Millisecond)
+		m.RecordTransfer("get", 1<<20, time.Duration(i)*time.Millisecond)
+	}
+	m.RecordOp("put", "error", OpLabels{}, 200*time.Millisecond)
+	m.RecordRetry("put")
+	m.RecordVerifyFailure()
+
+	s := w.Sample()
+	if s.ActiveWorkers != 4 || s.VerifyFailures != 1 {
+		t.Errorf("ActiveWorkers, VerifyFailures = %d, %d, want 4, 1", s.ActiveWorkers, s.VerifyFailures)
+	}
+	if names := s.OpNames(); len(names) != 2 || names[0] != "get" || names[1] != "put" {
+		t.Errorf("OpNames() = %v, want [get put]", names)
+	}
+
+	get := s.Ops["get"]
+	if get.Ops != 100 || get.Errors != 0 || get.Bytes != 100<<20 || get.Max != 100*time.Millisecond {
+		t.Errorf("get = %+v", get)
+	}
+	// Quantiles are bucket bounds, within 1/16 above the exact value
+	if get.P50 < 50*time.Millisecond || get.P50 > 54*time.Millisecond {
+		t.Errorf("get P50 = %v, want about 50ms", get.P50)
+	}
+	if get.P99 < 99*time.Millisecond || get.P99 > 100*time.Millisecond {
+		t.Errorf("get P99 = %v, want about 99ms", get.P99)
+	}
+
+	put := s.Ops["put"]
+	if put.Ops != 1 || put.Errors != 1 || put.Retries != 1 || put.P50 != 200*time.Millisecond {
+		t.Errorf("put = %+v", put)
+	}
+
+	if s.Total.Ops != 101 || s.Total.Errors != 1 || s.Total.Max != 200*time.Millisecond {
+		t.Errorf("Total = %+v", s.Total)
+	}
+	if rate := get.MiBPerSec(2 * time.Second); rate != 50 {
+		t.Errorf("MiBPerSec() = %v, want 50", rate)
+	}
+
+	// The next interval starts empty
+	s = w.Sample()
+	if len(s.Ops) != 0 || s.Total.Ops != 0 || s.Total.P99 != 0 {
+		t.Errorf("second Sample() = %+v, want no requests", s)
+	}
+}
+
+func TestWindowsIndependent(t *testing.T) {
+	m := NewMetrics()
+	a := m.NewWindow()
+	b := m.NewWindow()
+
+	m.RecordOp("get", "success", OpLabels{}, time.Millisecond)
+	if s := a.Sample(); s.Total.Ops != 1 {
+		t.Errorf("first window saw %d requests, want 1", s.Total.Ops)
+	}
+
+	m.RecordOp("get", "success", OpLabels{}, time.Millisecond)
+	if s := b.Sample(); s.Total.Ops != 2 {
+		t.Errorf("second window saw %d requests, want 2", s.Total.Ops)
+	}
+}
+
+func TestMergeWindows(t *testing.T) {
+	m := NewMetrics()
+	w := m.NewWindow()
+
+	// 90 fast GETs, then 10 slow ones in a later interval
+	for i := 0; i < 90; i++ {
+		m.RecordOp("get", "success", OpLabels{}, time.Millisecond)
+	}
+	first := w.Sample()
+	m.SetActiveWorkers(2)
+	for i := 0; i < 10; i++ {
+		m.RecordOp("get", "error", OpLabels{}, time.Second)
+	}
+	m.RecordVerifyFailure()
+	second := w.Sample()
+
+	merged := MergeWindows([]WindowStats{first, second})
+	if !merged.Start.Equal(first.Start) || !merged.End.Equal(second.End) {
+		t.Errorf("merged interval = %v to %v, want %v to %v", merged.Start, merged.End, first.Start, second.End)
+	}
+	if merged.ActiveWorkers != 2 || merged.VerifyFailures != 1 {
+		t.Errorf("ActiveWorkers, VerifyFailures = %d, %d, want 2, 1", merged.ActiveWorkers, merged.VerifyFailures)
+	}
+
+	get := merged.Ops["get"]
+	if get.Ops != 100 || get.Errors != 10 || get.Max != time.Second {
+		t.Errorf("get = %+v", get)
+	}
+	// The median is a fast request and the 99th percentile a slow one,
+	// which neither interval shows alone
+	if get.P50 > 2*time.Millisecond || get.P99 != time.Second {
+		t.Errorf("get P50, P99 = %v, %v, want about 1ms, 1s", get.P50, get.P99)
+	}
+	if merged.Total.Ops != 100 || merged.Total.P99 != time.Second {
+		t.Errorf("Total = %+v", merged.Total)
+	}
+
+	if empty := MergeWindows(nil); len(empty.Ops) != 0 || empty.Total.Ops != 0 {
+		t.Errorf("MergeWindows(nil) = %+v", empty)
+	}
+}
+
+func TestWindowSampleWhileRecording(t *testing.T) {
+	m := NewMetrics()
+	w := m.NewWindow()
+
+	const recorders, perRecorder = 8, 2000
+	var wg sync.WaitGroup
+	for i := 0; i < recorders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perRecorder; j++ {
+				m.RecordOp("put", string(StatusSuccess), OpLabels{}, time.Millisecond)
+				m.RecordTransfer("put", 10, time.Millisecond)
+			}
+		}()
+	}
+
+	// Every update lands in exactly one sample
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var samples []WindowStats
+	for sampling := true; sampling; {
+		select {
+		case <-done:
+			sampling = false
+		default:
+		}
+		samples = append(samples, w.Sample())
+	}
+
+	total := MergeWindows(samples).Ops["put"]
+	if want := int64(recorders * perRecorder); total.Ops != want || total.Bytes != 10*want {
+		t.Errorf("sampled %d ops and %d bytes, want %d and %d", total.Ops, total.Bytes, want, 10*want)
+	}
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/history"
+	"go.uber.org/zap"
+)
+
+// runAnalyzeHistory checks a recorded history for linearizability
+func (r *Runner) runAnalyzeHistory() error {
+	ops, err := history.Load(r.cfg.AnalyzeHistory)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %w", err)
+	}
+
+	r.logger.Info("analyzing history",
+		zap.String("file", r.cfg.AnalyzeHistory),
+		zap.Int("operations", len(ops)),
+	)
+
+	start := time.Now()
+	results := history.Check(ops, history.DefaultBudget)
+
+	var violations, unknown int
+	for _, res := range results {
+		switch {
+		case res.Unknown:
+			unknown++
+			r.logger.Warn("linearizability check inconclusive",
+				zap.String("key", res.Key),
+				zap.Int("operations", res.Ops),
+			)
+
+		case !res.Linearizable:
+			violations++
+			r.logger.Warn("history not linearizable",
+				zap.String("key", res.Key),
+				zap.Int("operations", res.Ops),
+				zap.Int("minimal_violation", len(res.Violation)),
+			)
+			for _, op := range res.Violation {
+				r.logger.Warn("  violating operation",
+					zap.String("key", op.Key),
+					zap.String("op", string(op.Kind)),
+					zap.Time("invoke", time.Unix(0, op.Invoke)),
+					zap.Time("complete", time.Unix(0, op.Complete)),
+					zap.String("value", op.Value),
+					zap.String("result", string(op.Result)),
+				)
+			}
+		}
+	}
+
+	r.logger.Info("history analysis completed",
+		zap.Int("keys", len(results)),
+		zap.Int("violations", violations),
+		zap.Int("inconclusive", unknown),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+
+	if violations > 0 {
+		return fmt.Errorf("history is not linearizable for %d of %d keys", violations, len(results))
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/data"
+	"github.com/paragkamble/s3bench/internal/manifest"
+	"github.com/paragkamble/s3bench/internal/s3"
+	"go.uber.org/zap"
+)
+
+// Audit exit code bits. Bit 0 is left for generic failures.
+const (
+	AuditExitMissing    = 1 << 1
+	AuditExitCorrupted  = 1 << 2
+	AuditExitUnexpected = 1 << 3
+	AuditExitIncomplete = 1 << 4
+)
+
+// AuditReport summarizes a durability audit
+type AuditReport struct {
+	Checked    int64
+	OK         int64
+	Missing    int64 // expected live, not found
+	Corrupted  int64 // found with wrong size or content
+	Unexpected int64 // expected deleted, still present
+	Errors     int64 // could not be checked
+}
+
+// ExitCode returns a process exit code with one bit set per finding class
+func (a AuditReport) ExitCode() int {
+	code := 0
+	if a.Missing > 0 {
+		code |= AuditExitMissing
+	}
+	if a.Corrupted > 0 {
+		code |= AuditExitCorrupted
+	}
+	if a.Unexpected > 0 {
+		code |= AuditExitUnexpected
+	}
+	if a.Errors > 0 {
+		code |= AuditExitIncomplete
+	}
+	return code
+}
+
+// AuditError is returned when an audit finds missing, corrupted or
+// unexpected objects, or could not check every object
+type AuditError struct {
+	Report AuditReport
+}
+
+func (e *AuditError) Error() string {
+	return fmt.Sprintf("audit failed: %d missing, %d corrupted, %d unexpected, %d errors",
+		e.Report.Missing, e.Report.Corrupted, e.Report.Unexpected, e.Report.Errors)
+}
+
+// ExitCode returns the process exit code for the audit result
+func (e *AuditError) ExitCode() int {
+	return e.Report.ExitCode()
+}
+
+// auditResult is the outcome of checking a single manifest entry
+type auditResult int
+
+const (
+	auditOK auditResult = iota
+	auditMissing
+	auditCorrupted
+	auditUnexpected
+	auditError
+)
+
+// runAudit verifies every object recorded in the manifest
+func (r *Runner) runAudit(ctx context.Context) error {
+	state, err := manifest.Load(r.cfg.ManifestFile)
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	r.logger.Info("running audit mode",
+		zap.String("manifest", r.cfg.ManifestFile),
+		zap.Int("keys", state.Len()),
+		zap.String("verify", r.cfg.AuditVerify),
+	)
+
+	start := time.Now()
+	var report AuditReport
+	entries := make(chan *manifest.Entry)
+	var wg sync.WaitGroup
+
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range entries {
+				result := r.auditEntry(ctx, entry)
+
+				atomic.AddInt64(&report.Checked, 1)
+				switch result {
+				case auditOK:
+					atomic.AddInt64(&report.OK, 1)
+				case auditMissing:
+					atomic.AddInt64(&report.Missing, 1)
+				case auditCorrupted:
+					atomic.AddInt64(&report.Corrupted, 1)
+				case auditUnexpected:
+					atomic.AddInt64(&report.Unexpected, 1)
+				case auditError:
+					atomic.AddInt64(&report.Errors, 1)
+				}
+			}
+		}()
+	}
+
+	for _, entry := range state.Entries() {
+		select {
+		case entries <- entry:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(entries)
+	wg.Wait()
+
+	r.logger.Info("audit completed",
+		zap.Int64("checked", report.Checked),
+		zap.Int64("ok", report.OK),
+		zap.Int64("missing", report.Missing),
+		zap.Int64("corrupted", report.Corrupted),
+		zap.Int64("unexpected", report.Unexpected),
+		zap.Int64("errors", report.Errors),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("audit interrupted after %d of %d keys: %w", report.Checked, state.Len(), ctx.Err())
+	}
+
+	if report.ExitCode() != 0 {
+		return &AuditError{Report: report}
+	}
+
+	return nil
+}
+
+// auditEntry checks a single key against its expected state. Writes that
+// overlapped in time may have landed in either order, so the key passes if
+// it holds any of its possible values.
+func (r *Runner) auditEntry(ctx context.Context, entry *manifest.Entry) auditResult {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.OpTimeout)
+	defer cancel()
+
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	values := entry.Values()
+	mayBeDeleted, mayBeLive := false, false
+	for _, v := range values {
+		if v.Deleted {
+			mayBeDeleted = true
+		} else {
+			mayBeLive = true
+		}
+	}
+
+	// Deleted keys only need to be absent
+	verify := r.cfg.AuditVerify
+	if !mayBeLive {
+		verify = "head"
+	}
+
+	var size int64
+	var actualHash string
+	err := s3.WithRetry(ctx, retryCfg, r.logger, verify, func(ctx context.Context) error {
+		if verify == "head" {
+			var err error
+			_, size, err = r.s3Client.HeadObject(ctx, entry.Key)
+			return err
+		}
+
+		body, _, n, err := r.s3Client.GetObject(ctx, entry.Key)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		size = n
+		actualHash, err = data.ComputeHash(body)
+		return err
+	})
+
+	switch {
+	case err == nil:
+	case s3.IsNotFound(err):
+		if mayBeDeleted {
+			return auditOK
+		}
+		r.logger.Warn("audit: object missing",
+			zap.String("key", entry.Key),
+			zap.String("version_id", entry.VersionID),
+			zap.Time("written_at", entry.Timestamp),
+		)
+		return auditMissing
+	default:
+		r.logger.Warn("audit: check failed", zap.String("key", entry.Key), zap.Error(err))
+		return auditError
+	}
+
+	if !mayBeLive {
+		r.logger.Warn("audit: deleted object still present",
+			zap.String("key", entry.Key),
+			zap.Time("deleted_at", entry.Timestamp),
+		)
+		return auditUnexpected
+	}
+
+	for _, v := range values {
+		if !v.Deleted && size == v.Size && (actualHash == "" || actualHash == v.SHA256) {
+			return auditOK
+		}
+	}
+
+	r.logger.Warn("audit: object corrupted",
+		zap.String("key", entry.Key),
+		zap.String("version_id", entry.VersionID),
+		zap.Int64("expected_size", entry.Size),
+		zap.Int64("actual_size", size),
+		zap.String("expected_sha256", entry.SHA256),
+		zap.String("actual_sha256", actualHash),
+		zap.Int("possible_values", len(values)),
+	)
+	return auditCorrupted
+}
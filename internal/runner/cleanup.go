@@ -0,0 +1,844 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/data"
+	"github.com/paragkamble/s3bench/internal/manifest"
+	"github.com/paragkamble/s3bench/internal/s3"
+	"go.uber.org/zap"
+)
+
+// cleanupProgressInterval is how often cleanup logs progress
+const cleanupProgressInterval = 10 * time.Second
+
+// cleanupCandidate is a listed key waiting to be deleted
+type cleanupCandidate struct {
+	key     string
+	matched bool // recognized from the manifest or key template
+	verify  bool // confirm created-by metadata with a HEAD first
+}
+
+// versionCandidate is a listed version or delete marker
+type versionCandidate struct {
+	s3.ObjectVersion
+	verify bool // confirm created-by metadata with a HEAD first
+}
+
+// versionGroup holds every version and delete marker of one key
+type versionGroup struct {
+	key      string
+	matched  bool
+	versions []versionCandidate
+}
+
+// cleanupBatch is a unit of work for a cleanup worker: keys for an
+// unversioned cleanup, or whole keys' version groups for a versioned one
+type cleanupBatch struct {
+	page       int
+	candidates []cleanupCandidate
+	groups     []versionGroup
+}
+
+// cleanupStats counts cleanup progress. In a versioned cleanup, deleted
+// counts versions and delete markers together.
+type cleanupStats struct {
+	listed   int64
+	headed   int64
+	deleted  int64
+	skipped  int64 // not created by s3bench, or already gone
+	failed   int64
+	versions int64 // object versions deleted
+	markers  int64 // delete markers removed
+	bytes    int64 // size of deleted versions
+}
+
+// cleanupLister feeds listed keys to the cleanup workers
+type cleanupLister struct {
+	known      *manifest.State
+	rng        *rand.Rand
+	checkpoint *cleanupCheckpoint
+	batches    chan<- cleanupBatch
+	stats      *cleanupStats
+}
+
+// cleanupCheckpoint tracks which listing pages are fully processed, so the
+// checkpoint never moves past a key whose batch is still in flight
+type cleanupCheckpoint struct {
+	path      string
+	mu        sync.Mutex
+	remaining map[int]int    // batches left per page
+	lastKey   map[int]string // last listed key per page
+	next      int            // lowest page not yet complete
+	logger    *zap.Logger
+}
+
+func newCleanupCheckpoint(path string, logger *zap.Logger) *cleanupCheckpoint {
+	return &cleanupCheckpoint{
+		path:      path,
+		remaining: make(map[int]int),
+		lastKey:   make(map[int]string),
+		logger:    logger,
+	}
+}
+
+// register records a listed page and the number of batches it was split into
+func (c *cleanupCheckpoint) register(page int, lastKey string, batches int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remaining[page] = batches
+	c.lastKey[page] = lastKey
+	c.advance()
+}
+
+// done records that one batch of page was fully processed. A batch that
+// failed or was interrupted is never done, which holds the checkpoint before
+// its page so that a resumed cleanup lists it again.
+func (c *cleanupCheckpoint) done(page int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remaining[page]--
+	c.advance()
+}
+
+// finish records the outcome of a batch of page: done only if every object
+// in it was handled and the cleanup is still running
+func (c *cleanupCheckpoint) finish(ctx context.Context, page int, ok bool) {
+	if ok && ctx.Err() == nil {
+		c.done(page)
+	}
+}
+
+func (c *cleanupCheckpoint) advance() {
+	key := ""
+	for {
+		rem, ok := c.remaining[c.next]
+		if !ok || rem > 0 {
+			break
+		}
+		if c.lastKey[c.next] != "" {
+			key = c.lastKey[c.next]
+		}
+		delete(c.remaining, c.next)
+		delete(c.lastKey, c.next)
+		c.next++
+	}
+
+	if key == "" || c.path == "" {
+		return
+	}
+	if err := saveCheckpoint(c.path, key); err != nil {
+		c.logger.Warn("failed to save cleanup checkpoint", zap.Error(err))
+	}
+}
+
+// loadCheckpoint returns the key to resume after, or "" if there is no checkpoint
+func loadCheckpoint(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read cleanup checkpoint: %w", err)
+	}
+	return strings.TrimRight(string(b), "\n"), nil
+}
+
+// saveCheckpoint atomically replaces the checkpoint file
+func saveCheckpoint(path, key string) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(key+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// runCleanup deletes tool-created objects under the prefix. Listing pages
+// are split into batches deleted by a worker pool. Keys recognized from the
+// manifest or key template skip the created-by HEAD, except for a sampled
+// fraction; a sampled key that turns out not to be ours aborts the cleanup.
+func (r *Runner) runCleanup(ctx context.Context) error {
+	// Estimated object count, for the ETA
+	var expected int64
+	var known *manifest.State
+	switch r.cfg.CleanupMatch {
+	case "manifest":
+		state, err := manifest.Load(r.cfg.ManifestFile)
+		if err != nil {
+			return fmt.Errorf("failed to load manifest: %w", err)
+		}
+		known = state
+		for _, entry := range state.Entries() {
+			if !entry.Deleted {
+				expected++
+			}
+		}
+	case "template":
+		expected = int64(r.cfg.Keys)
+	}
+
+	startAfter, err := loadCheckpoint(r.cfg.CleanupCheckpoint)
+	if err != nil {
+		return err
+	}
+
+	if err := r.abortStaleUploads(ctx); err != nil {
+		return fmt.Errorf("cleanup failed: %w", err)
+	}
+
+	// Versioned buckets keep noncurrent versions and delete markers that a
+	// plain delete leaves behind
+	versioned := r.cfg.CleanupVersions || r.cfg.Versioning == "on"
+
+	r.logger.Info("running cleanup mode",
+		zap.String("prefix", r.cfg.Prefix),
+		zap.Bool("versions", versioned),
+		zap.String("match", r.cfg.CleanupMatch),
+		zap.Float64("verify_rate", r.cfg.CleanupVerifyRate),
+		zap.String("resume_after", startAfter),
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var abortErr error
+	var abortOnce sync.Once
+	abort := func(err error) {
+		abortOnce.Do(func() {
+			abortErr = err
+			cancel()
+		})
+	}
+
+	start := time.Now()
+	var stats cleanupStats
+	checkpoint := newCleanupCheckpoint(r.cfg.CleanupCheckpoint, r.logger)
+	batches := make(chan cleanupBatch, r.cfg.Concurrency)
+	var wg sync.WaitGroup
+
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				// Drain batches queued before an interruption without
+				// issuing requests or moving the checkpoint
+				if ctx.Err() != nil {
+					continue
+				}
+
+				var ok bool
+				if versioned {
+					ok = r.cleanupVersions(ctx, batch.groups, &stats, abort)
+				} else {
+					ok = r.cleanupKeys(ctx, batch.candidates, &stats, abort)
+				}
+				checkpoint.finish(ctx, batch.page, ok)
+			}
+		}()
+	}
+
+	stopProgress := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(cleanupProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.logCleanupProgress(&stats, expected, start)
+			case <-stopProgress:
+				return
+			}
+		}
+	}()
+
+	lister := &cleanupLister{
+		known:      known,
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		checkpoint: checkpoint,
+		batches:    batches,
+		stats:      &stats,
+	}
+
+	var listErr error
+	if versioned {
+		listErr = r.listCleanupVersions(ctx, lister, startAfter)
+	} else {
+		listErr = r.listCleanupKeys(ctx, lister, startAfter)
+	}
+
+	close(batches)
+	wg.Wait()
+	close(stopProgress)
+
+	fields := []zap.Field{
+		zap.Int64("listed", stats.listed),
+		zap.Int64("deleted", stats.deleted),
+		zap.Int64("skipped", stats.skipped),
+		zap.Int64("failed", stats.failed),
+		zap.Int64("head_checks", stats.headed),
+		zap.Duration("elapsed", time.Since(start)),
+	}
+	if versioned {
+		fields = append(fields,
+			zap.Int64("versions_deleted", stats.versions),
+			zap.Int64("markers_deleted", stats.markers),
+			zap.Int64("bytes_reclaimed", stats.bytes),
+		)
+	}
+	r.logger.Info("cleanup completed", fields...)
+
+	switch {
+	case abortErr != nil:
+		return fmt.Errorf("cleanup aborted: %w", abortErr)
+	case listErr != nil:
+		return fmt.Errorf("cleanup failed: %w", listErr)
+	case ctx.Err() != nil:
+		return fmt.Errorf("cleanup interrupted: %w", ctx.Err())
+	}
+
+	// Finished the listing, so the next cleanup starts from the beginning
+	if r.cfg.CleanupCheckpoint != "" {
+		if err := os.Remove(r.cfg.CleanupCheckpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+			r.logger.Warn("failed to remove cleanup checkpoint", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// abortStaleUploads aborts incomplete multipart uploads under the prefix
+// that were initiated longer than --cleanup-upload-age ago. Uploads carry no
+// readable metadata, so age is the only safety check.
+func (r *Runner) abortStaleUploads(ctx context.Context) error {
+	if r.cfg.CleanupUploadAge == 0 {
+		return nil
+	}
+
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	var uploads []s3.MultipartUploadInfo
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "list_uploads", func(ctx context.Context) error {
+		var err error
+		uploads, err = r.s3Client.ListMultipartUploads(ctx, r.cfg.Prefix)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	stale := staleUploads(uploads, time.Now().Add(-r.cfg.CleanupUploadAge))
+	recent := len(uploads) - len(stale)
+	var aborted, failed int
+	for _, u := range stale {
+		err := s3.WithRetry(ctx, retryCfg, r.logger, "abort_multipart", func(ctx context.Context) error {
+			return r.s3Client.AbortMultipartUpload(ctx, u.Key, u.UploadID)
+		})
+		if err != nil {
+			r.logger.Warn("failed to abort multipart upload during cleanup",
+				zap.String("key", u.Key),
+				zap.String("upload_id", u.UploadID),
+				zap.Error(err),
+			)
+			failed++
+			continue
+		}
+		aborted++
+	}
+
+	r.logger.Info("aborted stale multipart uploads",
+		zap.Int("found", len(uploads)),
+		zap.Int("aborted", aborted),
+		zap.Int("failed", failed),
+		zap.Int("too_recent", recent),
+		zap.Duration("min_age", r.cfg.CleanupUploadAge),
+	)
+
+	return nil
+}
+
+// staleUploads returns the uploads initiated before cutoff
+func staleUploads(uploads []s3.MultipartUploadInfo, cutoff time.Time) []s3.MultipartUploadInfo {
+	var stale []s3.MultipartUploadInfo
+	for _, u := range uploads {
+		if u.Initiated.Before(cutoff) {
+			stale = append(stale, u)
+		}
+	}
+	return stale
+}
+
+// cleanupMatches reports whether key is known to be tool-created without a HEAD
+func (r *Runner) cleanupMatches(known *manifest.State, key string) bool {
+	switch r.cfg.CleanupMatch {
+	case "manifest":
+		_, ok := known.Get(key)
+		return ok
+	case "template":
+		return r.keygen.MatchesRun(key)
+	}
+	return false
+}
+
+// sendBatch hands a batch to the workers unless the cleanup was cancelled
+func (l *cleanupLister) sendBatch(ctx context.Context, batch cleanupBatch) {
+	select {
+	case l.batches <- batch:
+	case <-ctx.Done():
+	}
+}
+
+// listCleanupKeys lists current objects and splits each page into batches
+func (r *Runner) listCleanupKeys(ctx context.Context, l *cleanupLister, startAfter string) error {
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	token := ""
+	for page := 0; ctx.Err() == nil; page++ {
+		var listing *s3.ListPage
+		err := s3.WithRetry(ctx, retryCfg, r.logger, "list", func(ctx context.Context) error {
+			var err error
+			listing, err = r.s3Client.ListObjectsPage(ctx, r.cfg.Prefix, startAfter, token)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		atomic.AddInt64(&l.stats.listed, int64(len(listing.Keys)))
+
+		var chunks [][]cleanupCandidate
+		var chunk []cleanupCandidate
+		for _, key := range listing.Keys {
+			matched := r.cleanupMatches(l.known, key)
+			chunk = append(chunk, cleanupCandidate{
+				key:     key,
+				matched: matched,
+				verify:  !matched || l.rng.Float64() < r.cfg.CleanupVerifyRate,
+			})
+			if len(chunk) == r.cfg.MultiDeleteBatch {
+				chunks = append(chunks, chunk)
+				chunk = nil
+			}
+		}
+		if len(chunk) > 0 {
+			chunks = append(chunks, chunk)
+		}
+
+		lastKey := ""
+		if len(listing.Keys) > 0 {
+			lastKey = listing.Keys[len(listing.Keys)-1]
+		}
+		l.checkpoint.register(page, lastKey, len(chunks))
+
+		for _, c := range chunks {
+			l.sendBatch(ctx, cleanupBatch{page: page, candidates: c})
+		}
+
+		if listing.NextToken == "" {
+			return nil
+		}
+		token = listing.NextToken
+	}
+	return nil
+}
+
+// listCleanupVersions lists versions and delete markers and groups them by
+// key. The checkpoint only records keys whose group was complete.
+func (r *Runner) listCleanupVersions(ctx context.Context, l *cleanupLister, startAfter string) error {
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	list := func(ctx context.Context, keyMarker, versionMarker string) (*s3.VersionPage, error) {
+		var listing *s3.VersionPage
+		err := s3.WithRetry(ctx, retryCfg, r.logger, "list_versions", func(ctx context.Context) error {
+			var err error
+			listing, err = r.s3Client.ListObjectVersionsPage(ctx, r.cfg.Prefix, keyMarker, versionMarker)
+			return err
+		})
+		if err == nil {
+			atomic.AddInt64(&l.stats.listed, int64(len(listing.Versions)))
+		}
+		return listing, err
+	}
+
+	return walkVersions(ctx, startAfter, list, func(page int, groups []versionGroup) {
+		for i := range groups {
+			g := &groups[i]
+			g.matched = r.cleanupMatches(l.known, g.key)
+			for j := range g.versions {
+				v := &g.versions[j]
+				v.verify = !v.DeleteMarker && (!g.matched || l.rng.Float64() < r.cfg.CleanupVerifyRate)
+			}
+		}
+
+		var chunks [][]versionGroup
+		var chunk []versionGroup
+		count := 0
+		for _, g := range groups {
+			chunk = append(chunk, g)
+			count += len(g.versions)
+			if count >= r.cfg.MultiDeleteBatch {
+				chunks = append(chunks, chunk)
+				chunk, count = nil, 0
+			}
+		}
+		if len(chunk) > 0 {
+			chunks = append(chunks, chunk)
+		}
+
+		lastKey := ""
+		if len(groups) > 0 {
+			lastKey = groups[len(groups)-1].key
+		}
+		l.checkpoint.register(page, lastKey, len(chunks))
+
+		for _, c := range chunks {
+			l.sendBatch(ctx, cleanupBatch{page: page, groups: c})
+		}
+	})
+}
+
+// walkVersions lists the versions after the key startAfter page by page
+// and passes each page's complete key groups to visit. A key's versions can
+// span pages, so the last group of a page is held back until the next page
+// shows whether it continues.
+func walkVersions(ctx context.Context, startAfter string,
+	list func(ctx context.Context, keyMarker, versionMarker string) (*s3.VersionPage, error),
+	visit func(page int, groups []versionGroup)) error {
+
+	keyMarker, versionMarker := startAfter, ""
+	var pending *versionGroup
+
+	for page := 0; ctx.Err() == nil; page++ {
+		listing, err := list(ctx, keyMarker, versionMarker)
+		if err != nil {
+			return err
+		}
+
+		var groups []versionGroup
+		for _, v := range listing.Versions {
+			if pending != nil && pending.key != v.Key {
+				groups = append(groups, *pending)
+				pending = nil
+			}
+			if pending == nil {
+				pending = &versionGroup{key: v.Key}
+			}
+			pending.versions = append(pending.versions, versionCandidate{ObjectVersion: v})
+		}
+
+		last := listing.NextKeyMarker == "" && listing.NextVersionIDMarker == ""
+		if last && pending != nil {
+			groups = append(groups, *pending)
+			pending = nil
+		}
+
+		visit(page, groups)
+
+		if last {
+			return nil
+		}
+		keyMarker, versionMarker = listing.NextKeyMarker, listing.NextVersionIDMarker
+	}
+	return nil
+}
+
+// cleanupKeys confirms the keys that need a created-by check and deletes
+// the rest with a single DeleteObjects request. It reports whether every
+// key was either deleted or deliberately kept.
+func (r *Runner) cleanupKeys(ctx context.Context, candidates []cleanupCandidate, stats *cleanupStats, abort func(error)) bool {
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	ok := true
+	objects := make([]s3.ObjectID, 0, len(candidates))
+	for _, c := range candidates {
+		if !c.verify {
+			objects = append(objects, s3.ObjectID{Key: c.key})
+			continue
+		}
+
+		var metadata map[string]string
+		err := s3.WithRetry(ctx, retryCfg, r.logger, "head", func(ctx context.Context) error {
+			var err error
+			metadata, _, err = r.s3Client.HeadObject(ctx, c.key)
+			return err
+		})
+		atomic.AddInt64(&stats.headed, 1)
+
+		if err != nil {
+			if s3.IsNotFound(err) {
+				atomic.AddInt64(&stats.skipped, 1)
+				continue
+			}
+			r.logger.Warn("failed to head object during cleanup",
+				zap.String("key", c.key),
+				zap.Error(err),
+			)
+			atomic.AddInt64(&stats.failed, 1)
+			ok = false
+			continue
+		}
+
+		if metadata[data.MetadataKeyCreatedBy] != data.MetadataValueCreatedBy {
+			atomic.AddInt64(&stats.skipped, 1)
+			if c.matched {
+				abort(fmt.Errorf("key %q matched by --cleanup-match %s was not created by s3bench",
+					c.key, r.cfg.CleanupMatch))
+				return false
+			}
+			continue
+		}
+
+		objects = append(objects, s3.ObjectID{Key: c.key})
+	}
+
+	if len(objects) == 0 {
+		return ok
+	}
+
+	var failures []s3.DeleteFailure
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "multi_delete", func(ctx context.Context) error {
+		var err error
+		failures, err = r.s3Client.DeleteObjects(ctx, objects, true)
+		return err
+	})
+	if err != nil {
+		r.logger.Warn("failed to delete batch during cleanup",
+			zap.Int("objects", len(objects)),
+			zap.Error(err),
+		)
+		atomic.AddInt64(&stats.failed, int64(len(objects)))
+		return false
+	}
+
+	failed := make(map[string]bool, len(failures))
+	for _, f := range failures {
+		failed[f.Key] = true
+		r.logger.Warn("failed to delete object during cleanup",
+			zap.String("key", f.Key),
+			zap.String("code", f.Code),
+			zap.String("message", f.Message),
+		)
+	}
+	for _, obj := range objects {
+		if !failed[obj.Key] {
+			r.recordManifest(manifest.Record{Op: manifest.OpDelete, Key: obj.Key})
+		}
+	}
+
+	atomic.AddInt64(&stats.deleted, int64(len(objects)-len(failures)))
+	atomic.AddInt64(&stats.failed, int64(len(failures)))
+	return ok && len(failures) == 0
+}
+
+// logCleanupProgress logs counts, throughput and, when the object count is
+// known, an ETA
+func (r *Runner) logCleanupProgress(stats *cleanupStats, expected int64, start time.Time) {
+	deleted := atomic.LoadInt64(&stats.deleted)
+	skipped := atomic.LoadInt64(&stats.skipped)
+	failed := atomic.LoadInt64(&stats.failed)
+
+	elapsed := time.Since(start)
+	rate := float64(deleted+skipped+failed) / elapsed.Seconds()
+
+	fields := []zap.Field{
+		zap.Int64("listed", atomic.LoadInt64(&stats.listed)),
+		zap.Int64("deleted", deleted),
+		zap.Int64("skipped", skipped),
+		zap.Int64("failed", failed),
+		zap.Float64("objects_per_sec", rate),
+		zap.Duration("elapsed", elapsed.Round(time.Second)),
+	}
+
+	if remaining := expected - deleted; expected > 0 && remaining > 0 && rate > 0 {
+		eta := time.Duration(float64(remaining) / rate * float64(time.Second))
+		fields = append(fields, zap.Duration("eta", eta.Round(time.Second)))
+	}
+
+	r.logger.Info("cleanup progress", fields...)
+}
+
+// versionVerdict is the outcome of checking a listed version
+type versionVerdict int
+
+const (
+	versionDelete versionVerdict = iota // created by s3bench
+	versionGone                         // already deleted
+	versionKeep                         // foreign or unverifiable
+)
+
+// planGroupDeletes returns the versions and delete markers of g to delete.
+// check confirms the versions marked for verification. Delete markers are
+// removed only when no version of the key is kept, so removing them never
+// resurrects someone else's data; kept reports whether one was. check
+// errors stop the plan.
+func planGroupDeletes(g versionGroup, check func(versionCandidate) (versionVerdict, error)) (objects []s3.ObjectVersion, markers int, kept bool, err error) {
+	var deleteMarkers []s3.ObjectVersion
+	for _, v := range g.versions {
+		if v.DeleteMarker {
+			deleteMarkers = append(deleteMarkers, v.ObjectVersion)
+			continue
+		}
+		if !v.verify {
+			objects = append(objects, v.ObjectVersion)
+			continue
+		}
+
+		verdict, err := check(v)
+		if err != nil {
+			return nil, 0, true, err
+		}
+		switch verdict {
+		case versionDelete:
+			objects = append(objects, v.ObjectVersion)
+		case versionKeep:
+			kept = true
+		}
+	}
+
+	if kept {
+		return objects, len(deleteMarkers), true, nil
+	}
+	return append(objects, deleteMarkers...), 0, false, nil
+}
+
+// cleanupVersions deletes the tool-created versions of each group, and
+// their delete markers as planGroupDeletes allows. It reports whether every
+// version was either deleted or deliberately kept.
+func (r *Runner) cleanupVersions(ctx context.Context, groups []versionGroup, stats *cleanupStats, abort func(error)) bool {
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	ok := true
+	var objects []s3.ObjectVersion
+	kept := make(map[string]bool)
+
+	for _, g := range groups {
+		check := func(v versionCandidate) (versionVerdict, error) {
+			var metadata map[string]string
+			err := s3.WithRetry(ctx, retryCfg, r.logger, "head", func(ctx context.Context) error {
+				var err error
+				metadata, _, err = r.s3Client.HeadObjectVersion(ctx, v.Key, v.VersionID)
+				return err
+			})
+			atomic.AddInt64(&stats.headed, 1)
+
+			if err != nil {
+				if s3.IsNotFound(err) {
+					atomic.AddInt64(&stats.skipped, 1)
+					return versionGone, nil
+				}
+				r.logger.Warn("failed to head object version during cleanup",
+					zap.String("key", v.Key),
+					zap.String("version_id", v.VersionID),
+					zap.Error(err),
+				)
+				atomic.AddInt64(&stats.failed, 1)
+				ok = false
+				return versionKeep, nil
+			}
+
+			if metadata[data.MetadataKeyCreatedBy] != data.MetadataValueCreatedBy {
+				atomic.AddInt64(&stats.skipped, 1)
+				if g.matched {
+					return versionKeep, fmt.Errorf("key %q version %q matched by --cleanup-match %s was not created by s3bench",
+						v.Key, v.VersionID, r.cfg.CleanupMatch)
+				}
+				return versionKeep, nil
+			}
+			return versionDelete, nil
+		}
+
+		deletes, markers, keep, err := planGroupDeletes(g, check)
+		if err != nil {
+			abort(err)
+			return false
+		}
+		if keep {
+			kept[g.key] = true
+			atomic.AddInt64(&stats.skipped, int64(markers))
+		}
+		objects = append(objects, deletes...)
+	}
+
+	for start := 0; start < len(objects); start += r.cfg.MultiDeleteBatch {
+		end := start + r.cfg.MultiDeleteBatch
+		if end > len(objects) {
+			end = len(objects)
+		}
+		chunk := objects[start:end]
+
+		ids := make([]s3.ObjectID, len(chunk))
+		for i, v := range chunk {
+			ids[i] = s3.ObjectID{Key: v.Key, VersionID: v.VersionID}
+		}
+
+		var failures []s3.DeleteFailure
+		err := s3.WithRetry(ctx, retryCfg, r.logger, "multi_delete", func(ctx context.Context) error {
+			var err error
+			failures, err = r.s3Client.DeleteObjects(ctx, ids, true)
+			return err
+		})
+		if err != nil {
+			r.logger.Warn("failed to delete batch during cleanup",
+				zap.Int("objects", len(ids)),
+				zap.Error(err),
+			)
+			atomic.AddInt64(&stats.failed, int64(len(ids)))
+			for _, v := range chunk {
+				kept[v.Key] = true
+			}
+			ok = false
+			continue
+		}
+
+		failed := make(map[s3.ObjectID]bool, len(failures))
+		for _, f := range failures {
+			failed[s3.ObjectID{Key: f.Key, VersionID: f.VersionID}] = true
+			kept[f.Key] = true
+			ok = false
+			r.logger.Warn("failed to delete object version during cleanup",
+				zap.String("key", f.Key),
+				zap.String("version_id", f.VersionID),
+				zap.String("code", f.Code),
+				zap.String("message", f.Message),
+			)
+		}
+
+		for i, v := range chunk {
+			if failed[ids[i]] {
+				atomic.AddInt64(&stats.failed, 1)
+				continue
+			}
+			atomic.AddInt64(&stats.deleted, 1)
+			if v.DeleteMarker {
+				atomic.AddInt64(&stats.markers, 1)
+			} else {
+				atomic.AddInt64(&stats.versions, 1)
+				atomic.AddInt64(&stats.bytes, v.Size)
+			}
+		}
+	}
+
+	for _, g := range groups {
+		if !kept[g.key] {
+			r.recordManifest(manifest.Record{Op: manifest.OpDelete, Key: g.key})
+		}
+	}
+	return ok
+}
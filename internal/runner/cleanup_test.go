@@ -0,0 +1,269 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/s3"
+	"go.uber.org/zap"
+)
+
+// versionPages lists pages in order, recording the markers each was asked for
+type versionPages struct {
+	pages   []s3.VersionPage
+	markers [][2]string
+}
+
+func (p *versionPages) list(ctx context.Context, keyMarker, versionMarker string) (*s3.VersionPage, error) {
+	page := p.pages[len(p.markers)]
+	p.markers = append(p.markers, [2]string{keyMarker, versionMarker})
+	return &page, nil
+}
+
+func groupKeys(groups []versionGroup) []string {
+	keys := make([]string, len(groups))
+	for i, g := range groups {
+		keys[i] = g.key
+	}
+	return keys
+}
+
+func TestWalkVersions(t *testing.T) {
+	p := &versionPages{pages: []s3.VersionPage{
+		{
+			Versions: []s3.ObjectVersion{
+				{Key: "a", VersionID: "a2", IsLatest: true},
+				{Key: "b", VersionID: "b3", IsLatest: true, DeleteMarker: true},
+				{Key: "b", VersionID: "b2"},
+			},
+			NextKeyMarker:       "b",
+			NextVersionIDMarker: "b2",
+		},
+		{
+			Versions: []s3.ObjectVersion{
+				{Key: "b", VersionID: "b1"},
+				{Key: "c", VersionID: "c1", IsLatest: true},
+			},
+		},
+	}}
+
+	var pages [][]versionGroup
+	err := walkVersions(context.Background(), "", p.list, func(page int, groups []versionGroup) {
+		if page != len(pages) {
+			t.Errorf("page %d visited as %d", len(pages), page)
+		}
+		pages = append(pages, groups)
+	})
+	if err != nil {
+		t.Fatalf("walkVersions() error = %v", err)
+	}
+
+	if len(pages) != 2 {
+		t.Fatalf("visited %d pages, want 2", len(pages))
+	}
+
+	// b spans the page boundary, so it is held back to the second page
+	if keys := groupKeys(pages[0]); len(keys) != 1 || keys[0] != "a" {
+		t.Errorf("first page groups = %v, want [a]", keys)
+	}
+	if keys := groupKeys(pages[1]); len(keys) != 2 || keys[0] != "b" || keys[1] != "c" {
+		t.Fatalf("second page groups = %v, want [b c]", keys)
+	}
+	b := pages[1][0]
+	if len(b.versions) != 3 || b.versions[0].VersionID != "b3" || b.versions[2].VersionID != "b1" {
+		t.Errorf("b versions = %+v, want b3, b2, b1", b.versions)
+	}
+
+	if p.markers[1] != [2]string{"b", "b2"} {
+		t.Errorf("second page listed after %v, want [b b2]", p.markers[1])
+	}
+}
+
+func TestWalkVersionsResume(t *testing.T) {
+	p := &versionPages{pages: []s3.VersionPage{
+		{Versions: []s3.ObjectVersion{{Key: "m", VersionID: "m1"}}},
+	}}
+
+	var keys []string
+	err := walkVersions(context.Background(), "k", p.list, func(page int, groups []versionGroup) {
+		keys = append(keys, groupKeys(groups)...)
+	})
+	if err != nil {
+		t.Fatalf("walkVersions() error = %v", err)
+	}
+
+	// A checkpoint key resumes after all of that key's versions
+	if p.markers[0] != [2]string{"k", ""} {
+		t.Errorf("first page listed after %v, want [k \"\"]", p.markers[0])
+	}
+	if len(keys) != 1 || keys[0] != "m" {
+		t.Errorf("groups = %v, want [m]", keys)
+	}
+}
+
+func TestWalkVersionsError(t *testing.T) {
+	listErr := errors.New("list failed")
+	list := func(ctx context.Context, keyMarker, versionMarker string) (*s3.VersionPage, error) {
+		return nil, listErr
+	}
+
+	visited := false
+	err := walkVersions(context.Background(), "", list, func(int, []versionGroup) { visited = true })
+	if !errors.Is(err, listErr) || visited {
+		t.Errorf("walkVersions() = %v, visited %v, want %v and no pages", err, visited, listErr)
+	}
+}
+
+func TestPlanGroupDeletes(t *testing.T) {
+	group := func(verify bool) versionGroup {
+		return versionGroup{key: "k", versions: []versionCandidate{
+			{ObjectVersion: s3.ObjectVersion{Key: "k", VersionID: "m1", DeleteMarker: true}},
+			{ObjectVersion: s3.ObjectVersion{Key: "k", VersionID: "v2"}, verify: verify},
+			{ObjectVersion: s3.ObjectVersion{Key: "k", VersionID: "v1"}},
+		}}
+	}
+
+	tests := []struct {
+		name        string
+		verify      bool
+		verdict     versionVerdict
+		wantDeletes []string
+		wantMarkers int
+		wantKept    bool
+	}{
+		{"not verified", false, versionKeep, []string{"v2", "v1", "m1"}, 0, false},
+		{"confirmed", true, versionDelete, []string{"v2", "v1", "m1"}, 0, false},
+		{"already deleted", true, versionGone, []string{"v1", "m1"}, 0, false},
+		// Removing the marker would expose the kept version
+		{"version kept", true, versionKeep, []string{"v1"}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checked := 0
+			deletes, markers, kept, err := planGroupDeletes(group(tt.verify), func(v versionCandidate) (versionVerdict, error) {
+				checked++
+				if v.VersionID != "v2" {
+					t.Errorf("checked %s, want only v2", v.VersionID)
+				}
+				return tt.verdict, nil
+			})
+			if err != nil {
+				t.Fatalf("planGroupDeletes() error = %v", err)
+			}
+
+			if tt.verify != (checked == 1) {
+				t.Errorf("checked %d versions with verify %v", checked, tt.verify)
+			}
+			var ids []string
+			for _, v := range deletes {
+				ids = append(ids, v.VersionID)
+			}
+			if len(ids) != len(tt.wantDeletes) {
+				t.Fatalf("deletes = %v, want %v", ids, tt.wantDeletes)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantDeletes[i] {
+					t.Errorf("deletes = %v, want %v", ids, tt.wantDeletes)
+					break
+				}
+			}
+			if markers != tt.wantMarkers || kept != tt.wantKept {
+				t.Errorf("markers, kept = %d, %v, want %d, %v", markers, kept, tt.wantMarkers, tt.wantKept)
+			}
+		})
+	}
+
+	checkErr := errors.New("foreign version")
+	_, _, _, err := planGroupDeletes(group(true), func(versionCandidate) (versionVerdict, error) {
+		return versionKeep, checkErr
+	})
+	if !errors.Is(err, checkErr) {
+		t.Errorf("planGroupDeletes() error = %v, want %v", err, checkErr)
+	}
+}
+
+func TestStaleUploads(t *testing.T) {
+	cutoff := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	uploads := []s3.MultipartUploadInfo{
+		{Key: "old", UploadID: "1", Initiated: cutoff.Add(-time.Hour)},
+		{Key: "recent", UploadID: "2", Initiated: cutoff.Add(time.Minute)},
+		{Key: "at-cutoff", UploadID: "3", Initiated: cutoff},
+		{Key: "older", UploadID: "4", Initiated: cutoff.Add(-48 * time.Hour)},
+	}
+
+	stale := staleUploads(uploads, cutoff)
+	if len(stale) != 2 || stale[0].Key != "old" || stale[1].Key != "older" {
+		t.Errorf("staleUploads() = %+v, want old and older", stale)
+	}
+}
+
+func TestCleanupCheckpoint(t *testing.T) {
+	checkpointAfter := func(t *testing.T, finish func(c *cleanupCheckpoint)) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "checkpoint")
+		c := newCleanupCheckpoint(path, zap.NewNop())
+		c.register(0, "a", 1)
+		c.register(1, "b", 2)
+		c.register(2, "c", 1)
+		finish(c)
+
+		key, err := loadCheckpoint(path)
+		if err != nil {
+			t.Fatalf("loadCheckpoint() error = %v", err)
+		}
+		return key
+	}
+
+	ctx := context.Background()
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	tests := []struct {
+		name   string
+		finish func(c *cleanupCheckpoint)
+		want   string
+	}{
+		{"all done", func(c *cleanupCheckpoint) {
+			c.finish(ctx, 0, true)
+			c.finish(ctx, 1, true)
+			c.finish(ctx, 2, true)
+			c.finish(ctx, 1, true)
+		}, "c"},
+		{"in flight", func(c *cleanupCheckpoint) {
+			c.finish(ctx, 0, true)
+			c.finish(ctx, 1, true)
+			c.finish(ctx, 2, true)
+		}, "a"},
+		// A failed batch holds the checkpoint before its page for good
+		{"failed batch", func(c *cleanupCheckpoint) {
+			c.finish(ctx, 0, true)
+			c.finish(ctx, 1, false)
+			c.finish(ctx, 1, true)
+			c.finish(ctx, 2, true)
+		}, "a"},
+		// Batches interrupted by cancellation do not count, even if their
+		// requests happened to succeed
+		{"cancelled", func(c *cleanupCheckpoint) {
+			c.finish(ctx, 0, true)
+			c.finish(cancelled, 1, true)
+			c.finish(ctx, 1, true)
+			c.finish(cancelled, 2, true)
+		}, "a"},
+		{"nothing done", func(c *cleanupCheckpoint) {
+			c.finish(cancelled, 0, true)
+			c.finish(ctx, 1, true)
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkpointAfter(t, tt.finish); got != tt.want {
+				t.Errorf("checkpoint = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
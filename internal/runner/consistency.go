@@ -0,0 +1,57 @@
+package runner
+
+import (
+	"hash"
+
+	"github.com/paragkamble/s3bench/internal/consistency"
+	"go.uber.org/zap"
+)
+
+// reportViolation counts and logs a consistency violation
+func (r *Runner) reportViolation(v *consistency.Violation) {
+	r.metrics.RecordConsistencyViolation(string(v.Anomaly))
+	r.logger.Warn("consistency violation",
+		zap.String("anomaly", string(v.Anomaly)),
+		zap.String("key", v.Key),
+		zap.String("observed_sha256", v.Observed),
+		zap.String("expected_sha256", v.Expected),
+		zap.Time("baseline_ack", v.BaselineAck),
+		zap.Time("read_start", v.ReadStart),
+		zap.Time("read_end", v.ReadEnd),
+	)
+}
+
+// beginPut records the start of a PUT with the consistency tracker, if enabled
+func (r *Runner) beginPut(key, hash string) *consistency.Write {
+	if r.tracker == nil {
+		return nil
+	}
+	return r.tracker.BeginPut(key, hash)
+}
+
+// beginDelete records the start of a DELETE with the consistency tracker, if enabled
+func (r *Runner) beginDelete(key string) *consistency.Write {
+	if r.tracker == nil {
+		return nil
+	}
+	return r.tracker.BeginDelete(key)
+}
+
+// endWrite records the outcome of a tracked write
+func endWrite(w *consistency.Write, err error) {
+	if w != nil {
+		w.Done(err == nil)
+	}
+}
+
+// countingHash is a hash that also counts the bytes written to it
+type countingHash struct {
+	hash.Hash
+	n int64
+}
+
+func (c *countingHash) Write(p []byte) (int, error) {
+	n, err := c.Hash.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,183 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/data"
+	"github.com/paragkamble/s3bench/internal/history"
+	"github.com/paragkamble/s3bench/internal/manifest"
+	"github.com/paragkamble/s3bench/internal/s3"
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+// errNoCopySource is returned when none of the chosen objects can contribute
+// a range to a composed object
+var errNoCopySource = errors.New("no source object large enough to compose from")
+
+// executeMultipartCopy copies objects server-side with UploadPartCopy. With
+// one source the whole object is copied; with several, the destination is
+// composed from a byte range of each, the way compaction jobs merge objects.
+func (r *Runner) executeMultipartCopy(ctx context.Context, srcKey string, w *workerState) error {
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	// Small keyspaces may not have enough distinct keys
+	srcKeys := []string{srcKey}
+	for attempts := 0; len(srcKeys) < r.cfg.MultipartCopySources && attempts < 2*r.cfg.MultipartCopySources; attempts++ {
+		key := w.keys.Generate(w.scheduler.NextKey())
+		if !slices.Contains(srcKeys, key) {
+			srcKeys = append(srcKeys, key)
+		}
+	}
+
+	infos := make([]*s3.ObjectInfo, len(srcKeys))
+	for i, key := range srcKeys {
+		err := s3.WithRetry(ctx, retryCfg, r.logger, "head", func(ctx context.Context) error {
+			var err error
+			infos[i], err = r.s3Client.StatObject(ctx, key)
+			return err
+		})
+		if err != nil {
+			r.metrics.RecordRetry(string(workload.OpMultipartCopy))
+			return err
+		}
+	}
+
+	var sources []s3.CopySource
+	var metadata map[string]string
+	var size int64
+	var hash string
+	composed := len(srcKeys) > 1
+
+	if !composed {
+		// The source's metadata is kept, so the copy verifies like it
+		if infos[0].Size == 0 {
+			return errNoCopySource
+		}
+		sources = []s3.CopySource{{Key: srcKey, ETag: infos[0].ETag, Length: infos[0].Size}}
+		metadata = infos[0].Metadata
+	} else {
+		var segs []data.Segment
+		var err error
+		sources, segs, err = r.composeSources(srcKeys, infos, w.rng)
+		if err != nil {
+			return err
+		}
+
+		spec := data.EncodeSegments(segs)
+		if len(spec) > data.MaxSegmentsLen {
+			return fmt.Errorf("composed layout of %d segments is too long for metadata", len(segs))
+		}
+
+		reader, err := r.generator.GenerateSegments(segs)
+		if err != nil {
+			return fmt.Errorf("failed to generate composed data: %w", err)
+		}
+		if hash, err = data.ComputeHash(reader); err != nil {
+			return fmt.Errorf("failed to hash composed data: %w", err)
+		}
+
+		size = data.SegmentsLength(segs)
+		metadata = data.PrepareMetadata(hash, r.cfg.NamespaceTag)
+		metadata[data.MetadataKeySegments] = spec
+	}
+
+	dstKey := w.keys.Generate(w.scheduler.NextKey())
+
+	// Copied content is not tracked, so stop checking the destination
+	if r.tracker != nil {
+		r.tracker.Invalidate(dstKey)
+		defer r.tracker.Invalidate(dstKey)
+	}
+
+	opts := r.objectOptions(w)
+	invoke := time.Now()
+
+	var versionID string
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "multipart_copy", func(ctx context.Context) error {
+		var err error
+		versionID, err = r.s3Client.MultipartCopy(ctx, dstKey, sources, r.cfg.MultipartPartSize, r.cfg.MultipartMaxParts, metadata, opts)
+		return err
+	})
+	r.recordHistory(dstKey, history.KindCopy, invoke, "", err)
+
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpMultipartCopy))
+		return err
+	}
+
+	if !composed {
+		r.recordManifest(manifest.Record{
+			Op:        manifest.OpCopy,
+			Key:       dstKey,
+			SrcKey:    srcKey,
+			VersionID: versionID,
+			Invoked:   invoke,
+		})
+		return nil
+	}
+
+	r.recordManifest(manifest.Record{
+		Op:        manifest.OpPut,
+		Key:       dstKey,
+		VersionID: versionID,
+		Size:      size,
+		SHA256:    hash,
+		Pattern:   r.cfg.Pattern,
+		Invoked:   invoke,
+	})
+
+	return nil
+}
+
+// composeSources picks a random byte range of each source object and
+// returns the copy sources with the data layout of their concatenation.
+// Parts never span sources, so every range but the last is a whole number
+// of parts; objects smaller than a part can only end the composition.
+func (r *Runner) composeSources(keys []string, infos []*s3.ObjectInfo, rng *rand.Rand) ([]s3.CopySource, []data.Segment, error) {
+	partSize := r.cfg.MultipartPartSize
+
+	var sources []s3.CopySource
+	var segs []data.Segment
+	for i, key := range keys {
+		info := infos[i]
+		last := i == len(keys)-1
+
+		var length int64
+		switch {
+		case info.Size == 0:
+			continue
+		case last:
+			length = 1 + rng.Int63n(info.Size)
+		case info.Size >= partSize:
+			length = partSize * (1 + rng.Int63n(info.Size/partSize))
+		default:
+			continue
+		}
+		offset := rng.Int63n(info.Size - length + 1)
+
+		objSegs, err := data.ObjectSegments(key, info.Size, info.Metadata)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid layout of source %s: %w", key, err)
+		}
+		rangeSegs, err := data.SliceSegments(objSegs, offset, length)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, s := range rangeSegs {
+			segs = data.AppendSegment(segs, s)
+		}
+
+		sources = append(sources, s3.CopySource{Key: key, ETag: info.ETag, Offset: offset, Length: length})
+	}
+
+	if len(sources) == 0 {
+		return nil, nil, errNoCopySource
+	}
+	return sources, segs, nil
+}
@@ -0,0 +1,231 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// progressWindow is how far back progress rates and percentiles look
+const progressWindow = 10 * time.Second
+
+// progress follows a run for --progress. Rates and percentiles are over
+// the last progressWindow; error and retry counts are over the whole run.
+type progress struct {
+	start      time.Time
+	duration   time.Duration // planned length of the run, or 0
+	operations int64         // planned operation count, or 0
+	done       func() int64  // operations issued so far, with operations
+
+	recent  []metrics.WindowStats // oldest first
+	errors  map[string]int64      // by operation, for every operation seen
+	retries map[string]int64
+
+	totalErrors    int64
+	totalRetries   int64
+	verifyFailures int64
+}
+
+func newProgress(start time.Time, duration time.Duration, operations int64, done func() int64) *progress {
+	return &progress{
+		start:      start,
+		duration:   duration,
+		operations: operations,
+		done:       done,
+		errors:     make(map[string]int64),
+		retries:    make(map[string]int64),
+	}
+}
+
+// add counts an interval and returns the requests of the last
+// progressWindow
+func (p *progress) add(s metrics.WindowStats) metrics.WindowStats {
+	for op, o := range s.Ops {
+		p.errors[op] += o.Errors
+		p.retries[op] += o.Retries
+	}
+	p.totalErrors += s.Total.Errors
+	p.totalRetries += s.Total.Retries
+	p.verifyFailures += s.VerifyFailures
+
+	p.recent = append(p.recent, s)
+	for len(p.recent) > 1 && s.End.Sub(p.recent[0].End) >= progressWindow {
+		p.recent = p.recent[1:]
+	}
+	return metrics.MergeWindows(p.recent)
+}
+
+// remaining estimates the time left at now: what is left of the planned
+// duration, or of the operation count at the run's average rate
+func (p *progress) remaining(now time.Time) (time.Duration, bool) {
+	elapsed := now.Sub(p.start)
+	if p.duration > 0 {
+		if elapsed > p.duration {
+			return 0, true
+		}
+		return p.duration - elapsed, true
+	}
+	if p.operations > 0 && p.done != nil {
+		done := p.done()
+		if done == 0 {
+			return 0, false
+		}
+		left := p.operations - done
+		return time.Duration(float64(elapsed) * float64(left) / float64(done)), true
+	}
+	return 0, false
+}
+
+// ops returns every operation seen so far, sorted
+func (p *progress) ops() []string {
+	ops := make([]string, 0, len(p.errors))
+	for op := range p.errors {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// lines renders the progress panel for the interval ending at now
+func (p *progress) lines(rolling metrics.WindowStats, now time.Time) []string {
+	status := fmt.Sprintf("elapsed %s", now.Sub(p.start).Truncate(time.Second))
+	if remaining, ok := p.remaining(now); ok {
+		status += fmt.Sprintf("  remaining %s", remaining.Round(time.Second))
+	}
+	status += fmt.Sprintf("  workers %d  errors %d  retries %d  verify failures %d",
+		rolling.ActiveWorkers, p.totalErrors, p.totalRetries, p.verifyFailures)
+
+	interval := rolling.Interval()
+	row := func(op string, o metrics.OpWindowStats, errors, retries int64) string {
+		return fmt.Sprintf("%-22s %10.1f %10.1f %10s %10s %8d %8d",
+			op, o.OpsPerSec(interval), o.MiBPerSec(interval),
+			formatLatency(o.P50), formatLatency(o.P99), errors, retries)
+	}
+
+	lines := []string{
+		status,
+		fmt.Sprintf("%-22s %10s %10s %10s %10s %8s %8s", "op", "ops/s", "MiB/s", "p50", "p99", "errors", "retries"),
+		row("all", rolling.Total, p.totalErrors, p.totalRetries),
+	}
+	for _, op := range p.ops() {
+		lines = append(lines, row(op, rolling.Ops[op], p.errors[op], p.retries[op]))
+	}
+	return lines
+}
+
+// progressOp is an operation's entry in progress log lines
+type progressOp struct {
+	OpsPerSec float64 `json:"ops_per_sec"`
+	MiBPerSec float64 `json:"mib_per_sec"`
+	P50       string  `json:"p50"`
+	P99       string  `json:"p99"`
+	Errors    int64   `json:"errors"`
+	Retries   int64   `json:"retries"`
+}
+
+// fields renders the progress for a log line
+func (p *progress) fields(rolling metrics.WindowStats, now time.Time) []zap.Field {
+	interval := rolling.Interval()
+	fields := []zap.Field{zap.Duration("elapsed", now.Sub(p.start).Truncate(time.Second))}
+	if remaining, ok := p.remaining(now); ok {
+		fields = append(fields, zap.Duration("remaining", remaining.Round(time.Second)))
+	}
+
+	ops := make(map[string]progressOp)
+	for _, op := range p.ops() {
+		o := rolling.Ops[op]
+		ops[op] = progressOp{
+			OpsPerSec: round1(o.OpsPerSec(interval)),
+			MiBPerSec: round1(o.MiBPerSec(interval)),
+			P50:       formatLatency(o.P50),
+			P99:       formatLatency(o.P99),
+			Errors:    p.errors[op],
+			Retries:   p.retries[op],
+		}
+	}
+
+	return append(fields,
+		zap.Float64("ops_per_sec", round1(rolling.Total.OpsPerSec(interval))),
+		zap.Float64("mib_per_sec", round1(rolling.Total.MiBPerSec(interval))),
+		zap.Duration("p50", rolling.Total.P50),
+		zap.Duration("p99", rolling.Total.P99),
+		zap.Int64("errors", p.totalErrors),
+		zap.Int64("retries", p.totalRetries),
+		zap.Int64("verify_failures", p.verifyFailures),
+		zap.Int("active_workers", rolling.ActiveWorkers),
+		zap.Any("ops", ops),
+	)
+}
+
+// startProgress shows the run's progress every --progress-interval, if
+// enabled: as a panel redrawn in place when stdout is a terminal, as log
+// lines otherwise. The run is planned to last duration or issue operations,
+// of which done returns those issued; zero means unknown. The returned
+// function shows the final progress.
+func (r *Runner) startProgress(duration time.Duration, operations int64, done func() int64) func() {
+	if !r.cfg.Progress {
+		return func() {}
+	}
+
+	p := newProgress(time.Now(), duration, operations, done)
+	if !isTerminal(os.Stdout) {
+		return r.sampleEvery(r.cfg.ProgressInterval, func(s metrics.WindowStats) {
+			r.logger.Info("progress", p.fields(p.add(s), s.End)...)
+		})
+	}
+
+	drawn := 0
+	return r.sampleEvery(r.cfg.ProgressInterval, func(s metrics.WindowStats) {
+		drawn = redraw(os.Stdout, drawn, p.lines(p.add(s), s.End))
+	})
+}
+
+// redraw replaces the drawn lines last written to a terminal with lines and
+// returns how many it wrote
+func redraw(w io.Writer, drawn int, lines []string) int {
+	if drawn > 0 {
+		// Back to the first line of the panel
+		fmt.Fprintf(w, "\x1b[%dF", drawn)
+	}
+	for _, line := range lines {
+		fmt.Fprintf(w, "%s\x1b[K\n", line)
+	}
+	// Clear lines left over from a taller panel
+	fmt.Fprint(w, "\x1b[J")
+	return len(lines)
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatLatency formats a latency to three significant digits, or "-"
+// without requests
+func formatLatency(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "-"
+	case d < time.Millisecond:
+		return fmt.Sprintf("%.0fµs", float64(d)/float64(time.Microsecond))
+	case d < 10*time.Millisecond:
+		return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+	case d < 100*time.Millisecond:
+		return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+	case d < time.Second:
+		return fmt.Sprintf("%.0fms", float64(d)/float64(time.Millisecond))
+	default:
+		return fmt.Sprintf("%.2fs", d.Seconds())
+	}
+}
+
+func round1(f float64) float64 {
+	return math.Round(f*10) / 10
+}
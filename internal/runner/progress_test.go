@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/metrics"
+)
+
+// progressSample is a one-second interval ending at end
+func progressSample(end time.Time, ops map[string]metrics.OpWindowStats) metrics.WindowStats {
+	s := metrics.WindowStats{Start: end.Add(-time.Second), End: end, Ops: ops, ActiveWorkers: 4}
+	for _, o := range ops {
+		s.Total.Ops += o.Ops
+		s.Total.Errors += o.Errors
+		s.Total.Retries += o.Retries
+	}
+	return s
+}
+
+func TestProgressAdd(t *testing.T) {
+	start := time.Now()
+	p := newProgress(start, 0, 0, nil)
+
+	var rolling metrics.WindowStats
+	for i := 1; i <= 15; i++ {
+		ops := map[string]metrics.OpWindowStats{"get": {Ops: int64(i)}}
+		if i == 1 {
+			ops["put"] = metrics.OpWindowStats{Ops: 1, Errors: 1, Retries: 2}
+		}
+		rolling = p.add(progressSample(start.Add(time.Duration(i)*time.Second), ops))
+	}
+
+	// The last 10 seconds: GETs 6 to 15
+	if got := rolling.Interval(); got != 10*time.Second {
+		t.Errorf("rolling interval = %v, want 10s", got)
+	}
+	if got := rolling.Ops["get"].Ops; got != 105 {
+		t.Errorf("rolling GETs = %d, want 105", got)
+	}
+
+	// Errors and retries count from the start, and operations stay listed
+	if p.totalErrors != 1 || p.totalRetries != 2 || p.errors["put"] != 1 {
+		t.Errorf("errors, retries = %d, %d, want 1, 2", p.totalErrors, p.totalRetries)
+	}
+	if ops := p.ops(); len(ops) != 2 || ops[0] != "get" || ops[1] != "put" {
+		t.Errorf("ops() = %v, want [get put]", ops)
+	}
+}
+
+func TestProgressRemaining(t *testing.T) {
+	start := time.Now()
+	var done int64
+
+	tests := []struct {
+		name       string
+		duration   time.Duration
+		operations int64
+		done       int64
+		elapsed    time.Duration
+		want       time.Duration
+		wantOK     bool
+	}{
+		{"duration", 10 * time.Minute, 0, 0, 4 * time.Minute, 6 * time.Minute, true},
+		{"duration passed", time.Minute, 0, 0, 2 * time.Minute, 0, true},
+		{"operations", 0, 1000, 250, time.Minute, 3 * time.Minute, true},
+		{"operations not started", 0, 1000, 0, time.Second, 0, false},
+		{"unbounded", 0, 0, 0, time.Minute, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done = tt.done
+			p := newProgress(start, tt.duration, tt.operations, func() int64 { return done })
+			got, ok := p.remaining(start.Add(tt.elapsed))
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("remaining() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestProgressLines(t *testing.T) {
+	start := time.Now()
+	p := newProgress(start, time.Minute, 0, nil)
+	end := start.Add(15 * time.Second)
+
+	// 100 GETs in one second: 98 at 3ms and 2 failed at 40ms. The median
+	// shows as the bound of its latency bucket.
+	m := metrics.NewMetrics()
+	w := m.NewWindow()
+	m.SetActiveWorkers(4)
+	for i := 0; i < 100; i++ {
+		status, latency := metrics.StatusSuccess, 3*time.Millisecond
+		if i < 2 {
+			status, latency = metrics.StatusError, 40*time.Millisecond
+		}
+		m.RecordOp("get", string(status), metrics.OpLabels{}, latency)
+	}
+	m.RecordTransfer("get", 50<<20, 3*time.Millisecond)
+	s := w.Sample()
+	s.Start, s.End = end.Add(-time.Second), end
+
+	rolling := p.add(s)
+
+	lines := p.lines(rolling, end)
+	if len(lines) != 4 {
+		t.Fatalf("lines() = %q, want status, header, all and get", lines)
+	}
+	if want := "elapsed 15s  remaining 45s  workers 4  errors 2  retries 0  verify failures 0"; lines[0] != want {
+		t.Errorf("status = %q, want %q", lines[0], want)
+	}
+	for _, want := range []string{"get", "100.0", "50.0", "3.01ms", "40.0ms"} {
+		if !strings.Contains(lines[3], want) {
+			t.Errorf("get row %q does not contain %q", lines[3], want)
+		}
+	}
+}
+
+func TestRedraw(t *testing.T) {
+	var buf bytes.Buffer
+	drawn := redraw(&buf, 0, []string{"a", "b"})
+	if drawn != 2 || buf.String() != "a\x1b[K\nb\x1b[K\n\x1b[J" {
+		t.Errorf("first redraw = %d, %q", drawn, buf.String())
+	}
+
+	buf.Reset()
+	drawn = redraw(&buf, drawn, []string{"c"})
+	if drawn != 1 || buf.String() != "\x1b[2Fc\x1b[K\n\x1b[J" {
+		t.Errorf("second redraw = %d, %q", drawn, buf.String())
+	}
+}
+
+func TestFormatLatency(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "-"},
+		{850 * time.Microsecond, "850µs"},
+		{3200 * time.Microsecond, "3.20ms"},
+		{38912 * time.Microsecond, "38.9ms"},
+		{212 * time.Millisecond, "212ms"},
+		{2500 * time.Millisecond, "2.50s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatLatency(tt.d); got != tt.want {
+			t.Errorf("formatLatency(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
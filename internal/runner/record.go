@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/s3"
+	"github.com/paragkamble/s3bench/internal/trace"
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+// opNote collects what an operation learns about its object while it runs,
+// for the trace recorder
+type opNote struct {
+	key  string
+	size int64
+}
+
+type opNoteKey struct{}
+
+// withOpNote returns a context in which execute functions can note the key
+// and size of the object they operate on
+func withOpNote(ctx context.Context, key string) (context.Context, *opNote) {
+	note := &opNote{key: key}
+	return context.WithValue(ctx, opNoteKey{}, note), note
+}
+
+// noteSize records the size of the object written or read, when a trace is
+// being recorded
+func noteSize(ctx context.Context, size int64) {
+	if note, ok := ctx.Value(opNoteKey{}).(*opNote); ok {
+		note.size = size
+	}
+}
+
+// noteKey records that the operation chose a key other than the one it was
+// given
+func noteKey(ctx context.Context, key string) {
+	if note, ok := ctx.Value(opNoteKey{}).(*opNote); ok {
+		note.key = key
+	}
+}
+
+// recordTrace appends a completed operation to the trace, if recording
+func (r *Runner) recordTrace(worker int, op workload.OpType, note *opNote, invoke time.Time, err error) {
+	if r.trace == nil {
+		return
+	}
+
+	result := trace.ResultOK
+	switch {
+	case s3.IsNotFound(err):
+		result = trace.ResultNotFound
+	case err != nil:
+		result = trace.ResultError
+	}
+
+	// Keys are recorded relative to --prefix, which replay adds back
+	key := strings.TrimPrefix(note.key, r.cfg.Prefix)
+	if op == workload.OpList {
+		key = ""
+	}
+
+	r.trace.Record(invoke, trace.Event{
+		Op:      op,
+		Key:     key,
+		Size:    note.size,
+		Worker:  worker,
+		Result:  result,
+		Latency: time.Since(invoke),
+	})
+}
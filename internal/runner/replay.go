@@ -0,0 +1,292 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/trace"
+	"github.com/paragkamble/s3bench/internal/versions"
+	"github.com/paragkamble/s3bench/internal/workload"
+	"go.uber.org/zap"
+)
+
+// errUnknownOp is returned for operations that are not part of the workload,
+// such as misspelled ops in a hand-written trace
+var errUnknownOp = errors.New("unknown operation")
+
+// replayReport compares the timing of a replay with the trace
+type replayReport struct {
+	Events  int   // events in the trace
+	Issued  int64 // operations sent
+	Errors  int64
+	Skipped int64 // events and log entries that cannot be replayed
+
+	TraceDuration  time.Duration // from the first to the last traced event
+	TargetDuration time.Duration // trace duration divided by the speed
+	Elapsed        time.Duration // from the first to the last issued operation
+
+	// How late operations were issued relative to their scaled trace time
+	LagP50 time.Duration
+	LagP99 time.Duration
+	LagMax time.Duration
+}
+
+// AchievedSpeed returns the time scale the replay kept up with
+func (rep replayReport) AchievedSpeed() float64 {
+	if rep.Elapsed <= 0 {
+		return 0
+	}
+	return float64(rep.TraceDuration) / float64(rep.Elapsed)
+}
+
+// runReplay issues the operations of a trace at their traced times, scaled
+// by the replay speed. At most --concurrency operations are in flight; when
+// the server cannot keep up, operations start late and the lag is reported.
+func (r *Runner) runReplay(ctx context.Context) error {
+	format, err := trace.ParseFormat(r.cfg.ReplayFormat)
+	if err != nil {
+		return err
+	}
+	tr, err := trace.Load(r.cfg.Replay, format)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info("running replay mode",
+		zap.String("trace", r.cfg.Replay),
+		zap.Int("events", len(tr.Events)),
+		zap.Int("skipped", tr.Skipped),
+		zap.Duration("trace_duration", tr.Duration()),
+		zap.Float64("speed", r.cfg.ReplaySpeed),
+		zap.Int64("seed", r.seed),
+	)
+
+	// Count what cannot be replayed up front rather than dropping it
+	// quietly along the way
+	unknown := make(map[string]int)
+	versioned := false
+	for _, ev := range tr.Events {
+		switch {
+		case !ev.Op.Known():
+			unknown[string(ev.Op)]++
+		case ev.Op == workload.OpOverwrite || ev.Op == workload.OpGetVersion ||
+			ev.Op == workload.OpListVersions || ev.Op == workload.OpDeleteVersion:
+			versioned = true
+		}
+	}
+	if len(unknown) > 0 {
+		r.logger.Warn("trace contains operations that cannot be replayed; they are skipped",
+			zap.Any("ops", unknown),
+		)
+	}
+
+	// Versioning operations pick versions written earlier in the replay
+	if versioned && r.versions == nil {
+		r.versions = versions.NewChains(versions.DefaultLimit)
+	}
+
+	if r.cfg.ReplayPrepare {
+		if err := r.prepareReplay(ctx, tr.Events); err != nil {
+			r.abortOpenUploads()
+			return err
+		}
+	}
+
+	report := replayReport{
+		Events:         len(tr.Events),
+		Skipped:        int64(tr.Skipped),
+		TraceDuration:  tr.Duration(),
+		TargetDuration: time.Duration(float64(tr.Duration()) / r.cfg.ReplaySpeed),
+	}
+
+	// Each slot owns a worker's generators while its operation runs
+	slots := make(chan int, r.cfg.Concurrency)
+	for i := range r.workers {
+		slots <- i
+	}
+
+	lags := make([]time.Duration, 0, len(tr.Events))
+	var wg sync.WaitGroup
+	start := time.Now()
+	var last time.Time
+
+	r.metrics.SetActiveWorkers(r.cfg.Concurrency)
+	defer r.metrics.SetActiveWorkers(0)
+
+	stopSampling := r.startTimeseries()
+	stopProgress := r.startProgress(report.TargetDuration, 0, nil)
+
+replay:
+	for _, ev := range tr.Events {
+		if !ev.Op.Known() {
+			report.Skipped++
+			continue
+		}
+
+		due := start.Add(time.Duration(float64(ev.Time) / r.cfg.ReplaySpeed))
+		if wait := time.Until(due); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				break replay
+			case <-r.stopChan:
+				timer.Stop()
+				break replay
+			}
+		}
+
+		var slot int
+		select {
+		case slot = <-slots:
+		case <-ctx.Done():
+			break replay
+		case <-r.stopChan:
+			break replay
+		}
+
+		last = time.Now()
+		lags = append(lags, last.Sub(due))
+		atomic.AddInt64(&report.Issued, 1)
+
+		wg.Add(1)
+		go func(ev trace.Event, slot int) {
+			defer wg.Done()
+			defer func() { slots <- slot }()
+
+			key := trace.RemapKey(r.cfg.ReplayKeys, r.cfg.Prefix, ev.Key)
+			opCtx, cancel := context.WithTimeout(ctx, r.cfg.OpTimeout)
+			err := r.replayEvent(opCtx, ev, key, r.workers[slot])
+			cancel()
+
+			if err != nil {
+				atomic.AddInt64(&report.Errors, 1)
+				r.logger.Debug("replayed operation failed",
+					zap.String("op", string(ev.Op)),
+					zap.String("key", key),
+					zap.Error(err),
+				)
+			}
+		}(ev, slot)
+	}
+	wg.Wait()
+	stopSampling()
+	stopProgress()
+
+	r.abortOpenUploads()
+
+	if !last.IsZero() {
+		report.Elapsed = last.Sub(start)
+	}
+	if len(lags) > 0 {
+		sort.Slice(lags, func(i, j int) bool { return lags[i] < lags[j] })
+		report.LagP50 = lags[len(lags)/2]
+		report.LagP99 = lags[len(lags)*99/100]
+		report.LagMax = lags[len(lags)-1]
+	}
+
+	r.logger.Info("replay completed",
+		zap.Int("events", report.Events),
+		zap.Int64("issued", report.Issued),
+		zap.Int64("errors", report.Errors),
+		zap.Int64("skipped", report.Skipped),
+		zap.Duration("trace_duration", report.TraceDuration),
+		zap.Duration("target_duration", report.TargetDuration),
+		zap.Duration("elapsed", report.Elapsed),
+		zap.Float64("target_speed", r.cfg.ReplaySpeed),
+		zap.Float64("achieved_speed", report.AchievedSpeed()),
+		zap.Duration("lag_p50", report.LagP50),
+		zap.Duration("lag_p99", report.LagP99),
+		zap.Duration("lag_max", report.LagMax),
+	)
+	r.logSizeClasses(report.Elapsed)
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("replay interrupted after %d of %d events: %w", len(lags), len(tr.Events), ctx.Err())
+	}
+	return nil
+}
+
+// replayEvent issues one traced operation. Writes use the traced size and
+// data from the generator, keyed by the remapped key, so replayed objects
+// verify; other operations are issued as in a run.
+func (r *Runner) replayEvent(ctx context.Context, ev trace.Event, key string, w *workerState) error {
+	switch ev.Op {
+	case workload.OpPut:
+		return r.executePutWithSize(ctx, key, key, ev.Size, workload.OpPut, w)
+	case workload.OpMultipartPut:
+		return r.executeMultipartPutWithSize(ctx, key, key, ev.Size, workload.OpMultipartPut, w)
+	case workload.OpOverwrite:
+		dataKey := fmt.Sprintf("%s@%016x", key, w.rng.Uint64())
+		return r.executePutWithSize(ctx, key, dataKey, ev.Size, workload.OpOverwrite, w)
+	default:
+		return r.issueOp(ctx, w, ev.Op, key)
+	}
+}
+
+// prepareReplay writes the objects the trace reads before it writes them,
+// at the size the trace reports for them, so that the replayed reads find
+// data instead of failing
+func (r *Runner) prepareReplay(ctx context.Context, events []trace.Event) error {
+	sizes := make(map[string]int64)
+	seen := make(map[string]bool)
+	for _, ev := range events {
+		if ev.Op == workload.OpList || seen[ev.Key] {
+			continue
+		}
+		seen[ev.Key] = true
+		if (ev.Op == workload.OpGet || ev.Op == workload.OpHead || ev.Op == workload.OpRangeGet) && ev.Size > 0 {
+			sizes[trace.RemapKey(r.cfg.ReplayKeys, r.cfg.Prefix, ev.Key)] = ev.Size
+		}
+	}
+
+	r.logger.Info("preparing objects for replay", zap.Int("objects", len(sizes)))
+
+	start := time.Now()
+	var failed int64
+	keys := make(chan string)
+	var wg sync.WaitGroup
+	for _, w := range r.workers {
+		wg.Add(1)
+		go func(w *workerState) {
+			defer wg.Done()
+			for key := range keys {
+				opCtx, cancel := context.WithTimeout(ctx, r.cfg.OpTimeout)
+				if err := r.executePutWithSize(opCtx, key, key, sizes[key], workload.OpPut, w); err != nil {
+					atomic.AddInt64(&failed, 1)
+					r.logger.Debug("failed to prepare object", zap.String("key", key), zap.Error(err))
+				}
+				cancel()
+			}
+		}(w)
+	}
+
+	for key := range sizes {
+		select {
+		case keys <- key:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(keys)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("replay preparation interrupted: %w", ctx.Err())
+	}
+
+	r.logger.Info("prepared objects for replay",
+		zap.Int("objects", len(sizes)),
+		zap.Int64("failed", failed),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+	return nil
+}
@@ -2,6 +2,8 @@ package runner
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"math/rand"
@@ -10,25 +12,38 @@ import (
 	"time"
 
 	"github.com/paragkamble/s3bench/internal/config"
+	"github.com/paragkamble/s3bench/internal/consistency"
 	"github.com/paragkamble/s3bench/internal/data"
+	"github.com/paragkamble/s3bench/internal/history"
+	"github.com/paragkamble/s3bench/internal/manifest"
 	"github.com/paragkamble/s3bench/internal/metrics"
 	"github.com/paragkamble/s3bench/internal/s3"
+	"github.com/paragkamble/s3bench/internal/timeseries"
+	"github.com/paragkamble/s3bench/internal/trace"
+	"github.com/paragkamble/s3bench/internal/versions"
 	"github.com/paragkamble/s3bench/internal/workload"
 	"go.uber.org/zap"
 )
 
 // Runner orchestrates the workload execution
 type Runner struct {
-	cfg         *config.Config
-	s3Client    *s3.Client
-	generator   *data.Generator
-	verifier    *data.Verifier
-	scheduler   *workload.Scheduler
-	keygen      *workload.KeyGenerator
-	sizeDist    data.SizeDistribution
-	rateLimiter workload.RateLimiter
-	metrics     *metrics.Metrics
-	logger      *zap.Logger
+	cfg        *config.Config
+	s3Client   *s3.Client
+	generator  *data.Generator
+	verifier   *data.Verifier
+	verifyMode data.VerifyMode
+	putTags    map[string]string
+	keygen     *workload.KeyGenerator
+	seed       int64
+	workers    []*workerState
+	manifest   *manifest.Writer
+	tracker    *consistency.Tracker
+	history    *history.Recorder
+	trace      *trace.Writer
+	timeseries *timeseries.Writer
+	versions   *versions.Chains
+	metrics    *metrics.Metrics
+	logger     *zap.Logger
 
 	opsCounter int64
 	stopChan   chan struct{}
@@ -46,8 +61,19 @@ func New(cfg *config.Config, logger *zap.Logger, m *metrics.Metrics) (*Runner, e
 		SecretKey:     cfg.SecretKey,
 		PathStyle:     cfg.PathStyle,
 		SkipTLSVerify: cfg.SkipTLSVerify,
-		Logger:        logger,
-		Metrics:       m,
+		SSE: s3.SSEConfig{
+			Mode:         cfg.SSE,
+			KMSKeyID:     cfg.SSEKMSKeyID,
+			CustomerKeys: cfg.SSECKeys,
+			Secret:       cfg.SSECSecret,
+		},
+		Checksum: s3.ChecksumConfig{
+			Algorithm:   cfg.ChecksumAlgorithm,
+			Type:        cfg.ChecksumType,
+			ValidateGet: cfg.ChecksumValidate,
+		},
+		Logger:  logger,
+		Metrics: m,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create S3 client: %w", err)
@@ -61,41 +87,167 @@ func New(cfg *config.Config, logger *zap.Logger, m *metrics.Metrics) (*Runner, e
 
 	verifier := data.NewVerifier(generator)
 
-	// Create operation scheduler
-	scheduler, err := workload.NewScheduler(cfg.Mix, cfg.Keys, time.Now().UnixNano())
+	verifyMode, err := data.ParseVerifyMode(cfg.VerifyMode)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create scheduler: %w", err)
+		return nil, fmt.Errorf("invalid verify mode: %w", err)
+	}
+
+	putTags, err := data.ParseTags(cfg.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tags: %w", err)
 	}
 
 	// Create key generator
-	keygen := workload.NewKeyGenerator(cfg.Prefix, cfg.KeyTemplate, cfg.Keys)
+	keygen, err := workload.NewKeyGenerator(cfg.Prefix, cfg.KeyTemplate, cfg.Keys, cfg.RunID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create size distribution
-	sizeDist, err := data.ParseSizeDistribution(cfg.Size, time.Now().UnixNano())
+	// Break transfers down by size class
+	if cfg.SizeClasses != "" {
+		classes, err := parseSizeClasses(cfg.SizeClasses)
+		if err != nil {
+			return nil, err
+		}
+		m.SetSizeClasses(classes)
+	}
+
+	// Every random choice derives from the master seed
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	// Parse the operation mix, distributions and rate limit once
+	spec, err := newWorkerSpec(cfg, keygen)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse size distribution: %w", err)
+		return nil, err
+	}
+
+	// Create per-worker schedulers and distributions. With an explicit seed
+	// each worker issues its share of the operation count, so that reruns
+	// split the operations among workers the same way.
+	workers := make([]*workerState, cfg.Concurrency)
+	for i := range workers {
+		workers[i] = spec.worker(cfg, i, seed)
+		if cfg.Seed != 0 && cfg.Operations > 0 {
+			workers[i].quota = workerQuota(cfg.Operations, cfg.Concurrency, i)
+		}
+	}
+
+	// Open write manifest (audit mode only reads it)
+	var manifestWriter *manifest.Writer
+	if cfg.ManifestFile != "" && !cfg.Audit {
+		manifestWriter, err = manifest.NewWriter(cfg.ManifestFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open manifest: %w", err)
+		}
+	}
+
+	// Track version chains for the versioning operations
+	var chains *versions.Chains
+	if spec.mix.Includes(workload.OpOverwrite, workload.OpGetVersion, workload.OpListVersions, workload.OpDeleteVersion) {
+		chains = versions.NewChains(versions.DefaultLimit)
+	}
+
+	// Track acknowledged writes for consistency checking
+	var tracker *consistency.Tracker
+	if cfg.ConsistencyCheck {
+		tracker = consistency.NewTracker()
 	}
 
-	// Create rate limiter
-	rateLimiter := workload.NewRateLimiter(cfg.RateType, cfg.RateLimit, time.Now().UnixNano())
+	// Record operation history for offline linearizability checking
+	var recorder *history.Recorder
+	if cfg.HistoryFile != "" && !cfg.Audit && cfg.AnalyzeHistory == "" {
+		recorder, err = history.NewRecorder(cfg.HistoryFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create history recorder: %w", err)
+		}
+	}
+
+	// Record every issued operation as a replayable trace
+	var traceWriter *trace.Writer
+	if cfg.RecordTrace != "" && !cfg.Audit && cfg.AnalyzeHistory == "" && cfg.Replay == "" {
+		traceWriter, err = trace.NewWriter(cfg.RecordTrace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create trace recorder: %w", err)
+		}
+	}
+
+	// Export results interval by interval
+	var timeseriesWriter *timeseries.Writer
+	if cfg.TimeseriesFile != "" && !cfg.Audit && cfg.AnalyzeHistory == "" && !cfg.Cleanup {
+		format, err := timeseries.ParseFormat(cfg.TimeseriesFormat)
+		if err != nil {
+			return nil, err
+		}
+		timeseriesWriter, err = timeseries.NewWriter(cfg.TimeseriesFile, format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create time-series writer: %w", err)
+		}
+	}
 
 	return &Runner{
-		cfg:         cfg,
-		s3Client:    s3Client,
-		generator:   generator,
-		verifier:    verifier,
-		scheduler:   scheduler,
-		keygen:      keygen,
-		sizeDist:    sizeDist,
-		rateLimiter: rateLimiter,
-		metrics:     m,
-		logger:      logger,
-		stopChan:    make(chan struct{}),
+		cfg:        cfg,
+		s3Client:   s3Client,
+		generator:  generator,
+		verifier:   verifier,
+		verifyMode: verifyMode,
+		putTags:    putTags,
+		keygen:     keygen,
+		seed:       seed,
+		workers:    workers,
+		manifest:   manifestWriter,
+		tracker:    tracker,
+		history:    recorder,
+		trace:      traceWriter,
+		timeseries: timeseriesWriter,
+		versions:   chains,
+		metrics:    m,
+		logger:     logger,
+		stopChan:   make(chan struct{}),
 	}, nil
 }
 
 // Run starts the workload
 func (r *Runner) Run(ctx context.Context) error {
+	// Handle offline modes before touching bucket configuration
+	if r.cfg.AnalyzeHistory != "" {
+		return r.runAnalyzeHistory()
+	}
+	if r.cfg.Audit {
+		return r.runAudit(ctx)
+	}
+
+	if r.manifest != nil {
+		defer func() {
+			if err := r.manifest.Close(); err != nil {
+				r.logger.Warn("failed to close manifest", zap.Error(err))
+			}
+		}()
+	}
+	if r.history != nil {
+		defer func() {
+			if err := r.history.Close(); err != nil {
+				r.logger.Warn("failed to close history", zap.Error(err))
+			}
+		}()
+	}
+	if r.trace != nil {
+		defer func() {
+			if err := r.trace.Close(); err != nil {
+				r.logger.Warn("failed to close trace", zap.Error(err))
+			}
+		}()
+	}
+	if r.timeseries != nil {
+		defer func() {
+			if err := r.timeseries.Close(); err != nil {
+				r.logger.Warn("failed to close time series", zap.Error(err))
+			}
+		}()
+	}
+
 	// Setup bucket if needed
 	if r.cfg.CreateBucket {
 		if err := r.s3Client.CreateBucket(ctx); err != nil {
@@ -119,6 +271,11 @@ func (r *Runner) Run(ctx context.Context) error {
 		return r.runCleanup(ctx)
 	}
 
+	// Handle replay mode
+	if r.cfg.Replay != "" {
+		return r.runReplay(ctx)
+	}
+
 	// Create worker context
 	workerCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -134,14 +291,21 @@ func (r *Runner) Run(ctx context.Context) error {
 
 	// Start workers
 	r.logger.Info("starting workload",
+		zap.String("run_id", r.cfg.RunID),
+		zap.Int64("seed", r.seed),
 		zap.Int("concurrency", r.cfg.Concurrency),
 		zap.Duration("duration", r.cfg.Duration),
 		zap.Int64("operations", r.cfg.Operations),
 	)
 
+	start := time.Now()
+	stopSampling := r.startTimeseries()
+	stopProgress := r.startProgress(r.cfg.Duration, r.cfg.Operations, func() int64 {
+		return atomic.LoadInt64(&r.opsCounter)
+	})
 	for i := 0; i < r.cfg.Concurrency; i++ {
 		r.wg.Add(1)
-		go r.worker(workerCtx, i)
+		go r.worker(workerCtx, r.workers[i])
 	}
 
 	// Wait for completion
@@ -157,29 +321,48 @@ func (r *Runner) Run(ctx context.Context) error {
 
 	// Wait for all workers to finish
 	r.wg.Wait()
+	elapsed := time.Since(start)
+	stopSampling()
+	stopProgress()
+
+	r.abortOpenUploads()
 
 	r.logger.Info("workload completed",
 		zap.Int64("total_operations", atomic.LoadInt64(&r.opsCounter)),
+		zap.Duration("elapsed", elapsed),
+		zap.Int64("seed", r.seed),
 	)
+	r.logSizeClasses(elapsed)
 
 	return nil
 }
 
+// abortOpenUploads aborts multipart uploads this run started but never
+// completed, so interrupted runs don't leave parts behind
+func (r *Runner) abortOpenUploads() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.OpTimeout)
+	defer cancel()
+
+	aborted, err := r.s3Client.AbortOpenUploads(ctx)
+	if aborted > 0 {
+		r.logger.Info("aborted open multipart uploads", zap.Int("count", aborted))
+	}
+	if err != nil {
+		r.logger.Warn("failed to abort open multipart uploads", zap.Error(err))
+	}
+}
+
 // Stop gracefully stops the workload
 func (r *Runner) Stop() {
 	close(r.stopChan)
 }
 
 // worker executes operations in a loop
-func (r *Runner) worker(ctx context.Context, workerID int) {
+func (r *Runner) worker(ctx context.Context, w *workerState) {
 	defer r.wg.Done()
 
-	rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(workerID)))
-
-	r.metrics.SetActiveWorkers(r.cfg.Concurrency)
-	defer func() {
-		r.metrics.SetActiveWorkers(0)
-	}()
+	r.metrics.WorkerStarted()
+	defer r.metrics.WorkerStopped()
 
 	for {
 		// Check if we should stop
@@ -191,125 +374,236 @@ func (r *Runner) worker(ctx context.Context, workerID int) {
 		default:
 		}
 
-		// Check operation limit
-		if r.cfg.Operations > 0 {
-			current := atomic.LoadInt64(&r.opsCounter)
-			if current >= r.cfg.Operations {
-				return
-			}
+		// Check operation limit. Shared limits are only claimed below,
+		// after the rate limiter; this check just avoids waiting for nothing.
+		if w.quota == 0 {
+			return
+		}
+		if w.quota < 0 && r.cfg.Operations > 0 && atomic.LoadInt64(&r.opsCounter) >= r.cfg.Operations {
+			return
 		}
 
 		// Rate limiting
-		if err := r.rateLimiter.Wait(ctx); err != nil {
+		if err := w.limiter.Wait(ctx); err != nil {
 			return
 		}
 
-		// Increment operation counter
-		atomic.AddInt64(&r.opsCounter, 1)
+		// Claim an operation
+		if w.quota > 0 {
+			w.quota--
+			atomic.AddInt64(&r.opsCounter, 1)
+		} else if !r.claimOp() {
+			return
+		}
 
 		// Get next operation
-		op := r.scheduler.Next()
+		op := w.scheduler.Next()
 
 		// Execute operation with timeout
 		opCtx, cancel := context.WithTimeout(ctx, r.cfg.OpTimeout)
-		r.executeOp(opCtx, op, rng)
+		r.executeOp(opCtx, w, op)
 		cancel()
 	}
 }
 
+// claimOp counts an operation about to be issued. With an operation limit
+// shared by the workers it returns false once the limit is reached; the
+// counter never exceeds the limit, however many workers claim at once.
+func (r *Runner) claimOp() bool {
+	if r.cfg.Operations <= 0 {
+		atomic.AddInt64(&r.opsCounter, 1)
+		return true
+	}
+
+	for {
+		current := atomic.LoadInt64(&r.opsCounter)
+		if current >= r.cfg.Operations {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&r.opsCounter, current, current+1) {
+			return true
+		}
+	}
+}
+
 // executeOp executes a single operation
-func (r *Runner) executeOp(ctx context.Context, op workload.OpType, rng *rand.Rand) {
-	keySeq := r.scheduler.NextKey()
-	key := r.keygen.Generate(keySeq)
+func (r *Runner) executeOp(ctx context.Context, w *workerState, op workload.OpType) {
+	keySeq := w.scheduler.NextKey()
+	key := w.keys.Generate(keySeq)
 
-	var err error
+	var note *opNote
+	if r.trace != nil {
+		ctx, note = withOpNote(ctx, key)
+	}
+	invoke := time.Now()
+
+	err := r.issueOp(ctx, w, op, key)
+
+	if note != nil {
+		r.recordTrace(w.id, op, note, invoke, err)
+	}
+
+	if err != nil {
+		r.logger.Debug("operation failed",
+			zap.String("op", string(op)),
+			zap.String("key", key),
+			zap.Error(err),
+		)
+	}
+}
 
+// issueOp issues op on key. Operations that touch other objects as well,
+// such as copies and versioning operations, choose them as usual.
+func (r *Runner) issueOp(ctx context.Context, w *workerState, op workload.OpType, key string) error {
 	switch op {
 	case workload.OpPut:
-		err = r.executePut(ctx, key)
+		return r.executePut(ctx, key, key, w)
 	case workload.OpMultipartPut:
-		err = r.executeMultipartPut(ctx, key)
+		return r.executeMultipartPut(ctx, key, w)
 	case workload.OpGet:
-		err = r.executeGet(ctx, key, rng)
+		return r.executeGet(ctx, key, w.rng)
+	case workload.OpRangeGet:
+		return r.executeRangeGet(ctx, key, w)
 	case workload.OpDelete:
-		err = r.executeDelete(ctx, key)
+		return r.executeDelete(ctx, key)
+	case workload.OpMultiDelete:
+		return r.executeMultiDelete(ctx, key, w)
 	case workload.OpCopy:
-		err = r.executeCopy(ctx, key, rng)
+		return r.executeCopy(ctx, key, w)
 	case workload.OpList:
-		err = r.executeList(ctx)
+		return r.executeList(ctx, w)
 	case workload.OpHead:
-		err = r.executeHead(ctx, key)
+		return r.executeHead(ctx, key, w.rng)
+	case workload.OpOverwrite:
+		return r.executeOverwrite(ctx, key, w)
+	case workload.OpGetVersion:
+		return r.executeGetVersion(ctx, w.versions)
+	case workload.OpListVersions:
+		return r.executeListVersions(ctx, key, w.versions)
+	case workload.OpDeleteVersion:
+		return r.executeDeleteVersion(ctx, w.versions)
+	case workload.OpPutTagging:
+		return r.executePutTagging(ctx, key, w.rng)
+	case workload.OpGetTagging:
+		return r.executeGetTagging(ctx, key)
+	case workload.OpDeleteTagging:
+		return r.executeDeleteTagging(ctx, key)
+	case workload.OpCopyReplaceMetadata:
+		return r.executeCopyReplaceMetadata(ctx, key, w.rng)
+	case workload.OpTransition:
+		return r.executeTransition(ctx, key, w)
+	case workload.OpMultipartCopy:
+		return r.executeMultipartCopy(ctx, key, w)
+	default:
+		return errUnknownOp
 	}
+}
 
-	if err != nil {
-		r.logger.Debug("operation failed",
-			zap.String("op", string(op)),
-			zap.String("key", key),
-			zap.Error(err),
-		)
-	}
+// executePut executes a PUT operation. The object's content is generated
+// from dataKey, which is recorded in its metadata.
+func (r *Runner) executePut(ctx context.Context, key, dataKey string, w *workerState) error {
+	return r.executePutWithSize(ctx, key, dataKey, w.nextSize(workload.OpPut), workload.OpPut, w)
 }
 
-// executePut executes a PUT operation
-func (r *Runner) executePut(ctx context.Context, key string) error {
-	size := r.sizeDist.Next()
+// executePutWithSize executes a PUT operation with a given size, recorded
+// as op
+func (r *Runner) executePutWithSize(ctx context.Context, key, dataKey string, size int64, op workload.OpType, w *workerState) error {
+	noteSize(ctx, size)
 
-	// Check if we should use multipart upload
+	// Check if we should use multipart upload. Large PUTs are recorded as
+	// multipart uploads; other ops keep their own name.
 	if r.cfg.MultipartEnabled && size >= r.cfg.MultipartThreshold {
-		return r.executeMultipartPutWithSize(ctx, key, size)
+		if op == workload.OpPut {
+			op = workload.OpMultipartPut
+		}
+		return r.executeMultipartPutWithSize(ctx, key, dataKey, size, op, w)
 	}
 
 	// Generate data and hash
-	reader, hash, err := r.generator.GenerateAndHash(key, size)
+	reader, hash, err := r.generator.GenerateAndHash(dataKey, size)
 	if err != nil {
 		return fmt.Errorf("failed to generate data: %w", err)
 	}
 
 	// Prepare metadata
-	metadata := data.PrepareMetadata(hash, r.cfg.NamespaceTag)
+	metadata := r.objectMetadata(dataKey, hash)
 
 	// Upload with retry
 	retryCfg := s3.DefaultRetryConfig()
 	retryCfg.MaxAttempts = r.cfg.MaxRetries
 
-	err = s3.WithRetry(ctx, retryCfg, r.logger, "put", func(ctx context.Context) error {
+	write := r.beginPut(key, hash)
+	invoke := time.Now()
+
+	var versionID string
+	opts := r.objectOptions(w)
+	opts.Op = metrics.OpType(op)
+
+	err = s3.WithRetry(ctx, retryCfg, r.logger, string(op), func(ctx context.Context) error {
 		// Reset reader
 		if _, err := reader.Seek(0, io.SeekStart); err != nil {
 			return fmt.Errorf("failed to reset reader: %w", err)
 		}
-		return r.s3Client.PutObject(ctx, key, reader, size, metadata)
+		var err error
+		versionID, err = r.s3Client.PutObject(ctx, key, reader, size, metadata, opts)
+		return err
 	})
+	endWrite(write, err)
+	r.recordHistory(key, history.KindWrite, invoke, hash, err)
 
 	if err != nil {
-		r.metrics.RecordRetry(string(workload.OpPut))
+		r.metrics.RecordRetry(string(op))
+		return err
 	}
 
-	return err
+	r.recordManifest(manifest.Record{
+		Op:        manifest.OpPut,
+		Key:       key,
+		VersionID: versionID,
+		Size:      size,
+		SHA256:    hash,
+		Pattern:   r.cfg.Pattern,
+		Invoked:   invoke,
+	})
+	r.recordVersion(key, versionID, hash, size, invoke)
+
+	return nil
 }
 
 // executeMultipartPut executes a multipart PUT operation (explicit)
-func (r *Runner) executeMultipartPut(ctx context.Context, key string) error {
-	size := r.sizeDist.Next()
-	return r.executeMultipartPutWithSize(ctx, key, size)
+func (r *Runner) executeMultipartPut(ctx context.Context, key string, w *workerState) error {
+	size := w.nextSize(workload.OpMultipartPut)
+	return r.executeMultipartPutWithSize(ctx, key, key, size, workload.OpMultipartPut, w)
 }
 
-// executeMultipartPutWithSize executes a multipart PUT operation with a given size
-func (r *Runner) executeMultipartPutWithSize(ctx context.Context, key string, size int64) error {
+// executeMultipartPutWithSize executes a multipart PUT operation with a given
+// size, recorded as op
+func (r *Runner) executeMultipartPutWithSize(ctx context.Context, key, dataKey string, size int64, op workload.OpType, w *workerState) error {
+	noteSize(ctx, size)
+
 	// Generate data and hash
-	reader, hash, err := r.generator.GenerateAndHash(key, size)
+	reader, hash, err := r.generator.GenerateAndHash(dataKey, size)
 	if err != nil {
 		return fmt.Errorf("failed to generate data: %w", err)
 	}
 
 	// Prepare metadata
-	metadata := data.PrepareMetadata(hash, r.cfg.NamespaceTag)
+	metadata := r.objectMetadata(dataKey, hash)
 
 	// Upload with retry
 	retryCfg := s3.DefaultRetryConfig()
 	retryCfg.MaxAttempts = r.cfg.MaxRetries
 
-	err = s3.WithRetry(ctx, retryCfg, r.logger, "multipart_put", func(ctx context.Context) error {
-		return r.s3Client.MultipartUpload(
+	write := r.beginPut(key, hash)
+	invoke := time.Now()
+
+	var versionID string
+	opts := r.objectOptions(w)
+	opts.Op = metrics.OpType(op)
+
+	err = s3.WithRetry(ctx, retryCfg, r.logger, string(op), func(ctx context.Context) error {
+		var err error
+		versionID, err = r.s3Client.MultipartUpload(
 			ctx,
 			key,
 			reader,
@@ -317,14 +611,69 @@ func (r *Runner) executeMultipartPutWithSize(ctx context.Context, key string, si
 			r.cfg.MultipartPartSize,
 			r.cfg.MultipartMaxParts,
 			metadata,
+			opts,
 		)
+		return err
 	})
+	endWrite(write, err)
+	r.recordHistory(key, history.KindWrite, invoke, hash, err)
 
 	if err != nil {
-		r.metrics.RecordRetry(string(workload.OpMultipartPut))
+		r.metrics.RecordRetry(string(op))
+		return err
 	}
 
-	return err
+	r.recordManifest(manifest.Record{
+		Op:        manifest.OpPut,
+		Key:       key,
+		VersionID: versionID,
+		Size:      size,
+		SHA256:    hash,
+		Pattern:   r.cfg.Pattern,
+		Invoked:   invoke,
+	})
+	r.recordVersion(key, versionID, hash, size, invoke)
+
+	return nil
+}
+
+// recordHistory records a completed operation in the history, if enabled
+func (r *Runner) recordHistory(key string, kind history.Kind, invoke time.Time, value string, err error) {
+	if r.history != nil {
+		r.history.Record(key, kind, invoke, value, err)
+	}
+}
+
+// recordManifest appends a record to the write manifest, if enabled
+func (r *Runner) recordManifest(rec manifest.Record) {
+	if r.manifest == nil {
+		return
+	}
+	if err := r.manifest.Append(rec); err != nil {
+		r.logger.Warn("failed to record manifest entry",
+			zap.String("key", rec.Key),
+			zap.Error(err),
+		)
+	}
+}
+
+// recordVersion adds a written version to its key's chain, if tracked
+func (r *Runner) recordVersion(key, versionID, hash string, size int64, invoke time.Time) {
+	if r.versions != nil && versionID != "" && versionID != "null" {
+		r.versions.Add(key, versionID, hash, size, invoke, time.Now())
+	}
+}
+
+// objectOptions returns the write options for a new object
+func (r *Runner) objectOptions(w *workerState) s3.ObjectOptions {
+	return s3.ObjectOptions{Tags: r.putTags, StorageClass: w.classes.Next()}
+}
+
+// objectMetadata prepares the metadata stored with a newly written object
+func (r *Runner) objectMetadata(dataKey, hash string) map[string]string {
+	metadata := data.PrepareMetadata(hash, r.cfg.NamespaceTag)
+	metadata[data.MetadataKeyDataKey] = dataKey
+	return metadata
 }
 
 // executeGet executes a GET operation
@@ -333,27 +682,65 @@ func (r *Runner) executeGet(ctx context.Context, key string, rng *rand.Rand) err
 
 	var body io.ReadCloser
 	var metadata map[string]string
+	var size int64
 	var err error
 
 	// Download with retry
 	retryCfg := s3.DefaultRetryConfig()
 	retryCfg.MaxAttempts = r.cfg.MaxRetries
 
+	readStart := time.Now()
+
 	err = s3.WithRetry(ctx, retryCfg, r.logger, "get", func(ctx context.Context) error {
-		body, metadata, _, err = r.s3Client.GetObject(ctx, key)
+		body, metadata, size, err = r.s3Client.GetObject(ctx, key)
 		return err
 	})
 
 	if err != nil {
+		if s3.IsNotFound(err) {
+			if r.tracker != nil {
+				if v := r.tracker.CheckRead(key, readStart, time.Now(), false, ""); v != nil {
+					r.reportViolation(v)
+				}
+			}
+			r.recordHistory(key, history.KindRead, readStart, "", nil)
+		} else {
+			r.recordHistory(key, history.KindRead, readStart, "", err)
+		}
 		r.metrics.RecordRetry(string(workload.OpGet))
 		return err
 	}
 
 	defer body.Close()
+	noteSize(ctx, size)
+
+	// Hash the full body for consistency checking and history recording
+	var reader io.Reader = body
+	if r.tracker != nil || r.history != nil {
+		hasher := &countingHash{Hash: sha256.New()}
+		reader = io.TeeReader(body, hasher)
+		defer func() {
+			// A partially read body says nothing about consistency
+			if hasher.n != size {
+				r.recordHistory(key, history.KindRead, readStart, "", fmt.Errorf("short read"))
+				return
+			}
+			readHash := hex.EncodeToString(hasher.Sum(nil))
+			if r.tracker != nil {
+				if v := r.tracker.CheckRead(key, readStart, time.Now(), true, readHash); v != nil {
+					r.reportViolation(v)
+				}
+			}
+			r.recordHistory(key, history.KindRead, readStart, readHash, nil)
+		}()
+	}
 
 	// Verify if requested
 	if shouldVerify {
-		if err := r.verifier.VerifyWithMetadata(body, metadata); err != nil {
+		if err := r.verifier.VerifyObject(reader, key, size, metadata, r.verifyMode); err != nil {
+			if s3.IsChecksumMismatch(err) {
+				return r.checksumMismatch(key, err)
+			}
 			r.metrics.RecordVerifyFailure()
 			r.logger.Warn("verification failed",
 				zap.String("key", key),
@@ -362,8 +749,102 @@ func (r *Runner) executeGet(ctx context.Context, key string, rng *rand.Rand) err
 			return fmt.Errorf("verification failed: %w", err)
 		}
 		r.metrics.RecordVerifySuccess()
+	} else if _, err := io.Copy(io.Discard, reader); err != nil {
+		if s3.IsChecksumMismatch(err) {
+			return r.checksumMismatch(key, err)
+		}
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+
+	return nil
+}
+
+// checksumMismatch reports a GET whose body did not match the checksum
+// returned by the server
+func (r *Runner) checksumMismatch(key string, err error) error {
+	r.logger.Warn("checksum mismatch",
+		zap.String("key", key),
+		zap.Error(err),
+	)
+	return err
+}
+
+// executeRangeGet executes a ranged GET operation
+func (r *Runner) executeRangeGet(ctx context.Context, key string, w *workerState) error {
+	rng := w.rng
+	shouldVerify := workload.ShouldVerify(r.cfg.VerifyRate, rng)
+
+	length := w.ranges.Next()
+	if length < 1 {
+		length = 1
+	}
+
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	// Head and tail ranges need no object size
+	var rangeSpec string
+	switch r.cfg.RangeOffset {
+	case "head":
+		rangeSpec = fmt.Sprintf("bytes=0-%d", length-1)
+	case "tail":
+		rangeSpec = fmt.Sprintf("bytes=-%d", length)
+	default:
+		var size int64
+		err := s3.WithRetry(ctx, retryCfg, r.logger, "head", func(ctx context.Context) error {
+			var err error
+			_, size, err = r.s3Client.HeadObject(ctx, key)
+			return err
+		})
+		if err != nil {
+			r.metrics.RecordRetry(string(workload.OpRangeGet))
+			return err
+		}
+		if size == 0 {
+			return nil
+		}
+		if length > size {
+			length = size
+		}
+		offset := workload.RangeOffset(r.cfg.RangeOffset, size, length, rng)
+		rangeSpec = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
+
+	var body io.ReadCloser
+	var metadata map[string]string
+	var offset, size int64
+
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "range_get", func(ctx context.Context) error {
+		var err error
+		body, metadata, offset, size, err = r.s3Client.GetObjectRange(ctx, key, rangeSpec)
+		return err
+	})
+
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpRangeGet))
+		return err
+	}
+
+	defer body.Close()
+	noteSize(ctx, size)
+
+	// The server clamps ranges that extend past the end of the object
+	if offset+length > size {
+		length = size - offset
+	}
+
+	if shouldVerify {
+		if err := r.verifier.VerifyRange(body, key, size, offset, length, metadata); err != nil {
+			r.metrics.RecordVerifyFailure()
+			r.logger.Warn("range verification failed",
+				zap.String("key", key),
+				zap.String("range", rangeSpec),
+				zap.Error(err),
+			)
+			return fmt.Errorf("verification failed: %w", err)
+		}
+		r.metrics.RecordVerifySuccess()
 	} else {
-		// Discard body
 		io.Copy(io.Discard, body)
 	}
 
@@ -380,46 +861,169 @@ func (r *Runner) executeDelete(ctx context.Context, key string) error {
 	retryCfg := s3.DefaultRetryConfig()
 	retryCfg.MaxAttempts = r.cfg.MaxRetries
 
+	write := r.beginDelete(key)
+	invoke := time.Now()
+
 	err := s3.WithRetry(ctx, retryCfg, r.logger, "delete", func(ctx context.Context) error {
 		return r.s3Client.DeleteObject(ctx, key)
 	})
+	endWrite(write, err)
+	r.recordHistory(key, history.KindDelete, invoke, "", err)
 
 	if err != nil {
 		r.metrics.RecordRetry(string(workload.OpDelete))
+		return err
 	}
 
-	return err
+	r.recordManifest(manifest.Record{Op: manifest.OpDelete, Key: key, Invoked: invoke})
+
+	return nil
+}
+
+// executeMultiDelete deletes a batch of distinct keys, starting with key,
+// in a single DeleteObjects request
+func (r *Runner) executeMultiDelete(ctx context.Context, key string, w *workerState) error {
+	if r.cfg.KeepData {
+		// Skip delete in keep-data mode
+		return nil
+	}
+
+	batch := r.cfg.MultiDeleteBatch
+	if batch > r.cfg.Keys {
+		batch = r.cfg.Keys
+	}
+	objects := w.deleteBatch(key, batch)
+
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	writes := make([]*consistency.Write, len(objects))
+	for i, obj := range objects {
+		writes[i] = r.beginDelete(obj.Key)
+	}
+	invoke := time.Now()
+
+	var failures []s3.DeleteFailure
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "multi_delete", func(ctx context.Context) error {
+		var err error
+		failures, err = r.s3Client.DeleteObjects(ctx, objects, r.cfg.MultiDeleteQuiet)
+		return err
+	})
+
+	failed := make(map[string]error, len(failures))
+	for _, f := range failures {
+		failed[f.Key] = fmt.Errorf("%s: %s", f.Code, f.Message)
+		r.logger.Debug("batch delete key failed",
+			zap.String("key", f.Key),
+			zap.String("code", f.Code),
+			zap.String("message", f.Message),
+		)
+	}
+
+	for i, obj := range objects {
+		keyErr := err
+		if keyErr == nil {
+			keyErr = failed[obj.Key]
+		}
+		endWrite(writes[i], keyErr)
+		r.recordHistory(obj.Key, history.KindDelete, invoke, "", keyErr)
+		if keyErr == nil {
+			r.recordManifest(manifest.Record{Op: manifest.OpDelete, Key: obj.Key, Invoked: invoke})
+		}
+	}
+
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpMultiDelete))
+		return err
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("batch delete: %d of %d keys failed", len(failures), len(objects))
+	}
+
+	return nil
 }
 
 // executeCopy executes a COPY operation
-func (r *Runner) executeCopy(ctx context.Context, srcKey string, rng *rand.Rand) error {
+func (r *Runner) executeCopy(ctx context.Context, srcKey string, w *workerState) error {
 	// Generate destination key
-	dstSeq := r.scheduler.NextKey()
-	dstKey := r.keygen.Generate(dstSeq)
+	dstSeq := w.scheduler.NextKey()
+	dstKey := w.keys.Generate(dstSeq)
 
 	dstBucket := r.cfg.CopyDstBucket
 
 	retryCfg := s3.DefaultRetryConfig()
 	retryCfg.MaxAttempts = r.cfg.MaxRetries
 
+	// Copied content is not tracked, so stop checking the destination
+	if r.tracker != nil && dstBucket == "" {
+		r.tracker.Invalidate(dstKey)
+		defer r.tracker.Invalidate(dstKey)
+	}
+
+	invoke := time.Now()
+
+	var versionID string
 	err := s3.WithRetry(ctx, retryCfg, r.logger, "copy", func(ctx context.Context) error {
-		return r.s3Client.CopyObject(ctx, srcKey, dstKey, dstBucket)
+		var err error
+		versionID, err = r.s3Client.CopyObject(ctx, srcKey, dstKey, dstBucket, s3.ObjectOptions{StorageClass: w.classes.Next()})
+		return err
 	})
+	if dstBucket == "" {
+		r.recordHistory(dstKey, history.KindCopy, invoke, "", err)
+	}
 
 	if err != nil {
 		r.metrics.RecordRetry(string(workload.OpCopy))
+		return err
 	}
 
-	return err
+	// Copies to another bucket are outside the audited keyspace
+	if dstBucket == "" {
+		r.recordManifest(manifest.Record{
+			Op:        manifest.OpCopy,
+			Key:       dstKey,
+			SrcKey:    srcKey,
+			VersionID: versionID,
+			Invoked:   invoke,
+		})
+	}
+
+	return nil
 }
 
-// executeList executes a LIST operation
-func (r *Runner) executeList(ctx context.Context) error {
+// executeList executes a LIST operation: one page, or the whole listing in
+// full mode, optionally by directory, under a random sub-prefix, or
+// starting after a key
+func (r *Runner) executeList(ctx context.Context, w *workerState) error {
+	if r.tracker != nil {
+		return r.executeConsistencyList(ctx)
+	}
+
+	opts := s3.ListOptions{
+		Prefix:    r.cfg.Prefix,
+		Delimiter: r.cfg.ListDelimiter,
+		PageSize:  int32(r.cfg.ListPageSize),
+		Full:      r.cfg.ListMode == "full",
+	}
+	if r.cfg.ListRandomPrefix {
+		key := w.keys.Generate(w.scheduler.NextKey())
+		opts.Prefix = workload.SubPrefix(r.cfg.Prefix, key, r.cfg.ListDelimiter, w.rng)
+	}
+	switch r.cfg.ListStartAfter {
+	case "":
+	case "random":
+		opts.StartAfter = w.keys.Generate(w.scheduler.NextKey())
+	default:
+		opts.StartAfter = r.cfg.ListStartAfter
+	}
+
 	retryCfg := s3.DefaultRetryConfig()
 	retryCfg.MaxAttempts = r.cfg.MaxRetries
 
+	// A failed page restarts the whole listing
 	err := s3.WithRetry(ctx, retryCfg, r.logger, "list", func(ctx context.Context) error {
-		_, err := r.s3Client.ListObjects(ctx, r.cfg.Prefix, 1000)
+		_, err := r.s3Client.List(ctx, opts)
 		return err
 	})
 
@@ -430,38 +1034,68 @@ func (r *Runner) executeList(ctx context.Context) error {
 	return err
 }
 
-// executeHead executes a HEAD operation
-func (r *Runner) executeHead(ctx context.Context, key string) error {
+// executeConsistencyList lists the whole prefix and checks that it reflects
+// every acknowledged write and delete
+func (r *Runner) executeConsistencyList(ctx context.Context) error {
 	retryCfg := s3.DefaultRetryConfig()
 	retryCfg.MaxAttempts = r.cfg.MaxRetries
 
-	err := s3.WithRetry(ctx, retryCfg, r.logger, "head", func(ctx context.Context) error {
-		_, _, err := r.s3Client.HeadObject(ctx, key)
+	var keys []string
+	var listStart time.Time
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "list", func(ctx context.Context) error {
+		listStart = time.Now()
+		var err error
+		keys, err = r.s3Client.ListAllObjects(ctx, r.cfg.Prefix)
 		return err
 	})
 
 	if err != nil {
-		r.metrics.RecordRetry(string(workload.OpHead))
+		r.metrics.RecordRetry(string(workload.OpList))
+		return err
 	}
 
-	return err
+	for _, v := range r.tracker.CheckList(r.cfg.Prefix, listStart, time.Now(), keys) {
+		r.reportViolation(v)
+	}
+
+	return nil
 }
 
-// runCleanup runs cleanup mode
-func (r *Runner) runCleanup(ctx context.Context) error {
-	r.logger.Info("running cleanup mode", zap.String("prefix", r.cfg.Prefix))
+// executeHead executes a HEAD operation
+func (r *Runner) executeHead(ctx context.Context, key string, rng *rand.Rand) error {
+	shouldVerify := workload.ShouldVerify(r.cfg.VerifyRate, rng) && r.verifyMode != data.VerifyModeMetadata
 
-	deleted, err := r.s3Client.DeleteObjectsByMetadata(
-		ctx,
-		r.cfg.Prefix,
-		data.MetadataKeyCreatedBy,
-		data.MetadataValueCreatedBy,
-	)
+	var metadata map[string]string
+	var size int64
+
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "head", func(ctx context.Context) error {
+		var err error
+		metadata, size, err = r.s3Client.HeadObject(ctx, key)
+		return err
+	})
 
 	if err != nil {
-		return fmt.Errorf("cleanup failed: %w", err)
+		r.metrics.RecordRetry(string(workload.OpHead))
+		return err
+	}
+	noteSize(ctx, size)
+
+	// Check the reported size against the stored hash
+	if shouldVerify {
+		if err := r.verifier.VerifyHead(key, size, metadata, r.verifyMode); err != nil {
+			r.metrics.RecordVerifyFailure()
+			r.logger.Warn("head size verification failed",
+				zap.String("key", key),
+				zap.Int64("size", size),
+				zap.Error(err),
+			)
+			return fmt.Errorf("verification failed: %w", err)
+		}
+		r.metrics.RecordVerifySuccess()
 	}
 
-	r.logger.Info("cleanup completed", zap.Int("deleted", deleted))
 	return nil
 }
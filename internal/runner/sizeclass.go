@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/data"
+	"github.com/paragkamble/s3bench/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// parseSizeClasses parses the ascending upper bounds of size classes, such
+// as "64KiB,1MiB,16MiB,128MiB"
+func parseSizeClasses(spec string) (*metrics.SizeClasses, error) {
+	var bounds []int64
+	var names []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		bound, err := data.ParseSize(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size class %q: %w", name, err)
+		}
+		if bound < 1 || (len(bounds) > 0 && bound <= bounds[len(bounds)-1]) {
+			return nil, fmt.Errorf("size classes must be ascending and positive, got %q", spec)
+		}
+		bounds = append(bounds, bound)
+		names = append(names, name)
+	}
+	return metrics.NewSizeClasses(bounds, names), nil
+}
+
+// logSizeClasses reports the successful transfers of each operation by
+// size class, with rates over elapsed
+func (r *Runner) logSizeClasses(elapsed time.Duration) {
+	for _, s := range r.metrics.SizeClassStats() {
+		r.logger.Info("size class",
+			zap.String("op", s.Op),
+			zap.String("size_class", s.Class),
+			zap.Int64("ops", s.Ops),
+			zap.Int64("bytes", s.Bytes),
+			zap.Float64("ops_per_sec", s.OpsPerSec(elapsed)),
+			zap.Float64("mb_per_sec", s.MBPerSec(elapsed)),
+			zap.Duration("mean_latency", s.MeanLatency),
+		)
+	}
+}
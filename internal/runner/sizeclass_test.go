@@ -0,0 +1,40 @@
+package runner
+
+import "testing"
+
+func TestParseSizeClasses(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{"64KiB,1MiB,16MiB,128MiB", []string{"<64KiB", "<1MiB", "<16MiB", "<128MiB", ">=128MiB"}, false},
+		{" 4KiB , 1MiB", []string{"<4KiB", "<1MiB", ">=1MiB"}, false},
+		{"1MiB,64KiB", nil, true},
+		{"1MiB,1MiB", nil, true},
+		{"0", nil, true},
+		{"large", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			classes, err := parseSizeClasses(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSizeClasses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			got := classes.Labels()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Labels() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Labels()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/history"
+	"github.com/paragkamble/s3bench/internal/manifest"
+	"github.com/paragkamble/s3bench/internal/s3"
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+// errNoTransition is returned when the storage class mix has no class other
+// than the object's current one
+var errNoTransition = errors.New("no other storage class to transition to")
+
+// executeTransition moves an object to another storage class from the mix
+// with an in-place copy. S3 rejects in-place copies that change nothing, so
+// the current class is read first and excluded.
+func (r *Runner) executeTransition(ctx context.Context, key string, w *workerState) error {
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	var info *s3.ObjectInfo
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "head", func(ctx context.Context) error {
+		var err error
+		info, err = r.s3Client.StatObject(ctx, key)
+		return err
+	})
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpTransition))
+		return err
+	}
+
+	// STANDARD is not reported by HEAD
+	current := info.StorageClass
+	if current == "" {
+		current = "STANDARD"
+	}
+
+	target, ok := w.classes.NextExcept(current)
+	if !ok {
+		return errNoTransition
+	}
+
+	// The copy writes a new version that is not tracked, so stop checking
+	// the key, as for other copies
+	if r.tracker != nil {
+		r.tracker.Invalidate(key)
+		defer r.tracker.Invalidate(key)
+	}
+
+	invoke := time.Now()
+
+	var versionID string
+	err = s3.WithRetry(ctx, retryCfg, r.logger, "transition", func(ctx context.Context) error {
+		var err error
+		versionID, err = r.s3Client.TransitionObject(ctx, key, target)
+		return err
+	})
+	r.recordHistory(key, history.KindCopy, invoke, "", err)
+
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpTransition))
+		return err
+	}
+
+	r.recordManifest(manifest.Record{
+		Op:        manifest.OpCopy,
+		Key:       key,
+		SrcKey:    key,
+		VersionID: versionID,
+		Invoked:   invoke,
+	})
+
+	return nil
+}
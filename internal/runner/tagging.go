@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/data"
+	"github.com/paragkamble/s3bench/internal/history"
+	"github.com/paragkamble/s3bench/internal/manifest"
+	"github.com/paragkamble/s3bench/internal/s3"
+	"github.com/paragkamble/s3bench/internal/workload"
+	"go.uber.org/zap"
+)
+
+// executePutTagging replaces an object's tags with a generated,
+// self-verifying tag set
+func (r *Runner) executePutTagging(ctx context.Context, key string, rng *rand.Rand) error {
+	tags := data.GenerateTags(r.cfg.TagCount, r.cfg.TagValueSize, rng)
+
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "put_tagging", func(ctx context.Context) error {
+		return r.s3Client.PutObjectTagging(ctx, key, tags)
+	})
+
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpPutTagging))
+		return err
+	}
+
+	return nil
+}
+
+// executeGetTagging reads an object's tags and verifies them. Generated tag
+// sets carry their own checksum, so the check holds under concurrent
+// put_tagging and delete_tagging on the same key.
+func (r *Runner) executeGetTagging(ctx context.Context, key string) error {
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	var tags map[string]string
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "get_tagging", func(ctx context.Context) error {
+		var err error
+		tags, err = r.s3Client.GetObjectTagging(ctx, key)
+		return err
+	})
+
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpGetTagging))
+		return err
+	}
+
+	if err := data.VerifyTags(tags, r.putTags); err != nil {
+		r.metrics.RecordVerifyFailure()
+		r.logger.Error("tag verification failed",
+			zap.String("key", key),
+			zap.Any("tags", tags),
+			zap.Error(err),
+		)
+		return fmt.Errorf("tag verification failed: %w", err)
+	}
+	r.metrics.RecordVerifySuccess()
+
+	return nil
+}
+
+// executeDeleteTagging removes every tag from an object
+func (r *Runner) executeDeleteTagging(ctx context.Context, key string) error {
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "delete_tagging", func(ctx context.Context) error {
+		return r.s3Client.DeleteObjectTagging(ctx, key)
+	})
+
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpDeleteTagging))
+		return err
+	}
+
+	return nil
+}
+
+// executeCopyReplaceMetadata copies an object onto itself with a new set of
+// generated metadata. The tool's own metadata and the storage class are
+// carried over, so the object can still be verified and cleaned up.
+func (r *Runner) executeCopyReplaceMetadata(ctx context.Context, key string, rng *rand.Rand) error {
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	var current *s3.ObjectInfo
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "head", func(ctx context.Context) error {
+		var err error
+		current, err = r.s3Client.StatObject(ctx, key)
+		return err
+	})
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpCopyReplaceMetadata))
+		return err
+	}
+
+	metadata := data.GenerateMetadata(r.cfg.MetadataCount, r.cfg.MetadataValueSize, rng)
+	for k, v := range current.Metadata {
+		if !strings.HasPrefix(k, data.MetadataKeyGeneratedPrefix) {
+			metadata[k] = v
+		}
+	}
+
+	// The copy writes a new version that is not tracked, so stop checking
+	// the key, as for other copies
+	if r.tracker != nil {
+		r.tracker.Invalidate(key)
+		defer r.tracker.Invalidate(key)
+	}
+
+	invoke := time.Now()
+
+	var versionID string
+	err = s3.WithRetry(ctx, retryCfg, r.logger, "copy_replace_metadata", func(ctx context.Context) error {
+		var err error
+		versionID, err = r.s3Client.CopyObject(ctx, key, key, "", s3.ObjectOptions{
+			ReplaceMetadata: metadata,
+			StorageClass:    current.StorageClass,
+		})
+		return err
+	})
+	r.recordHistory(key, history.KindCopy, invoke, "", err)
+
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpCopyReplaceMetadata))
+		return err
+	}
+
+	r.recordManifest(manifest.Record{
+		Op:        manifest.OpCopy,
+		Key:       key,
+		SrcKey:    key,
+		VersionID: versionID,
+		Invoked:   invoke,
+	})
+
+	return nil
+}
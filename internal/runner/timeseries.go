@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// sampleEvery passes the requests recorded in each interval to sample until
+// the returned function is called, which samples the final, partial interval
+// and waits for sample to return
+func (r *Runner) sampleEvery(interval time.Duration, sample func(metrics.WindowStats)) func() {
+	window := r.metrics.NewWindow()
+	ticker := time.NewTicker(interval)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sample(window.Sample())
+			case <-stop:
+				sample(window.Sample())
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// startTimeseries writes the run's results to the time-series file every
+// interval, if one is configured. The returned function writes the final
+// interval.
+func (r *Runner) startTimeseries() func() {
+	if r.timeseries == nil {
+		return func() {}
+	}
+
+	failed := false
+	return r.sampleEvery(r.cfg.TimeseriesInterval, func(s metrics.WindowStats) {
+		if err := r.timeseries.Write(s); err != nil && !failed {
+			failed = true
+			r.logger.Warn("failed to write time series", zap.Error(err))
+		}
+	})
+}
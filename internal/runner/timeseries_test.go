@@ -0,0 +1,31 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/metrics"
+)
+
+func TestSampleEvery(t *testing.T) {
+	r := &Runner{metrics: metrics.NewMetrics()}
+
+	samples := make(chan metrics.WindowStats, 100)
+	stop := r.sampleEvery(time.Hour, func(s metrics.WindowStats) { samples <- s })
+	r.metrics.RecordOp("put", string(metrics.StatusSuccess), metrics.OpLabels{}, time.Millisecond)
+	r.metrics.RecordOp("put", string(metrics.StatusError), metrics.OpLabels{}, time.Millisecond)
+	stop()
+	close(samples)
+
+	// Stopping samples the partial interval
+	var got []metrics.WindowStats
+	for s := range samples {
+		got = append(got, s)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d samples, want 1", len(got))
+	}
+	if put := got[0].Ops["put"]; put.Ops != 2 || put.Errors != 1 {
+		t.Errorf("put = %+v, want 2 requests and 1 error", put)
+	}
+}
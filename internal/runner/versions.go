@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/paragkamble/s3bench/internal/data"
+	"github.com/paragkamble/s3bench/internal/s3"
+	"github.com/paragkamble/s3bench/internal/workload"
+	"go.uber.org/zap"
+)
+
+// errNoVersions is returned by version operations before any key has a
+// noncurrent version
+var errNoVersions = errors.New("no noncurrent versions tracked yet")
+
+// executeOverwrite PUTs a new version of a key that already has one. Each
+// version gets distinct content, so reading the wrong version is detected.
+// Which keys have versions depends on every worker's progress, so the key
+// is picked from the worker's version stream, which the rest of the run
+// does not share; the content is drawn from the worker's stream either
+// way, so seeded runs repeat it.
+func (r *Runner) executeOverwrite(ctx context.Context, key string, w *workerState) error {
+	if existing, ok := r.versions.RandomKey(w.versions); ok {
+		key = existing
+		noteKey(ctx, key)
+	}
+
+	dataKey := fmt.Sprintf("%s@%016x", key, w.rng.Uint64())
+	return r.executePutWithSize(ctx, key, dataKey, w.nextSize(workload.OpOverwrite), workload.OpOverwrite, w)
+}
+
+// executeGetVersion GETs a random noncurrent version by version ID and
+// checks it against the hash recorded when it was written
+func (r *Runner) executeGetVersion(ctx context.Context, rng *rand.Rand) error {
+	key, version, ok := r.versions.RandomNoncurrent(rng)
+	if !ok {
+		return errNoVersions
+	}
+
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	var size int64
+	var hash string
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "get_version", func(ctx context.Context) error {
+		body, _, n, err := r.s3Client.GetObjectVersion(ctx, key, version.ID)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		size = n
+		hash, err = data.ComputeHash(body)
+		return err
+	})
+
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpGetVersion))
+		return err
+	}
+
+	if size != version.Size || hash != version.SHA256 {
+		r.metrics.RecordVerifyFailure()
+		r.logger.Error("version verification failed",
+			zap.String("key", key),
+			zap.String("version_id", version.ID),
+			zap.Int64("expected_size", version.Size),
+			zap.Int64("actual_size", size),
+			zap.String("expected_sha256", version.SHA256),
+			zap.String("actual_sha256", hash),
+		)
+		return fmt.Errorf("version %s of %s does not match its recorded hash", version.ID, key)
+	}
+	r.metrics.RecordVerifySuccess()
+
+	return nil
+}
+
+// executeListVersions lists the versions of a key with a version chain, so
+// listing cost can be observed as chains grow
+func (r *Runner) executeListVersions(ctx context.Context, key string, rng *rand.Rand) error {
+	if existing, ok := r.versions.RandomKey(rng); ok {
+		key = existing
+	}
+
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	var listing *s3.VersionPage
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "list_versions", func(ctx context.Context) error {
+		var err error
+		listing, err = r.s3Client.ListObjectVersionsPage(ctx, key, "", "")
+		return err
+	})
+
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpListVersions))
+		return err
+	}
+
+	r.logger.Debug("list versions",
+		zap.String("key", key),
+		zap.Int("listed", len(listing.Versions)),
+		zap.Int("tracked", r.versions.Len(key)),
+	)
+
+	return nil
+}
+
+// executeDeleteVersion permanently deletes a random noncurrent version. The
+// current version is never chosen, so the key's visible content is unchanged.
+func (r *Runner) executeDeleteVersion(ctx context.Context, rng *rand.Rand) error {
+	if r.cfg.KeepData {
+		// Skip delete in keep-data mode
+		return nil
+	}
+
+	key, version, ok := r.versions.RandomNoncurrent(rng)
+	if !ok {
+		return errNoVersions
+	}
+
+	retryCfg := s3.DefaultRetryConfig()
+	retryCfg.MaxAttempts = r.cfg.MaxRetries
+
+	err := s3.WithRetry(ctx, retryCfg, r.logger, "delete_version", func(ctx context.Context) error {
+		return r.s3Client.DeleteObjectVersion(ctx, key, version.ID)
+	})
+
+	if err != nil {
+		r.metrics.RecordRetry(string(workload.OpDeleteVersion))
+		return err
+	}
+
+	r.versions.Remove(key, version.ID)
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/paragkamble/s3bench/internal/config"
+	"github.com/paragkamble/s3bench/internal/data"
+	"github.com/paragkamble/s3bench/internal/s3"
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+// workerSpec holds what the workers' generators are derived from. It is
+// parsed once and shared; each worker gets its own generators from it, so
+// that workers draw without contending for locks.
+type workerSpec struct {
+	mix     *workload.Mix
+	sizes   *data.SizeSpec
+	opSizes map[workload.OpType]*data.SizeSpec // ops with their own sizes
+	ranges  *data.SizeSpec
+	classes *workload.StorageClassMix
+	keygen  *workload.KeyGenerator
+	limiter workload.RateLimiter // shared by all workers, or nil for Poisson arrivals
+}
+
+// newWorkerSpec parses the workload options the workers' generators are
+// derived from
+func newWorkerSpec(cfg *config.Config, keygen *workload.KeyGenerator) (*workerSpec, error) {
+	mix, err := workload.NewMix(cfg.Mix, cfg.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scheduler: %w", err)
+	}
+
+	sizes, err := data.ParseSizeSpec(cfg.Size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse size distribution: %w", err)
+	}
+
+	opSizes := make(map[workload.OpType]*data.SizeSpec)
+	for op, size := range cfg.Sizes {
+		spec, err := data.ParseSizeSpec(size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s size distribution: %w", op, err)
+		}
+		opSizes[workload.OpType(op)] = spec
+	}
+
+	// Overwrites replace objects written by PUT
+	if _, ok := opSizes[workload.OpOverwrite]; !ok && opSizes[workload.OpPut] != nil {
+		opSizes[workload.OpOverwrite] = opSizes[workload.OpPut]
+	}
+
+	ranges, err := data.ParseSizeSpec(cfg.RangeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse range size: %w", err)
+	}
+
+	classes, err := workload.ParseStorageClassMix(cfg.StorageClass, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage class: %w", err)
+	}
+
+	// Poisson arrivals are generated per worker
+	var limiter workload.RateLimiter
+	if cfg.RateType != "poisson" || cfg.RateLimit <= 0 {
+		limiter = workload.NewRateLimiter(cfg.RateType, cfg.RateLimit, 0)
+	}
+
+	return &workerSpec{
+		mix:     mix,
+		sizes:   sizes,
+		opSizes: opSizes,
+		ranges:  ranges,
+		classes: classes,
+		keygen:  keygen,
+		limiter: limiter,
+	}, nil
+}
+
+// workerState holds the random generators of one worker. Each draws from
+// its own stream of the run's master seed, so that a run with the same
+// seed, concurrency and operation count issues the same operations. None
+// of it is safe for concurrent use.
+type workerState struct {
+	id        int
+	rng       *rand.Rand // verify sampling, range offsets and other picks
+	versions  *rand.Rand // picks among version chains, which other workers grow
+	keys      *workload.KeyGenerator
+	scheduler *workload.Scheduler
+	sizes     data.SizeDistribution
+	opSizes   map[workload.OpType]data.SizeDistribution
+	ranges    data.SizeDistribution
+	classes   *workload.StorageClassMix
+	limiter   workload.RateLimiter
+
+	// Operations left to issue, or -1 when workers share the limit
+	quota int64
+}
+
+// worker creates the generators of worker id out of concurrency. Poisson
+// arrivals are split among the workers, since the sum of Poisson processes
+// is one at the summed rate.
+func (s *workerSpec) worker(cfg *config.Config, id int, seed int64) *workerState {
+	limiter := s.limiter
+	if limiter == nil {
+		lambda := cfg.RateLimit / float64(cfg.Concurrency)
+		limiter = workload.NewPoissonRateLimiter(lambda, workload.DeriveSeed(seed, id, workload.StreamArrivals))
+	}
+
+	// Ops with their own sizes draw from their own streams, so that adding
+	// one leaves the sizes of the others unchanged
+	opSizes := make(map[workload.OpType]data.SizeDistribution, len(s.opSizes))
+	for op, spec := range s.opSizes {
+		opSizes[op] = spec.New(workload.DeriveSeed(seed, id, workload.StreamSizes+"/"+string(op)))
+	}
+
+	rng := rand.New(rand.NewSource(workload.DeriveSeed(seed, id, workload.StreamChoices)))
+	return &workerState{
+		id:        id,
+		rng:       rng,
+		keys:      s.keygen.ForWorker(id, rng),
+		scheduler: s.mix.Scheduler(workload.DeriveSeed(seed, id, workload.StreamOps)),
+		sizes:     s.sizes.New(workload.DeriveSeed(seed, id, workload.StreamSizes)),
+		opSizes:   opSizes,
+		ranges:    s.ranges.New(workload.DeriveSeed(seed, id, workload.StreamRanges)),
+		classes:   s.classes.WithSeed(workload.DeriveSeed(seed, id, workload.StreamClasses)),
+		versions:  rand.New(rand.NewSource(workload.DeriveSeed(seed, id, workload.StreamVersions))),
+		limiter:   limiter,
+		quota:     -1,
+	}
+}
+
+// nextSize draws the size of an object written by op
+func (w *workerState) nextSize(op workload.OpType) int64 {
+	if dist, ok := w.opSizes[op]; ok {
+		return dist.Next()
+	}
+	return w.sizes.Next()
+}
+
+// deleteBatch picks up to batch distinct keys to delete together, starting
+// with key. Picks that repeat a key are dropped; after 4*batch picks the
+// batch is returned as it is, so small keyspaces cannot stall it.
+func (w *workerState) deleteBatch(key string, batch int) []s3.ObjectID {
+	seen := map[string]bool{key: true}
+	objects := []s3.ObjectID{{Key: key}}
+	for attempts := 0; len(objects) < batch && attempts < 4*batch; attempts++ {
+		k := w.keys.Generate(w.scheduler.NextKey())
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		objects = append(objects, s3.ObjectID{Key: k})
+	}
+	return objects
+}
+
+// workerQuota returns worker id's share of the operation count. Shares
+// differ by at most one operation.
+func workerQuota(operations int64, workers, id int) int64 {
+	quota := operations / int64(workers)
+	if int64(id) < operations%int64(workers) {
+		quota++
+	}
+	return quota
+}
@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/paragkamble/s3bench/internal/config"
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+func TestWorkerQuota(t *testing.T) {
+	tests := []struct {
+		operations int64
+		workers    int
+		want       []int64
+	}{
+		{10, 2, []int64{5, 5}},
+		{10, 3, []int64{4, 3, 3}},
+		{2, 4, []int64{1, 1, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		var sum int64
+		for id, want := range tt.want {
+			got := workerQuota(tt.operations, tt.workers, id)
+			if got != want {
+				t.Errorf("workerQuota(%d, %d, %d) = %d, want %d", tt.operations, tt.workers, id, got, want)
+			}
+			sum += got
+		}
+		if sum != tt.operations {
+			t.Errorf("quotas of %d operations over %d workers sum to %d", tt.operations, tt.workers, sum)
+		}
+	}
+}
+
+func TestClaimOp(t *testing.T) {
+	r := &Runner{cfg: &config.Config{Operations: 1000}}
+
+	var claimed int64
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r.claimOp() {
+				atomic.AddInt64(&claimed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if claimed != 1000 {
+		t.Errorf("claimed %d operations, want 1000", claimed)
+	}
+	if r.opsCounter != 1000 {
+		t.Errorf("opsCounter = %d, want 1000", r.opsCounter)
+	}
+}
+
+func TestWorkerNextSize(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Sizes = map[string]string{"put": "fixed:4KiB", "multipart_put": "fixed:64MiB"}
+
+	keygen, err := workload.NewKeyGenerator("", cfg.KeyTemplate, cfg.Keys, "run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := newWorkerSpec(cfg, keygen)
+	if err != nil {
+		t.Fatalf("newWorkerSpec() failed: %v", err)
+	}
+	w := spec.worker(cfg, 0, 1)
+
+	tests := []struct {
+		op   workload.OpType
+		want int64
+	}{
+		{workload.OpPut, 4 << 10},
+		{workload.OpOverwrite, 4 << 10}, // follows put
+		{workload.OpMultipartPut, 64 << 20},
+		{workload.OpCopy, 1 << 20}, // --size default
+	}
+
+	for _, tt := range tests {
+		if got := w.nextSize(tt.op); got != tt.want {
+			t.Errorf("nextSize(%s) = %d, want %d", tt.op, got, tt.want)
+		}
+	}
+
+	cfg.Sizes = map[string]string{"put": "hist:4KiB=oops"}
+	if _, err := newWorkerSpec(cfg, keygen); err == nil {
+		t.Error("newWorkerSpec() accepted an invalid put size")
+	}
+}
+
+func TestWorkerDeleteBatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		keys  int
+		batch int
+		full  bool // whether the batch must be filled
+	}{
+		{"distinct keys", 10000, 100, true},
+		{"small keyspace", 5, 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig()
+			cfg.Keys = tt.keys
+
+			keygen, err := workload.NewKeyGenerator("", cfg.KeyTemplate, cfg.Keys, "run")
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec, err := newWorkerSpec(cfg, keygen)
+			if err != nil {
+				t.Fatalf("newWorkerSpec() failed: %v", err)
+			}
+
+			first := keygen.Generate(0)
+			objects := spec.worker(cfg, 0, 1).deleteBatch(first, tt.batch)
+			if len(objects) == 0 || objects[0].Key != first {
+				t.Fatalf("deleteBatch() = %v, want %q first", objects, first)
+			}
+			if len(objects) > tt.batch || tt.full && len(objects) != tt.batch {
+				t.Errorf("deleteBatch() returned %d keys, want %d", len(objects), tt.batch)
+			}
+
+			seen := make(map[string]bool)
+			for _, obj := range objects {
+				if seen[obj.Key] {
+					t.Errorf("deleteBatch() repeats %q", obj.Key)
+				}
+				seen[obj.Key] = true
+			}
+
+			// The same seed collects the same batch
+			again := spec.worker(cfg, 0, 1).deleteBatch(first, tt.batch)
+			if len(again) != len(objects) {
+				t.Fatalf("second deleteBatch() returned %d keys, want %d", len(again), len(objects))
+			}
+			for i := range objects {
+				if again[i] != objects[i] {
+					t.Errorf("second deleteBatch()[%d] = %q, want %q", i, again[i].Key, objects[i].Key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package s3
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/paragkamble/s3bench/internal/data"
+	"github.com/paragkamble/s3bench/internal/metrics"
+)
+
+// Multipart upload checksum types
+const (
+	// ChecksumComposite makes the object checksum a checksum of the part
+	// checksums, reported with a "-<parts>" suffix
+	ChecksumComposite = "COMPOSITE"
+
+	// ChecksumFullObject makes the object checksum cover the whole object,
+	// as if it had been uploaded in one request. CRC algorithms only.
+	ChecksumFullObject = "FULL_OBJECT"
+)
+
+// ErrChecksumMismatch is returned when data does not match the checksum
+// the server reported for it
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ChecksumConfig selects the checksums sent with uploads and validated on
+// downloads
+type ChecksumConfig struct {
+	Algorithm   string // data.ChecksumCRC32C etc., or empty for none
+	Type        string // multipart checksum type; empty picks the algorithm's default
+	ValidateGet bool   // request checksums on GET and validate them
+}
+
+// Label returns the metric label for the checksum algorithm
+func (c ChecksumConfig) Label() string {
+	if c.Algorithm == "" {
+		return "none"
+	}
+	return c.Algorithm
+}
+
+// Validate checks the checksum settings
+func (c ChecksumConfig) Validate() error {
+	if c.Algorithm == "" {
+		return nil
+	}
+	if _, err := data.ParseChecksumAlgorithm(c.Algorithm); err != nil {
+		return err
+	}
+
+	switch c.multipartType() {
+	case ChecksumComposite:
+		if c.Algorithm == data.ChecksumCRC64NVME {
+			return fmt.Errorf("%s only supports full-object multipart checksums", c.Algorithm)
+		}
+	case ChecksumFullObject:
+		if !data.IsCRCChecksum(c.Algorithm) {
+			return fmt.Errorf("%s does not support full-object multipart checksums", c.Algorithm)
+		}
+	default:
+		return fmt.Errorf("unsupported multipart checksum type %q", c.Type)
+	}
+	return nil
+}
+
+// multipartType returns the multipart checksum type, defaulting to
+// full-object for CRC64NVME and composite for everything else
+func (c ChecksumConfig) multipartType() string {
+	if c.Type != "" {
+		return c.Type
+	}
+	if c.Algorithm == data.ChecksumCRC64NVME {
+		return ChecksumFullObject
+	}
+	return ChecksumComposite
+}
+
+// checksumHeader returns the header carrying a checksum value
+func checksumHeader(algorithm string) string {
+	return "x-amz-checksum-" + strings.ToLower(algorithm)
+}
+
+// withHeaders returns a request option that sets the given header pairs.
+// Checksums are computed here and sent as raw headers rather than through
+// the SDK's ChecksumAlgorithm inputs, which predate CRC64NVME and the
+// full-object checksum type.
+func withHeaders(kv ...string) func(*s3.Options) {
+	return func(o *s3.Options) {
+		for i := 0; i+1 < len(kv); i += 2 {
+			o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue(kv[i], kv[i+1]))
+		}
+	}
+}
+
+// bodyChecksum computes the configured checksum of a seekable body and
+// rewinds it
+func (c *Client) bodyChecksum(body io.Reader) (string, error) {
+	seeker, ok := body.(io.ReadSeeker)
+	if !ok {
+		return "", fmt.Errorf("checksums require a seekable body")
+	}
+
+	sum, err := data.ComputeChecksum(seeker, c.checksum.Algorithm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind body: %w", err)
+	}
+	return sum, nil
+}
+
+// checksumHeaders returns a request option sending a precomputed checksum
+// with an upload
+func checksumHeaders(algorithm, sum string) func(*s3.Options) {
+	return withHeaders(
+		"x-amz-sdk-checksum-algorithm", algorithm,
+		checksumHeader(algorithm), sum,
+	)
+}
+
+// setPartChecksum sets a part checksum on a completed part. CRC64NVME has
+// no field here, but it is always full-object, where part checksums in the
+// completion request are optional.
+func setPartChecksum(part *types.CompletedPart, algorithm, sum string) {
+	switch algorithm {
+	case data.ChecksumCRC32C:
+		part.ChecksumCRC32C = &sum
+	case data.ChecksumSHA1:
+		part.ChecksumSHA1 = &sum
+	case data.ChecksumSHA256:
+		part.ChecksumSHA256 = &sum
+	}
+}
+
+// responseChecksum returns the checksum header of a response for the
+// algorithm, or the first checksum header present when algorithm is empty
+func responseChecksum(md middleware.Metadata, algorithm string) (string, string) {
+	resp, ok := awsmiddleware.GetRawResponse(md).(*smithyhttp.Response)
+	if !ok {
+		return "", ""
+	}
+
+	algorithms := []string{algorithm}
+	if algorithm == "" {
+		algorithms = []string{data.ChecksumCRC64NVME, data.ChecksumCRC32C, data.ChecksumSHA256, data.ChecksumSHA1}
+	}
+	for _, alg := range algorithms {
+		if v := resp.Header.Get(checksumHeader(alg)); v != "" {
+			return alg, v
+		}
+	}
+	return "", ""
+}
+
+// checkUploadChecksum compares the checksum the server reported for a
+// completed upload with the expected value
+func (c *Client) checkUploadChecksum(md middleware.Metadata, expected string) error {
+	_, reported := responseChecksum(md, c.checksum.Algorithm)
+	switch {
+	case reported == "":
+		c.metrics.RecordChecksumValidation(c.checksum.Algorithm, "skipped")
+	case reported != expected:
+		c.metrics.RecordChecksumValidation(c.checksum.Algorithm, "mismatch")
+		return fmt.Errorf("%w: %s expected %s, server reported %s", ErrChecksumMismatch, c.checksum.Algorithm, expected, reported)
+	default:
+		c.metrics.RecordChecksumValidation(c.checksum.Algorithm, "success")
+	}
+	return nil
+}
+
+// validateBody wraps a GET response body so that reading it to the end
+// validates the checksum the server returned. Multipart composite
+// checksums cannot be checked without the part boundaries and are skipped.
+func (c *Client) validateBody(body io.ReadCloser, md middleware.Metadata) io.ReadCloser {
+	algorithm, expected := responseChecksum(md, "")
+	if algorithm == "" || strings.Contains(expected, "-") {
+		c.metrics.RecordChecksumValidation(c.checksum.Label(), "skipped")
+		return body
+	}
+
+	h, err := data.NewChecksumHash(algorithm)
+	if err != nil {
+		return body
+	}
+	return &checksumReader{
+		ReadCloser: body,
+		client:     c,
+		algorithm:  algorithm,
+		expected:   expected,
+		hash:       h,
+	}
+}
+
+// checksumReader validates a checksum once the body has been fully read
+type checksumReader struct {
+	io.ReadCloser
+	client    *Client
+	algorithm string
+	expected  string
+	hash      hash.Hash
+	done      bool
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && !r.done {
+		r.done = true
+		actual := base64.StdEncoding.EncodeToString(r.hash.Sum(nil))
+		if actual != r.expected {
+			r.client.metrics.RecordChecksumValidation(r.algorithm, "mismatch")
+			return n, fmt.Errorf("%w: %s expected %s, got %s", ErrChecksumMismatch, r.algorithm, r.expected, actual)
+		}
+		r.client.metrics.RecordChecksumValidation(r.algorithm, "success")
+	}
+	return n, err
+}
+
+// errorStatus returns the metric status for a failed request
+func errorStatus(err error) metrics.OpStatus {
+	if IsChecksumMismatch(err) {
+		return metrics.StatusChecksumMismatch
+	}
+	return metrics.StatusError
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/paragkamble/s3bench/internal/data"
 	"github.com/paragkamble/s3bench/internal/metrics"
 	"go.uber.org/zap"
 )
@@ -25,6 +27,14 @@ type Client struct {
 	bucket   string
 	logger   *zap.Logger
 	metrics  *metrics.Metrics
+	sse      SSEConfig
+	checksum ChecksumConfig
+	labels   metrics.OpLabels
+
+	// Multipart uploads started by this client and not yet completed or
+	// aborted, by upload ID
+	uploadsMu sync.Mutex
+	uploads   map[string]string
 }
 
 // ClientConfig holds configuration for creating an S3 client
@@ -36,12 +46,21 @@ type ClientConfig struct {
 	SecretKey     string
 	PathStyle     bool
 	SkipTLSVerify bool
+	SSE           SSEConfig
+	Checksum      ChecksumConfig
 	Logger        *zap.Logger
 	Metrics       *metrics.Metrics
 }
 
 // NewClient creates a new S3 client
 func NewClient(ctx context.Context, cfg ClientConfig) (*Client, error) {
+	if err := cfg.SSE.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid encryption config: %w", err)
+	}
+	if err := cfg.Checksum.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid checksum config: %w", err)
+	}
+
 	var opts []func(*config.LoadOptions) error
 
 	// Region
@@ -101,9 +120,28 @@ func NewClient(ctx context.Context, cfg ClientConfig) (*Client, error) {
 		bucket:   cfg.Bucket,
 		logger:   cfg.Logger,
 		metrics:  cfg.Metrics,
+		sse:      cfg.SSE,
+		checksum: cfg.Checksum,
+		labels: metrics.OpLabels{
+			SSE:      cfg.SSE.Label(),
+			Checksum: cfg.Checksum.Label(),
+		},
+		uploads: make(map[string]string),
 	}, nil
 }
 
+// recordOp records an operation labeled with the client's request settings
+func (c *Client) recordOp(op metrics.OpType, status metrics.OpStatus, duration time.Duration) {
+	c.recordClassOp(op, status, "", duration)
+}
+
+// recordClassOp records an operation on an object of the given storage class
+func (c *Client) recordClassOp(op metrics.OpType, status metrics.OpStatus, storageClass string, duration time.Duration) {
+	labels := c.labels
+	labels.StorageClass = storageClass
+	c.metrics.RecordOp(string(op), string(status), labels, duration)
+}
+
 // Check performs a health check by doing a HEAD bucket operation
 func (c *Client) Check(ctx context.Context) error {
 	_, err := c.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{
@@ -157,27 +195,82 @@ func (c *Client) SetVersioning(ctx context.Context, enabled bool) error {
 	return nil
 }
 
-// PutObject uploads an object to S3
-func (c *Client) PutObject(ctx context.Context, key string, body io.Reader, size int64, metadata map[string]string) error {
+// ObjectOptions holds optional settings for object writes
+type ObjectOptions struct {
+	// Tags are set on the object when it is written
+	Tags map[string]string
+
+	// ReplaceMetadata, when set on a copy, replaces the source object's
+	// metadata instead of copying it
+	ReplaceMetadata map[string]string
+
+	// StorageClass is the class to write the object in; empty uses the
+	// bucket's default placement
+	StorageClass string
+
+	// Op is the operation a write is recorded as, such as overwrite; empty
+	// records it as put or multipart_put
+	Op metrics.OpType
+}
+
+// op returns the operation a write is recorded as
+func (o ObjectOptions) op(def metrics.OpType) metrics.OpType {
+	if o.Op != "" {
+		return o.Op
+	}
+	return def
+}
+
+// PutObject uploads an object to S3 and returns its version ID, if any
+func (c *Client) PutObject(ctx context.Context, key string, body io.Reader, size int64, metadata map[string]string, opts ObjectOptions) (string, error) {
+	op := opts.op(metrics.OpPut)
+	sse, kmsKeyID := c.serverSideEncryption()
+	ck := c.customerKey(key)
+
+	var optFns []func(*s3.Options)
+	var sum string
+	if c.checksum.Algorithm != "" {
+		var err error
+		if sum, err = c.bodyChecksum(body); err != nil {
+			return "", fmt.Errorf("put failed: %w", err)
+		}
+		optFns = append(optFns, checksumHeaders(c.checksum.Algorithm, sum))
+	}
+
 	start := time.Now()
 
-	_, err := c.s3Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:        aws.String(c.bucket),
-		Key:           aws.String(key),
-		Body:          body,
-		ContentLength: aws.Int64(size),
-		Metadata:      metadata,
-	})
+	result, err := c.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(c.bucket),
+		Key:                  aws.String(key),
+		Body:                 body,
+		ContentLength:        aws.Int64(size),
+		Metadata:             metadata,
+		Tagging:              encodeTags(opts.Tags),
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          kmsKeyID,
+		SSECustomerAlgorithm: ck.algorithm,
+		SSECustomerKey:       ck.key,
+		SSECustomerKeyMD5:    ck.keyMD5,
+		StorageClass:         types.StorageClass(opts.StorageClass),
+	}, optFns...)
 
 	duration := time.Since(start)
 
 	if err != nil {
-		c.metrics.RecordOp(string(metrics.OpPut), string(metrics.StatusError), duration)
-		return fmt.Errorf("put failed: %w", err)
+		c.recordClassOp(op, errorStatus(err), opts.StorageClass, duration)
+		return "", fmt.Errorf("put failed: %w", err)
 	}
 
-	c.metrics.RecordOp(string(metrics.OpPut), string(metrics.StatusSuccess), duration)
+	if sum != "" {
+		if err := c.checkUploadChecksum(result.ResultMetadata, sum); err != nil {
+			c.recordClassOp(op, metrics.StatusChecksumMismatch, opts.StorageClass, duration)
+			return "", fmt.Errorf("put %s: %w", key, err)
+		}
+	}
+
+	c.recordClassOp(op, metrics.StatusSuccess, opts.StorageClass, duration)
 	c.metrics.RecordBytesWritten(size)
+	c.metrics.RecordTransfer(string(op), size, duration)
 
 	c.logger.Debug("put object",
 		zap.String("key", key),
@@ -185,22 +278,46 @@ func (c *Client) PutObject(ctx context.Context, key string, body io.Reader, size
 		zap.Duration("latency", duration),
 	)
 
-	return nil
+	return aws.ToString(result.VersionId), nil
 }
 
 // GetObject downloads an object from S3
 func (c *Client) GetObject(ctx context.Context, key string) (io.ReadCloser, map[string]string, int64, error) {
+	return c.GetObjectVersion(ctx, key, "")
+}
+
+// GetObjectVersion downloads a specific version of an object, or the
+// current version when versionID is empty
+func (c *Client) GetObjectVersion(ctx context.Context, key, versionID string) (io.ReadCloser, map[string]string, int64, error) {
+	ck := c.customerKey(key)
+	input := &s3.GetObjectInput{
+		Bucket:               aws.String(c.bucket),
+		Key:                  aws.String(key),
+		SSECustomerAlgorithm: ck.algorithm,
+		SSECustomerKey:       ck.key,
+		SSECustomerKeyMD5:    ck.keyMD5,
+	}
+	op := metrics.OpGet
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+		op = metrics.OpGetVersion
+	}
+
+	// The mode header is set directly so that validation covers every
+	// algorithm, including those the SDK cannot validate itself
+	var optFns []func(*s3.Options)
+	if c.checksum.ValidateGet {
+		optFns = append(optFns, withHeaders("x-amz-checksum-mode", "ENABLED"))
+	}
+
 	start := time.Now()
 
-	result, err := c.s3Client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(c.bucket),
-		Key:    aws.String(key),
-	})
+	result, err := c.s3Client.GetObject(ctx, input, optFns...)
 
 	duration := time.Since(start)
 
 	if err != nil {
-		c.metrics.RecordOp(string(metrics.OpGet), string(metrics.StatusError), duration)
+		c.recordOp(op, metrics.StatusError, duration)
 		return nil, nil, 0, fmt.Errorf("get failed: %w", err)
 	}
 
@@ -209,46 +326,226 @@ func (c *Client) GetObject(ctx context.Context, key string) (io.ReadCloser, map[
 		size = *result.ContentLength
 	}
 
-	c.metrics.RecordOp(string(metrics.OpGet), string(metrics.StatusSuccess), duration)
+	c.recordClassOp(op, metrics.StatusSuccess, string(result.StorageClass), duration)
 	c.metrics.RecordBytesRead(size)
+	c.metrics.RecordTransfer(string(op), size, duration)
 
 	c.logger.Debug("get object",
 		zap.String("key", key),
+		zap.String("version_id", versionID),
 		zap.Int64("size", size),
 		zap.Duration("latency", duration),
 	)
 
+	if c.checksum.ValidateGet {
+		return c.validateBody(result.Body, result.ResultMetadata), result.Metadata, size, nil
+	}
 	return result.Body, result.Metadata, size, nil
 }
 
+// GetObjectRange downloads a byte range of an object. rangeSpec is an HTTP
+// Range value such as "bytes=0-1023" or "bytes=-1024". It returns the offset
+// of the first returned byte and the total object size from Content-Range.
+func (c *Client) GetObjectRange(ctx context.Context, key string, rangeSpec string) (io.ReadCloser, map[string]string, int64, int64, error) {
+	ck := c.customerKey(key)
+
+	start := time.Now()
+
+	result, err := c.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:               aws.String(c.bucket),
+		Key:                  aws.String(key),
+		Range:                aws.String(rangeSpec),
+		SSECustomerAlgorithm: ck.algorithm,
+		SSECustomerKey:       ck.key,
+		SSECustomerKeyMD5:    ck.keyMD5,
+	})
+
+	duration := time.Since(start)
+
+	if err != nil {
+		c.recordOp(metrics.OpRangeGet, metrics.StatusError, duration)
+		return nil, nil, 0, 0, fmt.Errorf("range get failed: %w", err)
+	}
+
+	offset, total, err := parseContentRange(aws.ToString(result.ContentRange))
+	if err != nil {
+		result.Body.Close()
+		c.recordOp(metrics.OpRangeGet, metrics.StatusError, duration)
+		return nil, nil, 0, 0, fmt.Errorf("range get failed: %w", err)
+	}
+
+	size := aws.ToInt64(result.ContentLength)
+
+	c.recordClassOp(metrics.OpRangeGet, metrics.StatusSuccess, string(result.StorageClass), duration)
+	c.metrics.RecordBytesRead(size)
+	c.metrics.RecordTransfer(string(metrics.OpRangeGet), size, duration)
+
+	c.logger.Debug("get object range",
+		zap.String("key", key),
+		zap.String("range", rangeSpec),
+		zap.Int64("size", size),
+		zap.Duration("latency", duration),
+	)
+
+	return result.Body, result.Metadata, offset, total, nil
+}
+
+// parseContentRange parses "bytes <first>-<last>/<total>"
+func parseContentRange(s string) (int64, int64, error) {
+	var first, last, total int64
+	if _, err := fmt.Sscanf(s, "bytes %d-%d/%d", &first, &last, &total); err != nil {
+		return 0, 0, fmt.Errorf("invalid content range %q: %w", s, err)
+	}
+	return first, total, nil
+}
+
 // DeleteObject deletes an object from S3
 func (c *Client) DeleteObject(ctx context.Context, key string) error {
-	start := time.Now()
+	return c.DeleteObjectVersion(ctx, key, "")
+}
 
-	_, err := c.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+// DeleteObjectVersion permanently deletes a specific version of an object,
+// or deletes the current version when versionID is empty
+func (c *Client) DeleteObjectVersion(ctx context.Context, key, versionID string) error {
+	input := &s3.DeleteObjectInput{
 		Bucket: aws.String(c.bucket),
 		Key:    aws.String(key),
-	})
+	}
+	op := metrics.OpDelete
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+		op = metrics.OpDeleteVersion
+	}
+
+	start := time.Now()
+
+	_, err := c.s3Client.DeleteObject(ctx, input)
 
 	duration := time.Since(start)
 
 	if err != nil {
-		c.metrics.RecordOp(string(metrics.OpDelete), string(metrics.StatusError), duration)
+		c.recordOp(op, metrics.StatusError, duration)
 		return fmt.Errorf("delete failed: %w", err)
 	}
 
-	c.metrics.RecordOp(string(metrics.OpDelete), string(metrics.StatusSuccess), duration)
+	c.recordOp(op, metrics.StatusSuccess, duration)
 
 	c.logger.Debug("delete object",
 		zap.String("key", key),
+		zap.String("version_id", versionID),
 		zap.Duration("latency", duration),
 	)
 
 	return nil
 }
 
-// CopyObject copies an object within or across buckets
-func (c *Client) CopyObject(ctx context.Context, srcKey, dstKey, dstBucket string) error {
+// ObjectID identifies an object, optionally at a specific version
+type ObjectID struct {
+	Key       string
+	VersionID string
+}
+
+// DeleteFailure is a per-object error from a batch delete
+type DeleteFailure struct {
+	Key       string
+	VersionID string
+	Code      string
+	Message   string
+}
+
+// MaxDeleteObjects is the maximum number of objects in one DeleteObjects request
+const MaxDeleteObjects = 1000
+
+// DeleteObjects deletes up to MaxDeleteObjects objects in a single request.
+// Objects the server failed to delete are returned as failures rather than
+// as an error; err is only set when the request itself failed. In quiet mode
+// the server reports failures only.
+func (c *Client) DeleteObjects(ctx context.Context, objects []ObjectID, quiet bool) ([]DeleteFailure, error) {
+	if len(objects) == 0 {
+		return nil, nil
+	}
+	if len(objects) > MaxDeleteObjects {
+		return nil, fmt.Errorf("too many objects in batch delete: %d > %d", len(objects), MaxDeleteObjects)
+	}
+
+	identifiers := make([]types.ObjectIdentifier, len(objects))
+	for i, obj := range objects {
+		identifiers[i] = types.ObjectIdentifier{Key: aws.String(obj.Key)}
+		if obj.VersionID != "" {
+			identifiers[i].VersionId = aws.String(obj.VersionID)
+		}
+	}
+
+	start := time.Now()
+
+	result, err := c.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(c.bucket),
+		Delete: &types.Delete{
+			Objects: identifiers,
+			Quiet:   aws.Bool(quiet),
+		},
+	})
+
+	duration := time.Since(start)
+
+	if err != nil {
+		c.recordOp(metrics.OpMultiDelete, metrics.StatusError, duration)
+		return nil, fmt.Errorf("batch delete failed: %w", err)
+	}
+
+	deleted, failures := deleteResult(len(objects), result, quiet)
+
+	c.recordOp(metrics.OpMultiDelete, metrics.StatusSuccess, duration)
+	c.metrics.RecordBatchDelete(deleted, len(failures))
+
+	c.logger.Debug("delete objects",
+		zap.Int("objects", len(objects)),
+		zap.Int("failed", len(failures)),
+		zap.Duration("latency", duration),
+	)
+
+	return failures, nil
+}
+
+// deleteResult maps a DeleteObjects response for requested objects to the
+// number deleted and the per-object failures. Quiet responses list failures
+// only, so every other requested object counts as deleted.
+func deleteResult(requested int, result *s3.DeleteObjectsOutput, quiet bool) (int, []DeleteFailure) {
+	failures := make([]DeleteFailure, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		failures = append(failures, DeleteFailure{
+			Key:       aws.ToString(e.Key),
+			VersionID: aws.ToString(e.VersionId),
+			Code:      aws.ToString(e.Code),
+			Message:   aws.ToString(e.Message),
+		})
+	}
+
+	if quiet {
+		return requested - len(failures), failures
+	}
+	return len(result.Deleted), failures
+}
+
+// CopyObject copies an object within or across buckets and returns the
+// destination version ID, if any. Metadata and tags are copied from the
+// source unless replaced through opts.
+func (c *Client) CopyObject(ctx context.Context, srcKey, dstKey, dstBucket string, opts ObjectOptions) (string, error) {
+	op := metrics.OpCopy
+	if opts.ReplaceMetadata != nil {
+		op = metrics.OpCopyReplaceMetadata
+	}
+	return c.copyObject(ctx, op, srcKey, dstKey, dstBucket, opts)
+}
+
+// TransitionObject changes an object's storage class by copying it onto
+// itself, keeping its metadata and tags
+func (c *Client) TransitionObject(ctx context.Context, key, storageClass string) (string, error) {
+	return c.copyObject(ctx, metrics.OpTransition, key, key, "", ObjectOptions{StorageClass: storageClass})
+}
+
+// copyObject performs a server-side copy recorded as op
+func (c *Client) copyObject(ctx context.Context, op metrics.OpType, srcKey, dstKey, dstBucket string, opts ObjectOptions) (string, error) {
 	start := time.Now()
 
 	if dstBucket == "" {
@@ -257,45 +554,112 @@ func (c *Client) CopyObject(ctx context.Context, srcKey, dstKey, dstBucket strin
 
 	copySource := fmt.Sprintf("%s/%s", c.bucket, srcKey)
 
-	_, err := c.s3Client.CopyObject(ctx, &s3.CopyObjectInput{
-		Bucket:     aws.String(dstBucket),
-		Key:        aws.String(dstKey),
-		CopySource: aws.String(copySource),
-	})
+	sse, kmsKeyID := c.serverSideEncryption()
+	srcCK := c.customerKey(srcKey)
+	dstCK := c.customerKey(dstKey)
+
+	input := &s3.CopyObjectInput{
+		Bucket:                         aws.String(dstBucket),
+		Key:                            aws.String(dstKey),
+		CopySource:                     aws.String(copySource),
+		ServerSideEncryption:           sse,
+		SSEKMSKeyId:                    kmsKeyID,
+		SSECustomerAlgorithm:           dstCK.algorithm,
+		SSECustomerKey:                 dstCK.key,
+		SSECustomerKeyMD5:              dstCK.keyMD5,
+		CopySourceSSECustomerAlgorithm: srcCK.algorithm,
+		CopySourceSSECustomerKey:       srcCK.key,
+		CopySourceSSECustomerKeyMD5:    srcCK.keyMD5,
+		StorageClass:                   types.StorageClass(opts.StorageClass),
+	}
+
+	if opts.ReplaceMetadata != nil {
+		input.MetadataDirective = types.MetadataDirectiveReplace
+		input.Metadata = opts.ReplaceMetadata
+	}
+	if opts.Tags != nil {
+		input.TaggingDirective = types.TaggingDirectiveReplace
+		input.Tagging = encodeTags(opts.Tags)
+	}
+
+	result, err := c.s3Client.CopyObject(ctx, input)
 
 	duration := time.Since(start)
 
 	if err != nil {
-		c.metrics.RecordOp(string(metrics.OpCopy), string(metrics.StatusError), duration)
-		return fmt.Errorf("copy failed: %w", err)
+		c.recordClassOp(op, metrics.StatusError, opts.StorageClass, duration)
+		return "", fmt.Errorf("%s failed: %w", op, err)
 	}
 
-	c.metrics.RecordOp(string(metrics.OpCopy), string(metrics.StatusSuccess), duration)
+	c.recordClassOp(op, metrics.StatusSuccess, opts.StorageClass, duration)
 
 	c.logger.Debug("copy object",
+		zap.String("op", string(op)),
 		zap.String("src_key", srcKey),
 		zap.String("dst_key", dstKey),
 		zap.String("dst_bucket", dstBucket),
+		zap.String("storage_class", opts.StorageClass),
 		zap.Duration("latency", duration),
 	)
 
-	return nil
+	return aws.ToString(result.VersionId), nil
 }
 
 // HeadObject retrieves object metadata without downloading
 func (c *Client) HeadObject(ctx context.Context, key string) (map[string]string, int64, error) {
+	return c.HeadObjectVersion(ctx, key, "")
+}
+
+// HeadObjectVersion retrieves the metadata of a specific version of an
+// object, or of the current version when versionID is empty
+func (c *Client) HeadObjectVersion(ctx context.Context, key, versionID string) (map[string]string, int64, error) {
+	info, err := c.StatObjectVersion(ctx, key, versionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return info.Metadata, info.Size, nil
+}
+
+// ObjectInfo is the result of a HEAD request
+type ObjectInfo struct {
+	Metadata map[string]string
+	Size     int64
+
+	ETag string
+
+	// StorageClass is empty for STANDARD, which S3 does not report
+	StorageClass string
+}
+
+// StatObject is HeadObject returning the object's storage class as well
+func (c *Client) StatObject(ctx context.Context, key string) (*ObjectInfo, error) {
+	return c.StatObjectVersion(ctx, key, "")
+}
+
+// StatObjectVersion is HeadObjectVersion returning the object's storage
+// class as well
+func (c *Client) StatObjectVersion(ctx context.Context, key, versionID string) (*ObjectInfo, error) {
+	ck := c.customerKey(key)
+	input := &s3.HeadObjectInput{
+		Bucket:               aws.String(c.bucket),
+		Key:                  aws.String(key),
+		SSECustomerAlgorithm: ck.algorithm,
+		SSECustomerKey:       ck.key,
+		SSECustomerKeyMD5:    ck.keyMD5,
+	}
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+	}
+
 	start := time.Now()
 
-	result, err := c.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
-		Bucket: aws.String(c.bucket),
-		Key:    aws.String(key),
-	})
+	result, err := c.s3Client.HeadObject(ctx, input)
 
 	duration := time.Since(start)
 
 	if err != nil {
-		c.metrics.RecordOp(string(metrics.OpHead), string(metrics.StatusError), duration)
-		return nil, 0, fmt.Errorf("head failed: %w", err)
+		c.recordOp(metrics.OpHead, metrics.StatusError, duration)
+		return nil, fmt.Errorf("head failed: %w", err)
 	}
 
 	size := int64(0)
@@ -303,73 +667,329 @@ func (c *Client) HeadObject(ctx context.Context, key string) (map[string]string,
 		size = *result.ContentLength
 	}
 
-	c.metrics.RecordOp(string(metrics.OpHead), string(metrics.StatusSuccess), duration)
+	c.recordClassOp(metrics.OpHead, metrics.StatusSuccess, string(result.StorageClass), duration)
 
 	c.logger.Debug("head object",
 		zap.String("key", key),
+		zap.String("version_id", versionID),
 		zap.Int64("size", size),
 		zap.Duration("latency", duration),
 	)
 
-	return result.Metadata, size, nil
+	return &ObjectInfo{
+		Metadata:     result.Metadata,
+		Size:         size,
+		ETag:         aws.ToString(result.ETag),
+		StorageClass: string(result.StorageClass),
+	}, nil
+}
+
+// ListOptions selects what a listing covers
+type ListOptions struct {
+	Prefix     string
+	Delimiter  string // keys containing it past the prefix are rolled up into common prefixes
+	StartAfter string
+	PageSize   int32 // 0 for the server default of 1000
+	Full       bool  // follow continuation tokens to the end of the listing
+}
+
+// ListResult is the outcome of a listing
+type ListResult struct {
+	Keys           []string
+	CommonPrefixes []string
+	Pages          int
 }
 
-// ListObjects lists objects with a given prefix
-func (c *Client) ListObjects(ctx context.Context, prefix string, maxKeys int32) ([]string, error) {
+// List lists one page of objects or, with Full, the whole listing. Each
+// page is recorded as a list operation and a full walk also as list_full.
+func (c *Client) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(c.bucket),
+		Prefix: aws.String(opts.Prefix),
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = aws.String(opts.Delimiter)
+	}
+	if opts.StartAfter != "" {
+		input.StartAfter = aws.String(opts.StartAfter)
+	}
+	if opts.PageSize > 0 {
+		input.MaxKeys = aws.Int32(opts.PageSize)
+	}
+
+	res := &ListResult{}
+	walkStart := time.Now()
+
+	for {
+		start := time.Now()
+
+		result, err := c.s3Client.ListObjectsV2(ctx, input)
+
+		duration := time.Since(start)
+
+		if err != nil {
+			c.recordOp(metrics.OpList, metrics.StatusError, duration)
+			if opts.Full {
+				c.recordOp(metrics.OpListFull, metrics.StatusError, time.Since(walkStart))
+			}
+			return nil, fmt.Errorf("list failed after %d pages: %w", res.Pages, err)
+		}
+
+		c.recordOp(metrics.OpList, metrics.StatusSuccess, duration)
+		res.Pages++
+
+		for _, obj := range result.Contents {
+			if obj.Key != nil {
+				res.Keys = append(res.Keys, *obj.Key)
+			}
+		}
+		for _, p := range result.CommonPrefixes {
+			if p.Prefix != nil {
+				res.CommonPrefixes = append(res.CommonPrefixes, *p.Prefix)
+			}
+		}
+
+		if !opts.Full || !aws.ToBool(result.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = result.NextContinuationToken
+	}
+
+	if opts.Full {
+		c.recordOp(metrics.OpListFull, metrics.StatusSuccess, time.Since(walkStart))
+	}
+
+	c.logger.Debug("list",
+		zap.String("prefix", opts.Prefix),
+		zap.String("delimiter", opts.Delimiter),
+		zap.Int("pages", res.Pages),
+		zap.Int("keys", len(res.Keys)),
+		zap.Int("common_prefixes", len(res.CommonPrefixes)),
+		zap.Duration("latency", time.Since(walkStart)),
+	)
+
+	return res, nil
+}
+
+// ListAllObjects lists every object under a prefix, following continuation
+// tokens. Each page is recorded as a list operation.
+func (c *Client) ListAllObjects(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	var continuationToken *string
+
+	for {
+		start := time.Now()
+
+		result, err := c.s3Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(c.bucket),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: continuationToken,
+		})
+
+		duration := time.Since(start)
+
+		if err != nil {
+			c.recordOp(metrics.OpList, metrics.StatusError, duration)
+			return nil, fmt.Errorf("list failed: %w", err)
+		}
+
+		c.recordOp(metrics.OpList, metrics.StatusSuccess, duration)
+
+		for _, obj := range result.Contents {
+			if obj.Key != nil {
+				keys = append(keys, *obj.Key)
+			}
+		}
+
+		if !aws.ToBool(result.IsTruncated) {
+			break
+		}
+
+		continuationToken = result.NextContinuationToken
+	}
+
+	c.logger.Debug("list all objects",
+		zap.String("prefix", prefix),
+		zap.Int("count", len(keys)),
+	)
+
+	return keys, nil
+}
+
+// ListPage is one page of a listing
+type ListPage struct {
+	Keys      []string
+	NextToken string // empty on the last page
+}
+
+// ListObjectsPage lists a single page of keys under prefix. The listing
+// starts after startAfter, or continues from token when it is set.
+func (c *Client) ListObjectsPage(ctx context.Context, prefix, startAfter, token string) (*ListPage, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(c.bucket),
+		Prefix: aws.String(prefix),
+	}
+	if token != "" {
+		input.ContinuationToken = aws.String(token)
+	} else if startAfter != "" {
+		input.StartAfter = aws.String(startAfter)
+	}
+
 	start := time.Now()
 
-	result, err := c.s3Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
-		Bucket:  aws.String(c.bucket),
-		Prefix:  aws.String(prefix),
-		MaxKeys: aws.Int32(maxKeys),
-	})
+	result, err := c.s3Client.ListObjectsV2(ctx, input)
 
 	duration := time.Since(start)
 
 	if err != nil {
-		c.metrics.RecordOp(string(metrics.OpList), string(metrics.StatusError), duration)
+		c.recordOp(metrics.OpList, metrics.StatusError, duration)
 		return nil, fmt.Errorf("list failed: %w", err)
 	}
 
-	keys := make([]string, 0, len(result.Contents))
+	c.recordOp(metrics.OpList, metrics.StatusSuccess, duration)
+
+	page := &ListPage{Keys: make([]string, 0, len(result.Contents))}
 	for _, obj := range result.Contents {
 		if obj.Key != nil {
-			keys = append(keys, *obj.Key)
+			page.Keys = append(page.Keys, *obj.Key)
 		}
 	}
+	if aws.ToBool(result.IsTruncated) {
+		page.NextToken = aws.ToString(result.NextContinuationToken)
+	}
 
-	c.metrics.RecordOp(string(metrics.OpList), string(metrics.StatusSuccess), duration)
+	return page, nil
+}
 
-	c.logger.Debug("list objects",
-		zap.String("prefix", prefix),
-		zap.Int("count", len(keys)),
-		zap.Duration("latency", duration),
-	)
+// ObjectVersion is one version or delete marker in a versioned listing
+type ObjectVersion struct {
+	Key          string
+	VersionID    string
+	Size         int64
+	IsLatest     bool
+	DeleteMarker bool
+}
 
-	return keys, nil
+// VersionPage is one page of a version listing, in ascending key order
+type VersionPage struct {
+	Versions            []ObjectVersion
+	NextKeyMarker       string // both markers are empty on the last page
+	NextVersionIDMarker string
 }
 
-// MultipartUpload performs a multipart upload for large objects
-func (c *Client) MultipartUpload(ctx context.Context, key string, body io.ReadSeeker, size int64, partSize int64, maxConcurrency int, metadata map[string]string) error {
+// ListObjectVersionsPage lists a single page of object versions and delete
+// markers under prefix, starting after the given key and version markers
+func (c *Client) ListObjectVersionsPage(ctx context.Context, prefix, keyMarker, versionIDMarker string) (*VersionPage, error) {
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(c.bucket),
+		Prefix: aws.String(prefix),
+	}
+	if keyMarker != "" {
+		input.KeyMarker = aws.String(keyMarker)
+	}
+	if versionIDMarker != "" {
+		input.VersionIdMarker = aws.String(versionIDMarker)
+	}
+
+	start := time.Now()
+
+	result, err := c.s3Client.ListObjectVersions(ctx, input)
+
+	duration := time.Since(start)
+
+	if err != nil {
+		c.recordOp(metrics.OpListVersions, metrics.StatusError, duration)
+		return nil, fmt.Errorf("list versions failed: %w", err)
+	}
+
+	c.recordOp(metrics.OpListVersions, metrics.StatusSuccess, duration)
+
+	page := &VersionPage{}
+	for _, v := range result.Versions {
+		page.Versions = append(page.Versions, ObjectVersion{
+			Key:       aws.ToString(v.Key),
+			VersionID: aws.ToString(v.VersionId),
+			Size:      aws.ToInt64(v.Size),
+			IsLatest:  aws.ToBool(v.IsLatest),
+		})
+	}
+	for _, m := range result.DeleteMarkers {
+		page.Versions = append(page.Versions, ObjectVersion{
+			Key:          aws.ToString(m.Key),
+			VersionID:    aws.ToString(m.VersionId),
+			IsLatest:     aws.ToBool(m.IsLatest),
+			DeleteMarker: true,
+		})
+	}
+
+	// The response lists versions and markers separately; merge them back
+	// into key order so all entries for a key are adjacent
+	sort.SliceStable(page.Versions, func(i, j int) bool {
+		return page.Versions[i].Key < page.Versions[j].Key
+	})
+
+	if aws.ToBool(result.IsTruncated) {
+		page.NextKeyMarker = aws.ToString(result.NextKeyMarker)
+		page.NextVersionIDMarker = aws.ToString(result.NextVersionIdMarker)
+	}
+
+	return page, nil
+}
+
+// MultipartUpload performs a multipart upload for large objects and returns
+// the version ID of the completed object, if any
+func (c *Client) MultipartUpload(ctx context.Context, key string, body io.ReadSeeker, size int64, partSize int64, maxConcurrency int, metadata map[string]string, opts ObjectOptions) (string, error) {
+	op := opts.op(metrics.OpMultipartPut)
+	sse, kmsKeyID := c.serverSideEncryption()
+	ck := c.customerKey(key)
+
+	// Checksum every part and the whole object up front, in one pass
+	algorithm := c.checksum.Algorithm
+	var partSums []string
+	var fullSum string
+	var createOptFns []func(*s3.Options)
+	if algorithm != "" {
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return "", fmt.Errorf("failed to rewind body: %w", err)
+		}
+		var err error
+		partSums, fullSum, err = data.PartChecksums(body, algorithm, size, partSize)
+		if err != nil {
+			return "", fmt.Errorf("multipart upload failed: %w", err)
+		}
+		createOptFns = append(createOptFns, withHeaders(
+			"x-amz-checksum-algorithm", algorithm,
+			"x-amz-checksum-type", c.checksum.multipartType(),
+		))
+	}
+
 	start := time.Now()
 
 	// Initiate multipart upload
 	createResp, err := c.s3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
-		Bucket:   aws.String(c.bucket),
-		Key:      aws.String(key),
-		Metadata: metadata,
-	})
+		Bucket:               aws.String(c.bucket),
+		Key:                  aws.String(key),
+		Metadata:             metadata,
+		Tagging:              encodeTags(opts.Tags),
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          kmsKeyID,
+		SSECustomerAlgorithm: ck.algorithm,
+		SSECustomerKey:       ck.key,
+		SSECustomerKeyMD5:    ck.keyMD5,
+		StorageClass:         types.StorageClass(opts.StorageClass),
+	}, createOptFns...)
 
 	if err != nil {
-		c.metrics.RecordOp(string(metrics.OpMultipartPut), string(metrics.StatusError), time.Since(start))
-		return fmt.Errorf("failed to initiate multipart upload: %w", err)
+		c.recordClassOp(op, metrics.StatusError, opts.StorageClass, time.Since(start))
+		return "", fmt.Errorf("failed to initiate multipart upload: %w", err)
 	}
 
 	uploadID := createResp.UploadId
 	if uploadID == nil {
-		c.metrics.RecordOp(string(metrics.OpMultipartPut), string(metrics.StatusError), time.Since(start))
-		return fmt.Errorf("upload ID is nil")
+		c.recordClassOp(op, metrics.StatusError, opts.StorageClass, time.Since(start))
+		return "", fmt.Errorf("upload ID is nil")
 	}
+	c.trackUpload(*uploadID, key)
 
 	// Calculate number of parts
 	numParts := int((size + partSize - 1) / partSize)
@@ -406,15 +1026,23 @@ func (c *Client) MultipartUpload(ctx context.Context, key string, body io.ReadSe
 			// Create a limited reader for this part
 			partReader := io.LimitReader(body, length)
 
+			var partOptFns []func(*s3.Options)
+			if algorithm != "" {
+				partOptFns = append(partOptFns, checksumHeaders(algorithm, partSums[partNumber-1]))
+			}
+
 			// Upload part
 			uploadPartResp, err := c.s3Client.UploadPart(ctx, &s3.UploadPartInput{
-				Bucket:        aws.String(c.bucket),
-				Key:           aws.String(key),
-				UploadId:      uploadID,
-				PartNumber:    aws.Int32(int32(partNumber)),
-				Body:          partReader,
-				ContentLength: aws.Int64(length),
-			})
+				Bucket:               aws.String(c.bucket),
+				Key:                  aws.String(key),
+				UploadId:             uploadID,
+				PartNumber:           aws.Int32(int32(partNumber)),
+				Body:                 partReader,
+				ContentLength:        aws.Int64(length),
+				SSECustomerAlgorithm: ck.algorithm,
+				SSECustomerKey:       ck.key,
+				SSECustomerKeyMD5:    ck.keyMD5,
+			}, partOptFns...)
 
 			if err != nil {
 				errChan <- fmt.Errorf("failed to upload part %d: %w", partNumber, err)
@@ -425,6 +1053,9 @@ func (c *Client) MultipartUpload(ctx context.Context, key string, body io.ReadSe
 				ETag:       uploadPartResp.ETag,
 				PartNumber: aws.Int32(int32(partNumber)),
 			}
+			if algorithm != "" {
+				setPartChecksum(&completedParts[partNumber-1], algorithm, partSums[partNumber-1])
+			}
 		}(partNum)
 	}
 
@@ -440,43 +1071,62 @@ func (c *Client) MultipartUpload(ctx context.Context, key string, body io.ReadSe
 
 	if len(uploadErrors) > 0 {
 		// Abort multipart upload on error
-		_, abortErr := c.s3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
-			Bucket:   aws.String(c.bucket),
-			Key:      aws.String(key),
-			UploadId: uploadID,
-		})
-
-		if abortErr != nil {
-			c.logger.Warn("failed to abort multipart upload after error",
-				zap.String("key", key),
-				zap.Error(abortErr),
-			)
-		}
+		c.abortAfterError(ctx, key, *uploadID)
 
 		duration := time.Since(start)
-		c.metrics.RecordOp(string(metrics.OpMultipartPut), string(metrics.StatusError), duration)
-		return fmt.Errorf("multipart upload failed with %d errors: %v", len(uploadErrors), uploadErrors[0])
+		c.recordClassOp(op, errorStatus(errors.Join(uploadErrors...)), opts.StorageClass, duration)
+		return "", fmt.Errorf("multipart upload failed with %d errors: %v", len(uploadErrors), uploadErrors[0])
+	}
+
+	// Full-object checksums are sent with the completion request; composite
+	// ones are derived by the server from the part checksums
+	var completeOptFns []func(*s3.Options)
+	expectedSum := fullSum
+	if algorithm != "" {
+		if c.checksum.multipartType() == ChecksumFullObject {
+			completeOptFns = append(completeOptFns, withHeaders(
+				"x-amz-checksum-type", ChecksumFullObject,
+				checksumHeader(algorithm), fullSum,
+			))
+		} else if expectedSum, err = data.CompositeChecksum(algorithm, partSums); err != nil {
+			c.abortAfterError(ctx, key, *uploadID)
+			c.recordClassOp(op, metrics.StatusError, opts.StorageClass, time.Since(start))
+			return "", fmt.Errorf("multipart upload failed: %w", err)
+		}
 	}
 
 	// Complete multipart upload
-	_, err = c.s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+	completeResp, err := c.s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
 		Bucket:   aws.String(c.bucket),
 		Key:      aws.String(key),
 		UploadId: uploadID,
 		MultipartUpload: &types.CompletedMultipartUpload{
 			Parts: completedParts,
 		},
-	})
+		SSECustomerAlgorithm: ck.algorithm,
+		SSECustomerKey:       ck.key,
+		SSECustomerKeyMD5:    ck.keyMD5,
+	}, completeOptFns...)
 
 	duration := time.Since(start)
 
 	if err != nil {
-		c.metrics.RecordOp(string(metrics.OpMultipartPut), string(metrics.StatusError), duration)
-		return fmt.Errorf("failed to complete multipart upload: %w", err)
+		c.recordClassOp(op, errorStatus(err), opts.StorageClass, duration)
+		c.abortAfterError(ctx, key, *uploadID)
+		return "", fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	c.untrackUpload(*uploadID)
+
+	if algorithm != "" {
+		if err := c.checkUploadChecksum(completeResp.ResultMetadata, expectedSum); err != nil {
+			c.recordClassOp(op, metrics.StatusChecksumMismatch, opts.StorageClass, duration)
+			return "", fmt.Errorf("multipart upload %s: %w", key, err)
+		}
 	}
 
-	c.metrics.RecordOp(string(metrics.OpMultipartPut), string(metrics.StatusSuccess), duration)
+	c.recordClassOp(op, metrics.StatusSuccess, opts.StorageClass, duration)
 	c.metrics.RecordBytesWritten(size)
+	c.metrics.RecordTransfer(string(op), size, duration)
 
 	c.logger.Debug("multipart upload completed",
 		zap.String("key", key),
@@ -485,63 +1135,5 @@ func (c *Client) MultipartUpload(ctx context.Context, key string, body io.ReadSe
 		zap.Duration("latency", duration),
 	)
 
-	return nil
-}
-
-// DeleteObjectsByMetadata deletes objects matching specific metadata
-func (c *Client) DeleteObjectsByMetadata(ctx context.Context, prefix string, metadataKey string, metadataValue string) (int, error) {
-	var deleted int
-	var continuationToken *string
-
-	for {
-		result, err := c.s3Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
-			Bucket:            aws.String(c.bucket),
-			Prefix:            aws.String(prefix),
-			ContinuationToken: continuationToken,
-		})
-
-		if err != nil {
-			return deleted, fmt.Errorf("list failed: %w", err)
-		}
-
-		for _, obj := range result.Contents {
-			if obj.Key == nil {
-				continue
-			}
-
-			// Check metadata
-			head, err := c.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
-				Bucket: aws.String(c.bucket),
-				Key:    obj.Key,
-			})
-
-			if err != nil {
-				c.logger.Warn("failed to head object during cleanup",
-					zap.String("key", *obj.Key),
-					zap.Error(err),
-				)
-				continue
-			}
-
-			// Check if metadata matches
-			if val, ok := head.Metadata[metadataKey]; ok && val == metadataValue {
-				if err := c.DeleteObject(ctx, *obj.Key); err != nil {
-					c.logger.Warn("failed to delete object during cleanup",
-						zap.String("key", *obj.Key),
-						zap.Error(err),
-					)
-					continue
-				}
-				deleted++
-			}
-		}
-
-		if !aws.ToBool(result.IsTruncated) {
-			break
-		}
-
-		continuationToken = result.NextContinuationToken
-	}
-
-	return deleted, nil
+	return aws.ToString(completeResp.VersionId), nil
 }
@@ -0,0 +1,75 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestDeleteResult(t *testing.T) {
+	failed := []types.Error{
+		{Key: aws.String("b"), Code: aws.String("AccessDenied"), Message: aws.String("Access Denied")},
+		{Key: aws.String("c"), VersionId: aws.String("v1"), Code: aws.String("InternalError")},
+	}
+
+	tests := []struct {
+		name        string
+		requested   int
+		result      *s3.DeleteObjectsOutput
+		quiet       bool
+		wantDeleted int
+		wantFailed  int
+	}{
+		{
+			name:        "all deleted",
+			requested:   3,
+			result:      &s3.DeleteObjectsOutput{Deleted: []types.DeletedObject{{Key: aws.String("a")}, {Key: aws.String("b")}, {Key: aws.String("c")}}},
+			wantDeleted: 3,
+		},
+		{
+			name:        "partial failure",
+			requested:   3,
+			result:      &s3.DeleteObjectsOutput{Deleted: []types.DeletedObject{{Key: aws.String("a")}}, Errors: failed},
+			wantDeleted: 1,
+			wantFailed:  2,
+		},
+		{
+			// Quiet responses list failures only
+			name:        "quiet partial failure",
+			requested:   5,
+			result:      &s3.DeleteObjectsOutput{Errors: failed},
+			quiet:       true,
+			wantDeleted: 3,
+			wantFailed:  2,
+		},
+		{
+			name:        "quiet all deleted",
+			requested:   5,
+			result:      &s3.DeleteObjectsOutput{},
+			quiet:       true,
+			wantDeleted: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted, failures := deleteResult(tt.requested, tt.result, tt.quiet)
+			if deleted != tt.wantDeleted || len(failures) != tt.wantFailed {
+				t.Errorf("deleteResult() = %d deleted, %d failed, want %d, %d", deleted, len(failures), tt.wantDeleted, tt.wantFailed)
+			}
+		})
+	}
+
+	_, failures := deleteResult(3, &s3.DeleteObjectsOutput{Errors: failed}, false)
+	want := []DeleteFailure{
+		{Key: "b", Code: "AccessDenied", Message: "Access Denied"},
+		{Key: "c", VersionID: "v1", Code: "InternalError"},
+	}
+	for i := range want {
+		if failures[i] != want[i] {
+			t.Errorf("failure %d = %+v, want %+v", i, failures[i], want[i])
+		}
+	}
+}
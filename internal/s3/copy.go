@@ -0,0 +1,194 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/paragkamble/s3bench/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// MinPartSize is the smallest part S3 accepts other than the last one
+const MinPartSize = 5 * 1024 * 1024
+
+// CopySource is a byte range of an existing object
+type CopySource struct {
+	Key    string
+	ETag   string // when set, the copy fails if the source has changed
+	Offset int64
+	Length int64
+}
+
+// copyPart is one UploadPartCopy request
+type copyPart struct {
+	source CopySource
+	offset int64
+	length int64
+}
+
+// splitCopyParts splits source ranges into parts of at most partSize.
+// Parts never span sources, so a range that is not a multiple of partSize
+// leaves a short part, which S3 only accepts at the end.
+func splitCopyParts(sources []CopySource, partSize int64) ([]copyPart, error) {
+	var parts []copyPart
+	for _, src := range sources {
+		if src.Length <= 0 {
+			return nil, fmt.Errorf("empty range of source %s", src.Key)
+		}
+		for off := int64(0); off < src.Length; off += partSize {
+			length := partSize
+			if off+length > src.Length {
+				length = src.Length - off
+			}
+			parts = append(parts, copyPart{source: src, offset: src.Offset + off, length: length})
+		}
+	}
+
+	for i, p := range parts[:len(parts)-1] {
+		if p.length < MinPartSize {
+			return nil, fmt.Errorf("part %d from %s is %d bytes, below the %d byte minimum", i+1, p.source.Key, p.length, MinPartSize)
+		}
+	}
+	return parts, nil
+}
+
+// MultipartCopy creates dstKey from byte ranges of one or more objects in
+// the bucket using UploadPartCopy, so no data passes through the client.
+// Unlike CopyObject, metadata is not taken from the source and must be
+// given.
+func (c *Client) MultipartCopy(ctx context.Context, dstKey string, sources []CopySource, partSize int64, maxConcurrency int, metadata map[string]string, opts ObjectOptions) (string, error) {
+	if len(sources) == 0 {
+		return "", fmt.Errorf("multipart copy needs at least one source")
+	}
+	parts, err := splitCopyParts(sources, partSize)
+	if err != nil {
+		return "", fmt.Errorf("multipart copy failed: %w", err)
+	}
+
+	sse, kmsKeyID := c.serverSideEncryption()
+	dstCK := c.customerKey(dstKey)
+
+	start := time.Now()
+
+	createResp, err := c.s3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(c.bucket),
+		Key:                  aws.String(dstKey),
+		Metadata:             metadata,
+		Tagging:              encodeTags(opts.Tags),
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          kmsKeyID,
+		SSECustomerAlgorithm: dstCK.algorithm,
+		SSECustomerKey:       dstCK.key,
+		SSECustomerKeyMD5:    dstCK.keyMD5,
+		StorageClass:         types.StorageClass(opts.StorageClass),
+	})
+	if err != nil {
+		c.recordClassOp(metrics.OpMultipartCopy, metrics.StatusError, opts.StorageClass, time.Since(start))
+		return "", fmt.Errorf("failed to initiate multipart copy: %w", err)
+	}
+
+	uploadID := aws.ToString(createResp.UploadId)
+	if uploadID == "" {
+		c.recordClassOp(metrics.OpMultipartCopy, metrics.StatusError, opts.StorageClass, time.Since(start))
+		return "", fmt.Errorf("upload ID is nil")
+	}
+	c.trackUpload(uploadID, dstKey)
+
+	completedParts := make([]types.CompletedPart, len(parts))
+	sem := make(chan struct{}, maxConcurrency)
+	errChan := make(chan error, len(parts))
+	var wg sync.WaitGroup
+
+	for i, part := range parts {
+		wg.Add(1)
+		go func(partNumber int, part copyPart) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			srcCK := c.customerKey(part.source.Key)
+			input := &s3.UploadPartCopyInput{
+				Bucket:                         aws.String(c.bucket),
+				Key:                            aws.String(dstKey),
+				UploadId:                       aws.String(uploadID),
+				PartNumber:                     aws.Int32(int32(partNumber)),
+				CopySource:                     aws.String(fmt.Sprintf("%s/%s", c.bucket, part.source.Key)),
+				CopySourceRange:                aws.String(fmt.Sprintf("bytes=%d-%d", part.offset, part.offset+part.length-1)),
+				SSECustomerAlgorithm:           dstCK.algorithm,
+				SSECustomerKey:                 dstCK.key,
+				SSECustomerKeyMD5:              dstCK.keyMD5,
+				CopySourceSSECustomerAlgorithm: srcCK.algorithm,
+				CopySourceSSECustomerKey:       srcCK.key,
+				CopySourceSSECustomerKeyMD5:    srcCK.keyMD5,
+			}
+			if part.source.ETag != "" {
+				input.CopySourceIfMatch = aws.String(part.source.ETag)
+			}
+
+			resp, err := c.s3Client.UploadPartCopy(ctx, input)
+			if err != nil {
+				errChan <- fmt.Errorf("failed to copy part %d from %s: %w", partNumber, part.source.Key, err)
+				return
+			}
+
+			completedParts[partNumber-1] = types.CompletedPart{
+				PartNumber: aws.Int32(int32(partNumber)),
+			}
+			if resp.CopyPartResult != nil {
+				completedParts[partNumber-1].ETag = resp.CopyPartResult.ETag
+			}
+		}(i+1, part)
+	}
+
+	wg.Wait()
+	close(errChan)
+
+	var copyErrors []error
+	for err := range errChan {
+		copyErrors = append(copyErrors, err)
+	}
+
+	if len(copyErrors) > 0 {
+		c.abortAfterError(ctx, dstKey, uploadID)
+		c.recordClassOp(metrics.OpMultipartCopy, metrics.StatusError, opts.StorageClass, time.Since(start))
+		return "", fmt.Errorf("multipart copy failed with %d errors: %v", len(copyErrors), copyErrors[0])
+	}
+
+	completeResp, err := c.s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(c.bucket),
+		Key:      aws.String(dstKey),
+		UploadId: aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: completedParts,
+		},
+		SSECustomerAlgorithm: dstCK.algorithm,
+		SSECustomerKey:       dstCK.key,
+		SSECustomerKeyMD5:    dstCK.keyMD5,
+	})
+
+	duration := time.Since(start)
+
+	if err != nil {
+		c.recordClassOp(metrics.OpMultipartCopy, metrics.StatusError, opts.StorageClass, duration)
+		c.abortAfterError(ctx, dstKey, uploadID)
+		return "", fmt.Errorf("failed to complete multipart copy: %w", err)
+	}
+	c.untrackUpload(uploadID)
+
+	c.recordClassOp(metrics.OpMultipartCopy, metrics.StatusSuccess, opts.StorageClass, duration)
+
+	c.logger.Debug("multipart copy completed",
+		zap.String("dst_key", dstKey),
+		zap.Int("sources", len(sources)),
+		zap.Int("parts", len(parts)),
+		zap.Duration("latency", duration),
+	)
+
+	return aws.ToString(completeResp.VersionId), nil
+}
@@ -0,0 +1,55 @@
+package s3
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// IsNotFound reports whether err means the object, version or multipart
+// upload does not exist. GET returns NoSuchKey while HEAD, having no body,
+// returns a bare NotFound.
+func IsNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var nsk *types.NoSuchKey
+	var nf *types.NotFound
+	var nsu *types.NoSuchUpload
+	if errors.As(err, &nsk) || errors.As(err, &nf) || errors.As(err, &nsu) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NoSuchKey", "NotFound", "NoSuchVersion", "NoSuchUpload":
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsChecksumMismatch reports whether err means data did not match its
+// checksum, either as detected here or as rejected by the server
+func IsChecksumMismatch(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrChecksumMismatch) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "BadDigest", "XAmzContentChecksumMismatch":
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,102 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestSSEConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sse     SSEConfig
+		wantErr bool
+	}{
+		{"none", SSEConfig{}, false},
+		{"sse-s3", SSEConfig{Mode: SSES3}, false},
+		{"kms", SSEConfig{Mode: SSEKMS, KMSKeyID: "key-1"}, false},
+		{"kms without key id", SSEConfig{Mode: SSEKMS}, true},
+		{"sse-c per key", SSEConfig{Mode: SSECustom, CustomerKeys: SSECKeysPerKey, Secret: "s"}, false},
+		{"sse-c without secret", SSEConfig{Mode: SSECustom, CustomerKeys: SSECKeysPerRun}, true},
+		{"sse-c without scope", SSEConfig{Mode: SSECustom, Secret: "s"}, true},
+		{"unknown", SSEConfig{Mode: "aws:kms:dsse"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sse.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCustomerKey(t *testing.T) {
+	client := func(keys, secret string) *Client {
+		return &Client{sse: SSEConfig{Mode: SSECustom, CustomerKeys: keys, Secret: secret}}
+	}
+
+	perKey := client(SSECKeysPerKey, "secret")
+	a, b := perKey.customerKey("a"), perKey.customerKey("b")
+	if aws.ToString(a.key) == aws.ToString(b.key) {
+		t.Error("per-key customer keys are the same for different object keys")
+	}
+
+	// Another client with the same secret, as in a later run, reads the data
+	if again := client(SSECKeysPerKey, "secret").customerKey("a"); aws.ToString(again.key) != aws.ToString(a.key) {
+		t.Error("per-key customer key differs between clients with the same secret")
+	}
+	if other := client(SSECKeysPerKey, "other").customerKey("a"); aws.ToString(other.key) == aws.ToString(a.key) {
+		t.Error("per-key customer key is the same for different secrets")
+	}
+
+	perRun := client(SSECKeysPerRun, "secret")
+	if x, y := perRun.customerKey("a"), perRun.customerKey("b"); aws.ToString(x.key) != aws.ToString(y.key) {
+		t.Error("per-run customer keys differ between object keys")
+	}
+	if aws.ToString(perRun.customerKey("a").key) == aws.ToString(a.key) {
+		t.Error("per-run and per-key customer keys are the same")
+	}
+
+	for _, ck := range []customerKey{a, b, perRun.customerKey("a")} {
+		if aws.ToString(ck.algorithm) != "AES256" {
+			t.Errorf("algorithm = %q, want AES256", aws.ToString(ck.algorithm))
+		}
+		raw, err := base64.StdEncoding.DecodeString(aws.ToString(ck.key))
+		if err != nil || len(raw) != 32 {
+			t.Fatalf("key is not a base64 256-bit key: %d bytes, %v", len(raw), err)
+		}
+		sum := md5.Sum(raw)
+		if want := base64.StdEncoding.EncodeToString(sum[:]); aws.ToString(ck.keyMD5) != want {
+			t.Errorf("key MD5 = %q, want %q", aws.ToString(ck.keyMD5), want)
+		}
+	}
+
+	if ck := (&Client{sse: SSEConfig{Mode: SSES3}}).customerKey("a"); ck.algorithm != nil || ck.key != nil || ck.keyMD5 != nil {
+		t.Errorf("customerKey() without sse-c = %+v, want no headers", ck)
+	}
+}
+
+func TestServerSideEncryption(t *testing.T) {
+	tests := []struct {
+		sse     SSEConfig
+		want    types.ServerSideEncryption
+		wantKey string
+	}{
+		{SSEConfig{}, "", ""},
+		{SSEConfig{Mode: SSES3}, types.ServerSideEncryptionAes256, ""},
+		{SSEConfig{Mode: SSEKMS, KMSKeyID: "key-1"}, types.ServerSideEncryptionAwsKms, "key-1"},
+		// Customer keys travel in their own headers
+		{SSEConfig{Mode: SSECustom, CustomerKeys: SSECKeysPerRun, Secret: "s"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		got, key := (&Client{sse: tt.sse}).serverSideEncryption()
+		if got != tt.want || aws.ToString(key) != tt.wantKey {
+			t.Errorf("serverSideEncryption() with %q = %q, %q, want %q, %q", tt.sse.Mode, got, aws.ToString(key), tt.want, tt.wantKey)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/paragkamble/s3bench/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// encodeTags encodes tags for the x-amz-tagging header, or returns nil if
+// there are none
+func encodeTags(tags map[string]string) *string {
+	if len(tags) == 0 {
+		return nil
+	}
+	values := url.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return aws.String(values.Encode())
+}
+
+// PutObjectTagging replaces the tag set of an object
+func (c *Client) PutObjectTagging(ctx context.Context, key string, tags map[string]string) error {
+	tagSet := make([]types.Tag, 0, len(tags))
+	for k, v := range tags {
+		tagSet = append(tagSet, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	start := time.Now()
+
+	_, err := c.s3Client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(c.bucket),
+		Key:     aws.String(key),
+		Tagging: &types.Tagging{TagSet: tagSet},
+	})
+
+	duration := time.Since(start)
+
+	if err != nil {
+		c.recordOp(metrics.OpPutTagging, metrics.StatusError, duration)
+		return fmt.Errorf("put tagging failed: %w", err)
+	}
+
+	c.recordOp(metrics.OpPutTagging, metrics.StatusSuccess, duration)
+
+	c.logger.Debug("put object tagging",
+		zap.String("key", key),
+		zap.Int("tags", len(tags)),
+		zap.Duration("latency", duration),
+	)
+
+	return nil
+}
+
+// GetObjectTagging returns the tag set of an object
+func (c *Client) GetObjectTagging(ctx context.Context, key string) (map[string]string, error) {
+	start := time.Now()
+
+	result, err := c.s3Client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+
+	duration := time.Since(start)
+
+	if err != nil {
+		c.recordOp(metrics.OpGetTagging, metrics.StatusError, duration)
+		return nil, fmt.Errorf("get tagging failed: %w", err)
+	}
+
+	c.recordOp(metrics.OpGetTagging, metrics.StatusSuccess, duration)
+
+	tags := make(map[string]string, len(result.TagSet))
+	for _, t := range result.TagSet {
+		tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+
+	c.logger.Debug("get object tagging",
+		zap.String("key", key),
+		zap.Int("tags", len(tags)),
+		zap.Duration("latency", duration),
+	)
+
+	return tags, nil
+}
+
+// DeleteObjectTagging removes every tag from an object
+func (c *Client) DeleteObjectTagging(ctx context.Context, key string) error {
+	start := time.Now()
+
+	_, err := c.s3Client.DeleteObjectTagging(ctx, &s3.DeleteObjectTaggingInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+
+	duration := time.Since(start)
+
+	if err != nil {
+		c.recordOp(metrics.OpDeleteTagging, metrics.StatusError, duration)
+		return fmt.Errorf("delete tagging failed: %w", err)
+	}
+
+	c.recordOp(metrics.OpDeleteTagging, metrics.StatusSuccess, duration)
+
+	c.logger.Debug("delete object tagging",
+		zap.String("key", key),
+		zap.Duration("latency", duration),
+	)
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/paragkamble/s3bench/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// abortTimeout bounds an abort issued after the upload's own context ended
+const abortTimeout = 30 * time.Second
+
+// MultipartUploadInfo describes an incomplete multipart upload
+type MultipartUploadInfo struct {
+	Key       string
+	UploadID  string
+	Initiated time.Time
+}
+
+func (c *Client) trackUpload(uploadID, key string) {
+	c.uploadsMu.Lock()
+	defer c.uploadsMu.Unlock()
+
+	c.uploads[uploadID] = key
+	c.metrics.SetMultipartUploadsOpen(len(c.uploads))
+}
+
+func (c *Client) untrackUpload(uploadID string) {
+	c.uploadsMu.Lock()
+	defer c.uploadsMu.Unlock()
+
+	delete(c.uploads, uploadID)
+	c.metrics.SetMultipartUploadsOpen(len(c.uploads))
+}
+
+// abortAfterError aborts a failed upload. The upload's context may already
+// be done, so the abort gets its own deadline. Uploads that cannot be
+// aborted stay tracked for AbortOpenUploads.
+func (c *Client) abortAfterError(ctx context.Context, key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abortTimeout)
+	defer cancel()
+
+	if err := c.AbortMultipartUpload(ctx, key, uploadID); err != nil {
+		c.logger.Warn("failed to abort multipart upload after error",
+			zap.String("key", key),
+			zap.String("upload_id", uploadID),
+			zap.Error(err),
+		)
+	}
+}
+
+// AbortMultipartUpload aborts an incomplete multipart upload and frees its
+// parts. An upload that no longer exists counts as aborted.
+func (c *Client) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	start := time.Now()
+
+	_, err := c.s3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(c.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+
+	duration := time.Since(start)
+
+	if err != nil && !IsNotFound(err) {
+		c.recordOp(metrics.OpAbortMultipart, metrics.StatusError, duration)
+		return fmt.Errorf("abort multipart upload failed: %w", err)
+	}
+
+	c.recordOp(metrics.OpAbortMultipart, metrics.StatusSuccess, duration)
+	c.untrackUpload(uploadID)
+
+	c.logger.Debug("abort multipart upload",
+		zap.String("key", key),
+		zap.String("upload_id", uploadID),
+		zap.Duration("latency", duration),
+	)
+
+	return nil
+}
+
+// AbortOpenUploads aborts every multipart upload this client started that
+// has not been completed or aborted, returning how many were aborted
+func (c *Client) AbortOpenUploads(ctx context.Context) (int, error) {
+	c.uploadsMu.Lock()
+	open := make(map[string]string, len(c.uploads))
+	for id, key := range c.uploads {
+		open[id] = key
+	}
+	c.uploadsMu.Unlock()
+
+	aborted := 0
+	var errs []error
+	for id, key := range open {
+		if err := c.AbortMultipartUpload(ctx, key, id); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		aborted++
+	}
+
+	return aborted, errors.Join(errs...)
+}
+
+// ListMultipartUploads lists every incomplete multipart upload under prefix
+func (c *Client) ListMultipartUploads(ctx context.Context, prefix string) ([]MultipartUploadInfo, error) {
+	var uploads []MultipartUploadInfo
+	var keyMarker, uploadIDMarker *string
+
+	for {
+		start := time.Now()
+
+		result, err := c.s3Client.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{
+			Bucket:         aws.String(c.bucket),
+			Prefix:         aws.String(prefix),
+			KeyMarker:      keyMarker,
+			UploadIdMarker: uploadIDMarker,
+		})
+
+		duration := time.Since(start)
+
+		if err != nil {
+			c.recordOp(metrics.OpListUploads, metrics.StatusError, duration)
+			return nil, fmt.Errorf("list multipart uploads failed: %w", err)
+		}
+
+		c.recordOp(metrics.OpListUploads, metrics.StatusSuccess, duration)
+
+		for _, u := range result.Uploads {
+			uploads = append(uploads, MultipartUploadInfo{
+				Key:       aws.ToString(u.Key),
+				UploadID:  aws.ToString(u.UploadId),
+				Initiated: aws.ToTime(u.Initiated),
+			})
+		}
+
+		if !aws.ToBool(result.IsTruncated) {
+			break
+		}
+
+		keyMarker = result.NextKeyMarker
+		uploadIDMarker = result.NextUploadIdMarker
+	}
+
+	return uploads, nil
+}
@@ -0,0 +1,181 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/paragkamble/s3bench/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// fakeUploads serves the multipart upload requests of a single bucket. Abort
+// answers with abortStatus and its error code, if set.
+type fakeUploads struct {
+	mu          sync.Mutex
+	next        int
+	aborted     []string
+	abortStatus int
+	abortCode   string
+}
+
+func (f *fakeUploads) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.next++
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>k</Key><UploadId>upload-%d</UploadId></InitiateMultipartUploadResult>", f.next)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		w.Header().Set("ETag", fmt.Sprintf("\"part-%s\"", query.Get("partNumber")))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		fmt.Fprint(w, "<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>k</Key><ETag>\"done\"</ETag></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		if f.abortStatus != 0 {
+			w.WriteHeader(f.abortStatus)
+			fmt.Fprintf(w, "<Error><Code>%s</Code><Message>abort failed</Message></Error>", f.abortCode)
+			return
+		}
+		f.aborted = append(f.aborted, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+// newTestClient returns a client of bucket on server. Over HTTPS the SDK
+// sends part bodies unsigned, as against a real endpoint.
+func newTestClient(t *testing.T, server *httptest.Server) *Client {
+	t.Helper()
+
+	c, err := NewClient(context.Background(), ClientConfig{
+		Endpoint:      server.URL,
+		Region:        "us-east-1",
+		Bucket:        "bucket",
+		AccessKey:     "access",
+		SecretKey:     "secret",
+		PathStyle:     true,
+		SkipTLSVerify: true,
+		Logger:        zap.NewNop(),
+		Metrics:       metrics.NewMetrics(),
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return c
+}
+
+func (c *Client) openUploads() int {
+	c.uploadsMu.Lock()
+	defer c.uploadsMu.Unlock()
+	return len(c.uploads)
+}
+
+func TestMultipartUploadUntracksOnComplete(t *testing.T) {
+	server := httptest.NewTLSServer(&fakeUploads{})
+	defer server.Close()
+	c := newTestClient(t, server)
+
+	body := bytes.NewReader(make([]byte, 12))
+	if _, err := c.MultipartUpload(context.Background(), "k", body, 12, 5, 1, nil, ObjectOptions{}); err != nil {
+		t.Fatalf("MultipartUpload() error = %v", err)
+	}
+	if open := c.openUploads(); open != 0 {
+		t.Errorf("%d uploads open after completing, want 0", open)
+	}
+}
+
+func TestAbortMultipartUpload(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		code     string
+		wantErr  bool
+		wantOpen int
+	}{
+		{name: "aborted"},
+		// The upload is gone, which is what the abort wanted
+		{name: "no such upload", status: http.StatusNotFound, code: "NoSuchUpload"},
+		// Uploads that could not be aborted are left for AbortOpenUploads
+		{name: "failed", status: http.StatusForbidden, code: "AccessDenied", wantErr: true, wantOpen: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(&fakeUploads{abortStatus: tt.status, abortCode: tt.code})
+			defer server.Close()
+			c := newTestClient(t, server)
+
+			c.trackUpload("upload-1", "k")
+			err := c.AbortMultipartUpload(context.Background(), "k", "upload-1")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AbortMultipartUpload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if open := c.openUploads(); open != tt.wantOpen {
+				t.Errorf("%d uploads open, want %d", open, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestAbortOpenUploads(t *testing.T) {
+	fake := &fakeUploads{}
+	server := httptest.NewTLSServer(fake)
+	defer server.Close()
+	c := newTestClient(t, server)
+
+	c.trackUpload("upload-1", "a")
+	c.trackUpload("upload-2", "b")
+	c.trackUpload("upload-3", "c")
+	c.untrackUpload("upload-2")
+
+	aborted, err := c.AbortOpenUploads(context.Background())
+	if err != nil {
+		t.Fatalf("AbortOpenUploads() error = %v", err)
+	}
+	if aborted != 2 || len(fake.aborted) != 2 {
+		t.Errorf("aborted %d uploads, server saw %v, want upload-1 and upload-3", aborted, fake.aborted)
+	}
+	for _, id := range fake.aborted {
+		if id == "upload-2" {
+			t.Errorf("aborted untracked upload %s", id)
+		}
+	}
+	if open := c.openUploads(); open != 0 {
+		t.Errorf("%d uploads open after aborting, want 0", open)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"no such key", &types.NoSuchKey{}, true},
+		{"not found", &types.NotFound{}, true},
+		{"no such upload", &types.NoSuchUpload{}, true},
+		{"wrapped no such upload", fmt.Errorf("abort: %w", &types.NoSuchUpload{}), true},
+		{"no such upload code", &smithy.GenericAPIError{Code: "NoSuchUpload"}, true},
+		{"no such version code", &smithy.GenericAPIError{Code: "NoSuchVersion"}, true},
+		{"access denied", &smithy.GenericAPIError{Code: "AccessDenied"}, false},
+		{"other", fmt.Errorf("connection reset"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNotFound(tt.err); got != tt.want {
+				t.Errorf("IsNotFound(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
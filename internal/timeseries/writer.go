@@ -0,0 +1,237 @@
+// Package timeseries writes a run's results interval by interval, so that
+// warm-up, throttling and degradation over time can be plotted
+package timeseries
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/metrics"
+)
+
+// Format is the format of a time-series file
+type Format string
+
+const (
+	FormatAuto  Format = "auto" // by file extension
+	FormatCSV   Format = "csv"  // one row per operation per interval
+	FormatJSONL Format = "jsonl"
+)
+
+// ParseFormat parses a time-series format name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatAuto, FormatCSV, FormatJSONL:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown time-series format %q (want auto, csv or jsonl)", s)
+	}
+}
+
+// formatFor resolves FormatAuto by the extension of path: JSON lines for
+// .jsonl, .ndjson and .json, CSV otherwise
+func formatFor(path string, f Format) Format {
+	if f != FormatAuto {
+		return f
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson", ".json":
+		return FormatJSONL
+	default:
+		return FormatCSV
+	}
+}
+
+// TotalOp is the operation name of the rows summing all operations
+const TotalOp = "all"
+
+// csvHeader names the columns of a CSV time series. Run-wide columns are
+// repeated on every row of an interval.
+var csvHeader = []string{
+	"time", "elapsed", "op", "ops", "ops_per_sec", "bytes", "mib_per_sec",
+	"errors", "retries", "p50_ms", "p99_ms", "max_ms", "verify_failures", "active_workers",
+}
+
+// Writer writes interval samples to a time-series file. Each sample is
+// flushed as it is written, so the file can be followed during a run. It is
+// not safe for concurrent use.
+type Writer struct {
+	file   *os.File
+	buf    *bufio.Writer
+	csv    *csv.Writer
+	format Format
+	start  time.Time // of the first interval
+}
+
+// NewWriter creates a time-series file, truncating any existing one.
+// Elapsed times are recorded relative to the start of the first interval.
+func NewWriter(path string, format Format) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create time-series file: %w", err)
+	}
+
+	w := &Writer{
+		file:   f,
+		buf:    bufio.NewWriter(f),
+		format: formatFor(path, format),
+	}
+	if w.format == FormatCSV {
+		w.csv = csv.NewWriter(w.buf)
+		if err := w.csv.Write(csvHeader); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to write time-series header: %w", err)
+		}
+	}
+	return w, nil
+}
+
+// Write appends one interval
+func (w *Writer) Write(s metrics.WindowStats) error {
+	if w.start.IsZero() {
+		w.start = s.Start
+	}
+
+	var err error
+	if w.format == FormatCSV {
+		err = w.writeCSV(s)
+	} else {
+		err = w.writeJSON(s)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write time series: %w", err)
+	}
+	return w.buf.Flush()
+}
+
+func (w *Writer) writeCSV(s metrics.WindowStats) error {
+	interval := s.Interval()
+	ts := s.End.UTC().Format(time.RFC3339Nano)
+	elapsed := formatSeconds(s.End.Sub(w.start))
+
+	row := func(op string, o metrics.OpWindowStats) []string {
+		return []string{
+			ts,
+			elapsed,
+			op,
+			strconv.FormatInt(o.Ops, 10),
+			formatFloat(o.OpsPerSec(interval)),
+			strconv.FormatInt(o.Bytes, 10),
+			formatFloat(o.MiBPerSec(interval)),
+			strconv.FormatInt(o.Errors, 10),
+			strconv.FormatInt(o.Retries, 10),
+			formatMillis(o.P50),
+			formatMillis(o.P99),
+			formatMillis(o.Max),
+			strconv.FormatInt(s.VerifyFailures, 10),
+			strconv.Itoa(s.ActiveWorkers),
+		}
+	}
+
+	if err := w.csv.Write(row(TotalOp, s.Total)); err != nil {
+		return err
+	}
+	for _, op := range s.OpNames() {
+		if err := w.csv.Write(row(op, s.Ops[op])); err != nil {
+			return err
+		}
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// jsonOp is an operation's stats in a JSON lines sample
+type jsonOp struct {
+	Ops       int64   `json:"ops"`
+	OpsPerSec float64 `json:"ops_per_sec"`
+	Bytes     int64   `json:"bytes"`
+	MiBPerSec float64 `json:"mib_per_sec"`
+	Errors    int64   `json:"errors"`
+	Retries   int64   `json:"retries"`
+	P50Ms     float64 `json:"p50_ms"`
+	P99Ms     float64 `json:"p99_ms"`
+	MaxMs     float64 `json:"max_ms"`
+}
+
+// jsonSample is one line of a JSON lines time series
+type jsonSample struct {
+	Time           time.Time         `json:"time"`
+	Elapsed        float64           `json:"elapsed"`  // seconds since the run started
+	Interval       float64           `json:"interval"` // seconds
+	VerifyFailures int64             `json:"verify_failures"`
+	ActiveWorkers  int               `json:"active_workers"`
+	Total          jsonOp            `json:"total"`
+	Ops            map[string]jsonOp `json:"ops"`
+}
+
+func (w *Writer) writeJSON(s metrics.WindowStats) error {
+	interval := s.Interval()
+	op := func(o metrics.OpWindowStats) jsonOp {
+		return jsonOp{
+			Ops:       o.Ops,
+			OpsPerSec: o.OpsPerSec(interval),
+			Bytes:     o.Bytes,
+			MiBPerSec: o.MiBPerSec(interval),
+			Errors:    o.Errors,
+			Retries:   o.Retries,
+			P50Ms:     millis(o.P50),
+			P99Ms:     millis(o.P99),
+			MaxMs:     millis(o.Max),
+		}
+	}
+
+	sample := jsonSample{
+		Time:           s.End.UTC(),
+		Elapsed:        s.End.Sub(w.start).Seconds(),
+		Interval:       interval.Seconds(),
+		VerifyFailures: s.VerifyFailures,
+		ActiveWorkers:  s.ActiveWorkers,
+		Total:          op(s.Total),
+		Ops:            make(map[string]jsonOp, len(s.Ops)),
+	}
+	for name, o := range s.Ops {
+		sample.Ops[name] = op(o)
+	}
+
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	_, err = w.buf.Write(line)
+	return err
+}
+
+// Close flushes and closes the time-series file
+func (w *Writer) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to flush time series: %w", err)
+	}
+	return w.file.Close()
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// formatMillis formats a duration as milliseconds with microsecond precision
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(millis(d), 'f', 3, 64)
+}
+
+// formatSeconds formats a duration as seconds with millisecond precision
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
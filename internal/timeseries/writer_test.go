@@ -0,0 +1,137 @@
+package timeseries
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/metrics"
+)
+
+func TestFormatFor(t *testing.T) {
+	tests := []struct {
+		path   string
+		format Format
+		want   Format
+	}{
+		{"run.csv", FormatAuto, FormatCSV},
+		{"run.jsonl", FormatAuto, FormatJSONL},
+		{"run.NDJSON", FormatAuto, FormatJSONL},
+		{"run.json", FormatAuto, FormatJSONL},
+		{"run", FormatAuto, FormatCSV},
+		{"run.json", FormatCSV, FormatCSV},
+		{"run.csv", FormatJSONL, FormatJSONL},
+	}
+
+	for _, tt := range tests {
+		if got := formatFor(tt.path, tt.format); got != tt.want {
+			t.Errorf("formatFor(%q, %q) = %q, want %q", tt.path, tt.format, got, tt.want)
+		}
+	}
+}
+
+// testSample is a 2s interval ending at end
+func testSample(end time.Time) metrics.WindowStats {
+	return metrics.WindowStats{
+		Start: end.Add(-2 * time.Second),
+		End:   end,
+		Ops: map[string]metrics.OpWindowStats{
+			"put": {Ops: 10, Bytes: 4 << 20, Errors: 1, Retries: 2, P50: 5 * time.Millisecond, P99: 9 * time.Millisecond, Max: 12 * time.Millisecond},
+			"get": {Ops: 30, Bytes: 12 << 20, P50: time.Millisecond, P99: 3 * time.Millisecond, Max: 4 * time.Millisecond},
+		},
+		Total:          metrics.OpWindowStats{Ops: 40, Bytes: 16 << 20, Errors: 1, Retries: 2, P50: 2 * time.Millisecond, P99: 9 * time.Millisecond, Max: 12 * time.Millisecond},
+		VerifyFailures: 3,
+		ActiveWorkers:  8,
+	}
+}
+
+func TestWriterCSV(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "run.csv")
+	w, err := NewWriter(path, FormatAuto)
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+	if err := w.Write(testSample(start)); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+
+	want := [][]string{
+		csvHeader,
+		{"2024-01-02T03:04:05Z", "2.000", "all", "40", "20.000", "16777216", "8.000", "1", "2", "2.000", "9.000", "12.000", "3", "8"},
+		{"2024-01-02T03:04:05Z", "2.000", "get", "30", "15.000", "12582912", "6.000", "0", "0", "1.000", "3.000", "4.000", "3", "8"},
+		{"2024-01-02T03:04:05Z", "2.000", "put", "10", "5.000", "4194304", "2.000", "1", "2", "5.000", "9.000", "12.000", "3", "8"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %v", len(rows), len(want), rows)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if rows[i][j] != want[i][j] {
+				t.Errorf("row %d column %s = %q, want %q", i, csvHeader[j], rows[i][j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestWriterJSONL(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	w, err := NewWriter(path, FormatAuto)
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+	for i := 1; i <= 2; i++ {
+		if err := w.Write(testSample(start.Add(time.Duration(2*i) * time.Second))); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+		var s jsonSample
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			t.Fatalf("line %d is not a sample: %v", lines, err)
+		}
+		if s.Elapsed != float64(2*lines) || s.Interval != 2 || s.ActiveWorkers != 8 || s.VerifyFailures != 3 {
+			t.Errorf("line %d = %+v", lines, s)
+		}
+		if s.Total.Ops != 40 || s.Total.OpsPerSec != 20 || s.Total.MiBPerSec != 8 {
+			t.Errorf("line %d total = %+v", lines, s.Total)
+		}
+		if put := s.Ops["put"]; put.Errors != 1 || put.Retries != 2 || put.P99Ms != 9 || put.MaxMs != 12 {
+			t.Errorf("line %d put = %+v", lines, put)
+		}
+	}
+	if lines != 2 {
+		t.Errorf("got %d lines, want 2", lines)
+	}
+}
@@ -0,0 +1,105 @@
+package trace
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+// csvHeader names the columns of a CSV trace. Only the first four are
+// required; traces written by hand or converted from other tools may stop
+// there.
+var csvHeader = []string{"time", "op", "key", "size", "worker", "result", "latency"}
+
+// readCSV parses a CSV trace. Times are seconds since the start of the
+// trace, or RFC 3339 timestamps. A torn final line is ignored.
+func readCSV(rd io.Reader) (*Trace, error) {
+	r := csv.NewReader(rd)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+
+	t := &Trace{}
+	var pending error
+	for line := 1; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+
+		// Only the last line may be torn
+		if pending != nil {
+			return nil, pending
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			pending = fmt.Errorf("invalid trace record at line %d: %w", line, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read trace: %w", err)
+		}
+
+		if line == 1 && len(rec) > 0 && rec[0] == csvHeader[0] {
+			continue
+		}
+
+		ev, err := parseCSVRecord(rec)
+		if err != nil {
+			pending = fmt.Errorf("invalid trace record at line %d: %w", line, err)
+			continue
+		}
+		t.Events = append(t.Events, ev)
+	}
+	return t, nil
+}
+
+// parseCSVRecord parses one CSV trace record
+func parseCSVRecord(rec []string) (Event, error) {
+	if len(rec) < 4 {
+		return Event{}, fmt.Errorf("want at least %d fields (%s), got %d", 4, strings.Join(csvHeader[:4], ","), len(rec))
+	}
+
+	ev := Event{Op: workload.OpType(rec[1]), Key: rec[2], Worker: -1}
+
+	if secs, err := strconv.ParseFloat(rec[0], 64); err == nil {
+		ev.Time = time.Duration(secs * float64(time.Second))
+	} else if ts, err := time.Parse(time.RFC3339Nano, rec[0]); err == nil {
+		ev.Time = unixTime(ts)
+	} else {
+		return Event{}, fmt.Errorf("invalid time %q", rec[0])
+	}
+
+	if ev.Op == "" || ev.Key == "" && ev.Op != workload.OpList {
+		return Event{}, fmt.Errorf("missing op or key")
+	}
+
+	var err error
+	if ev.Size, err = strconv.ParseInt(rec[3], 10, 64); err != nil || ev.Size < 0 {
+		return Event{}, fmt.Errorf("invalid size %q", rec[3])
+	}
+
+	if len(rec) > 4 && rec[4] != "" {
+		if ev.Worker, err = strconv.Atoi(rec[4]); err != nil {
+			return Event{}, fmt.Errorf("invalid worker %q", rec[4])
+		}
+	}
+	if len(rec) > 5 {
+		ev.Result = rec[5]
+	}
+	if len(rec) > 6 && rec[6] != "" {
+		secs, err := strconv.ParseFloat(rec[6], 64)
+		if err != nil {
+			return Event{}, fmt.Errorf("invalid latency %q", rec[6])
+		}
+		ev.Latency = time.Duration(secs * float64(time.Second))
+	}
+
+	return ev, nil
+}
@@ -0,0 +1,175 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+// rgwEntry is the part of an RGW ops log entry replay needs
+type rgwEntry struct {
+	Object        string `json:"object"`
+	Time          string `json:"time"`
+	Operation     string `json:"operation"`
+	URI           string `json:"uri"`
+	ObjectSize    int64  `json:"object_size"`
+	BytesReceived int64  `json:"bytes_received"`
+}
+
+// rgwTimeLayouts are the time formats RGW has used in ops logs
+var rgwTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// readRGW parses an RGW ops log: a JSON array of entries, or one entry per
+// line as the file and socket backends write them
+func readRGW(rd io.Reader) (*Trace, error) {
+	br := bufio.NewReader(rd)
+	dec := json.NewDecoder(br)
+
+	inArray := false
+	if first, err := firstNonSpace(br); err == nil && first == '[' {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("invalid RGW ops log: %w", err)
+		}
+		inArray = true
+	}
+
+	t := &Trace{}
+	parts := newPartSizes()
+	for n := 1; ; n++ {
+		if inArray && !dec.More() {
+			break
+		}
+
+		var e rgwEntry
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid RGW ops log entry %d: %w", n, err)
+		}
+
+		ev, ok, err := e.event(parts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RGW ops log entry %d: %w", n, err)
+		}
+		if !ok {
+			t.Skipped++
+			continue
+		}
+		t.Events = append(t.Events, ev)
+	}
+	return t, nil
+}
+
+// event maps an ops log entry to an event. Multipart parts are not
+// events; their sizes are added up into the completed upload.
+func (e *rgwEntry) event(parts partSizes) (Event, bool, error) {
+	var ts time.Time
+	var err error
+	for _, layout := range rgwTimeLayouts {
+		if ts, err = time.Parse(layout, e.Time); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return Event{}, false, fmt.Errorf("invalid time %q", e.Time)
+	}
+
+	method, query := splitRequest(e.URI)
+	size := e.ObjectSize
+	if size == 0 {
+		size = e.BytesReceived
+	}
+
+	ev := Event{Time: unixTime(ts), Key: e.Object, Size: size, Worker: -1}
+	switch e.Operation {
+	case "get_obj":
+		ev.Op = workload.OpGet
+		if method == "HEAD" {
+			ev.Op = workload.OpHead
+		}
+	case "put_obj":
+		if id := query.Get("uploadId"); id != "" {
+			parts.add(id, size)
+			return Event{}, false, nil
+		}
+		ev.Op = workload.OpPut
+	case "complete_multipart":
+		ev.Op = workload.OpMultipartPut
+		ev.Size = parts.complete(query.Get("uploadId"), e.ObjectSize)
+	case "delete_obj":
+		ev.Op = workload.OpDelete
+	case "list_bucket":
+		ev.Op = workload.OpList
+		ev.Key = ""
+	default:
+		return Event{}, false, nil
+	}
+
+	if ev.Key == "" && ev.Op != workload.OpList {
+		return Event{}, false, nil
+	}
+	return ev, true, nil
+}
+
+// firstNonSpace returns the first byte that is not white space without
+// consuming it
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b)) {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
+// splitRequest returns the method and query of a request line such as
+// "PUT /bucket/key?uploadId=x HTTP/1.1"
+func splitRequest(line string) (string, url.Values) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	if len(fields) < 2 {
+		return fields[0], nil
+	}
+	u, err := url.ParseRequestURI(fields[1])
+	if err != nil {
+		return fields[0], nil
+	}
+	return fields[0], u.Query()
+}
+
+// partSizes adds up the sizes of the parts of multipart uploads
+type partSizes map[string]int64
+
+func newPartSizes() partSizes {
+	return make(partSizes)
+}
+
+func (p partSizes) add(uploadID string, size int64) {
+	p[uploadID] += size
+}
+
+// complete returns the size of a completed upload, or fallback when no
+// parts were seen
+func (p partSizes) complete(uploadID string, fallback int64) int64 {
+	size, ok := p[uploadID]
+	if !ok {
+		return fallback
+	}
+	delete(p, uploadID)
+	return size
+}
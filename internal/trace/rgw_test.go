@@ -0,0 +1,74 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+func TestReadRGW(t *testing.T) {
+	entries := []string{
+		`{"bucket":"prod","object":"img/a.jpg","time":"2024-05-17T09:30:00.000000Z","operation":"put_obj","uri":"PUT /prod/img/a.jpg HTTP/1.1","http_status":"200","object_size":1024,"bytes_received":1024}`,
+		`{"bucket":"prod","object":"img/a.jpg","time":"2024-05-17T09:30:01.500000Z","operation":"get_obj","uri":"GET /prod/img/a.jpg HTTP/1.1","http_status":"200","object_size":1024,"bytes_sent":1024}`,
+		`{"bucket":"prod","object":"img/a.jpg","time":"2024-05-17T09:30:02.000000Z","operation":"get_obj","uri":"HEAD /prod/img/a.jpg HTTP/1.1","http_status":"200","object_size":1024}`,
+		`{"bucket":"prod","object":"big.bin","time":"2024-05-17T09:30:03.000000Z","operation":"init_multipart","uri":"POST /prod/big.bin?uploads HTTP/1.1"}`,
+		`{"bucket":"prod","object":"big.bin","time":"2024-05-17T09:30:04.000000Z","operation":"put_obj","uri":"PUT /prod/big.bin?partNumber=1&uploadId=u1 HTTP/1.1","object_size":100}`,
+		`{"bucket":"prod","object":"big.bin","time":"2024-05-17T09:30:05.000000Z","operation":"put_obj","uri":"PUT /prod/big.bin?partNumber=2&uploadId=u1 HTTP/1.1","object_size":50}`,
+		`{"bucket":"prod","object":"big.bin","time":"2024-05-17T09:30:06.000000Z","operation":"complete_multipart","uri":"POST /prod/big.bin?uploadId=u1 HTTP/1.1","object_size":0}`,
+		`{"bucket":"prod","object":"","time":"2024-05-17T09:30:07.000000Z","operation":"list_bucket","uri":"GET /prod/?prefix=img HTTP/1.1"}`,
+		`{"bucket":"prod","object":"img/a.jpg","time":"2024-05-17T09:30:08.000000Z","operation":"delete_obj","uri":"DELETE /prod/img/a.jpg HTTP/1.1"}`,
+	}
+
+	want := []struct {
+		at   time.Duration
+		op   workload.OpType
+		key  string
+		size int64
+	}{
+		{0, workload.OpPut, "img/a.jpg", 1024},
+		{1500 * time.Millisecond, workload.OpGet, "img/a.jpg", 1024},
+		{2 * time.Second, workload.OpHead, "img/a.jpg", 1024},
+		{6 * time.Second, workload.OpMultipartPut, "big.bin", 150},
+		{7 * time.Second, workload.OpList, "", 0},
+		{8 * time.Second, workload.OpDelete, "img/a.jpg", 0},
+	}
+
+	inputs := map[string]string{
+		"lines": strings.Join(entries, "\n"),
+		"array": "[" + strings.Join(entries, ",\n") + "]",
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			tr, err := Read(strings.NewReader(input), FormatAuto)
+			if err != nil {
+				t.Fatalf("Read() failed: %v", err)
+			}
+			if tr.Skipped != 3 {
+				t.Errorf("Skipped = %d, want 3 (init and two parts)", tr.Skipped)
+			}
+			if len(tr.Events) != len(want) {
+				t.Fatalf("Read() = %+v, want %d events", tr.Events, len(want))
+			}
+			for i, w := range want {
+				ev := tr.Events[i]
+				if ev.Time != w.at || ev.Op != w.op || ev.Key != w.key || ev.Size != w.size {
+					t.Errorf("event %d = %+v, want %+v", i, ev, w)
+				}
+			}
+		})
+	}
+}
+
+func TestReadRGWInvalid(t *testing.T) {
+	for _, input := range []string{
+		`{"object":"a","time":"yesterday","operation":"get_obj"}`,
+		`{"object":"a",`,
+	} {
+		if _, err := Read(strings.NewReader(input), FormatRGW); err == nil {
+			t.Errorf("Read(%q) succeeded", input)
+		}
+	}
+}
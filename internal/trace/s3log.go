@@ -0,0 +1,161 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+// S3 server access log fields used by replay
+const (
+	s3logTime       = 2
+	s3logOperation  = 6
+	s3logKey        = 7
+	s3logRequestURI = 8
+	s3logBytesSent  = 11
+	s3logObjectSize = 12
+	s3logMinFields  = 13
+)
+
+// s3logTimeLayout is the time format of access logs, e.g. 06/Feb/2019:00:00:38 +0000
+const s3logTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// readS3Log parses S3 server access log lines
+func readS3Log(rd io.Reader) (*Trace, error) {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	t := &Trace{}
+	parts := newPartSizes()
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		ev, ok, err := parseS3LogLine(line, parts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access log line %d: %w", lineNum, err)
+		}
+		if !ok {
+			t.Skipped++
+			continue
+		}
+		t.Events = append(t.Events, ev)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read access log: %w", err)
+	}
+	return t, nil
+}
+
+// parseS3LogLine maps an access log line to an event. Multipart parts are
+// not events; their sizes are added up into the completed upload.
+func parseS3LogLine(line string, parts partSizes) (Event, bool, error) {
+	fields, err := splitS3LogFields(line)
+	if err != nil {
+		return Event{}, false, err
+	}
+	if len(fields) < s3logMinFields {
+		return Event{}, false, fmt.Errorf("want at least %d fields, got %d", s3logMinFields, len(fields))
+	}
+
+	ts, err := time.Parse(s3logTimeLayout, fields[s3logTime])
+	if err != nil {
+		return Event{}, false, fmt.Errorf("invalid time %q", fields[s3logTime])
+	}
+
+	key := ""
+	if k := fields[s3logKey]; k != "-" {
+		if key, err = url.PathUnescape(k); err != nil {
+			return Event{}, false, fmt.Errorf("invalid key %q", k)
+		}
+	}
+
+	size := s3logNumber(fields[s3logObjectSize])
+	_, query := splitRequest(fields[s3logRequestURI])
+
+	ev := Event{Time: unixTime(ts), Key: key, Size: size, Worker: -1}
+	switch fields[s3logOperation] {
+	case "REST.GET.OBJECT":
+		ev.Op = workload.OpGet
+	case "REST.HEAD.OBJECT":
+		ev.Op = workload.OpHead
+	case "REST.PUT.OBJECT":
+		ev.Op = workload.OpPut
+	case "REST.PUT.PART":
+		parts.add(query.Get("uploadId"), size)
+		return Event{}, false, nil
+	case "REST.POST.UPLOAD":
+		ev.Op = workload.OpMultipartPut
+		ev.Size = parts.complete(query.Get("uploadId"), size)
+	case "REST.DELETE.OBJECT":
+		ev.Op = workload.OpDelete
+	case "REST.GET.BUCKET":
+		ev.Op = workload.OpList
+		ev.Key = ""
+	default:
+		return Event{}, false, nil
+	}
+
+	// Some servers leave the object size empty on reads
+	if ev.Size == 0 && ev.Op == workload.OpGet {
+		ev.Size = s3logNumber(fields[s3logBytesSent])
+	}
+
+	if ev.Key == "" && ev.Op != workload.OpList {
+		return Event{}, false, nil
+	}
+	return ev, true, nil
+}
+
+// splitS3LogFields splits an access log line on spaces, keeping
+// [bracketed] and "quoted" fields whole and without their delimiters
+func splitS3LogFields(line string) ([]string, error) {
+	var fields []string
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+
+		var end byte = ' '
+		switch line[i] {
+		case '[':
+			end = ']'
+			i++
+		case '"':
+			end = '"'
+			i++
+		}
+
+		j := strings.IndexByte(line[i:], end)
+		if j == -1 {
+			if end != ' ' {
+				return nil, fmt.Errorf("unterminated field at column %d", i)
+			}
+			j = len(line) - i
+		}
+		fields = append(fields, line[i:i+j])
+		i += j + 1
+	}
+	return fields, nil
+}
+
+// s3logNumber parses a numeric field, where "-" means none
+func s3logNumber(s string) int64 {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
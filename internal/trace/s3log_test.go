@@ -0,0 +1,68 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+func TestReadS3Log(t *testing.T) {
+	lines := []string{
+		`79a5 prod [06/Feb/2019:00:00:38 +0000] 192.0.2.3 79a5 3E57 REST.PUT.OBJECT photos/a%20b.jpg "PUT /prod/photos/a%20b.jpg HTTP/1.1" 200 - - 2048 70 10 "-" "aws-cli/1.16" - s9lz= SigV4 ECDHE-RSA-AES128-GCM-SHA256 AuthHeader prod.s3.amazonaws.com TLSv1.2`,
+		`79a5 prod [06/Feb/2019:00:00:40 +0000] 192.0.2.3 79a5 3E58 REST.GET.OBJECT photos/a%20b.jpg "GET /prod/photos/a%20b.jpg HTTP/1.1" 200 - 2048 2048 5 4 "-" "aws-cli/1.16" -`,
+		`79a5 prod [06/Feb/2019:00:00:39 +0000] 192.0.2.3 79a5 3E59 REST.HEAD.OBJECT photos/a%20b.jpg "HEAD /prod/photos/a%20b.jpg HTTP/1.1" 200 - - 2048 3 - "-" "aws-cli/1.16" -`,
+		`79a5 prod [06/Feb/2019:00:00:41 +0000] 192.0.2.3 79a5 3E60 REST.PUT.PART big.bin "PUT /prod/big.bin?partNumber=1&uploadId=u1 HTTP/1.1" 200 - - 100 3 - "-" "aws-cli/1.16" -`,
+		`79a5 prod [06/Feb/2019:00:00:42 +0000] 192.0.2.3 79a5 3E61 REST.POST.UPLOAD big.bin "POST /prod/big.bin?uploadId=u1 HTTP/1.1" 200 - 300 - 3 - "-" "aws-cli/1.16" -`,
+		`79a5 prod [06/Feb/2019:00:00:43 +0000] 192.0.2.3 79a5 3E62 REST.GET.BUCKET - "GET /prod/?list-type=2 HTTP/1.1" 200 - 500 - 3 - "-" "aws-cli/1.16" -`,
+		`79a5 prod [06/Feb/2019:00:00:44 +0000] 192.0.2.3 79a5 3E63 REST.DELETE.OBJECT photos/a%20b.jpg "DELETE /prod/photos/a%20b.jpg HTTP/1.1" 204 - - - 3 - "-" "aws-cli/1.16" -`,
+		`79a5 prod [06/Feb/2019:00:00:45 +0000] 192.0.2.3 79a5 3E64 REST.GET.ACL photos/a%20b.jpg "GET /prod/photos/a%20b.jpg?acl HTTP/1.1" 200 - 500 - 3 - "-" "aws-cli/1.16" -`,
+	}
+
+	tr, err := Read(strings.NewReader(strings.Join(lines, "\n")), FormatAuto)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+
+	want := []struct {
+		at   time.Duration
+		op   workload.OpType
+		key  string
+		size int64
+	}{
+		{0, workload.OpPut, "photos/a b.jpg", 2048},
+		{time.Second, workload.OpHead, "photos/a b.jpg", 2048},
+		{2 * time.Second, workload.OpGet, "photos/a b.jpg", 2048},
+		{4 * time.Second, workload.OpMultipartPut, "big.bin", 100},
+		{5 * time.Second, workload.OpList, "", 0},
+		{6 * time.Second, workload.OpDelete, "photos/a b.jpg", 0},
+	}
+	if tr.Skipped != 2 {
+		t.Errorf("Skipped = %d, want 2 (part and ACL)", tr.Skipped)
+	}
+	if len(tr.Events) != len(want) {
+		t.Fatalf("Read() = %+v, want %d events", tr.Events, len(want))
+	}
+	for i, w := range want {
+		ev := tr.Events[i]
+		if ev.Time != w.at || ev.Op != w.op || ev.Key != w.key || ev.Size != w.size {
+			t.Errorf("event %d = %+v, want %+v", i, ev, w)
+		}
+	}
+}
+
+func TestSplitS3LogFields(t *testing.T) {
+	got, err := splitS3LogFields(`a [b c] "d e" - f`)
+	if err != nil {
+		t.Fatalf("splitS3LogFields() failed: %v", err)
+	}
+	want := []string{"a", "b c", "d e", "-", "f"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitS3LogFields() = %q, want %q", got, want)
+	}
+
+	if _, err := splitS3LogFields(`a "b c`); err == nil {
+		t.Error("splitS3LogFields() with unterminated quote succeeded")
+	}
+}
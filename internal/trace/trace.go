@@ -0,0 +1,175 @@
+// Package trace reads and writes traces of S3 operations: the tool's own
+// CSV traces, RGW ops logs and S3 server access logs.
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+// Format is the format of a trace file
+type Format string
+
+const (
+	FormatAuto  Format = "auto"
+	FormatCSV   Format = "csv"   // the tool's own format, see Writer
+	FormatRGW   Format = "rgw"   // RGW ops log, JSON objects
+	FormatS3Log Format = "s3log" // S3 server access log
+)
+
+// ParseFormat parses a trace format name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatAuto, FormatCSV, FormatRGW, FormatS3Log:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown trace format %q (want auto, csv, rgw or s3log)", s)
+	}
+}
+
+// Event is one operation in a trace
+type Event struct {
+	Time time.Duration // since the first event of the trace
+	Op   workload.OpType
+	Key  string
+	Size int64 // bytes written, or the size of the object read
+
+	// Only recorded traces carry these
+	Worker  int // -1 when unknown
+	Result  string
+	Latency time.Duration
+}
+
+// Trace is a parsed trace
+type Trace struct {
+	Events  []Event // in time order
+	Skipped int     // entries that do not map to an operation, such as multipart parts
+}
+
+// Duration returns the time from the first to the last event
+func (t *Trace) Duration() time.Duration {
+	if len(t.Events) == 0 {
+		return 0
+	}
+	return t.Events[len(t.Events)-1].Time
+}
+
+// Read parses a trace, decompressing it if it is gzipped. Events are
+// sorted by time, since access logs are not written in order, and their
+// times made relative to the first.
+func Read(rd io.Reader, format Format) (*Trace, error) {
+	br := bufio.NewReader(rd)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress trace: %w", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(truncatedReader{gz})
+	}
+
+	if format == FormatAuto || format == "" {
+		format = detectFormat(br)
+	}
+
+	var t *Trace
+	var err error
+	switch format {
+	case FormatCSV:
+		t, err = readCSV(br)
+	case FormatRGW:
+		t, err = readRGW(br)
+	case FormatS3Log:
+		t, err = readS3Log(br)
+	default:
+		return nil, fmt.Errorf("unknown trace format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(t.Events, func(i, j int) bool { return t.Events[i].Time < t.Events[j].Time })
+	if len(t.Events) > 0 {
+		first := t.Events[0].Time
+		for i := range t.Events {
+			t.Events[i].Time -= first
+		}
+	}
+	return t, nil
+}
+
+// Load reads the trace file at path
+func Load(path string, format Format) (*Trace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace: %w", err)
+	}
+	defer f.Close()
+
+	return Read(f, format)
+}
+
+// detectFormat guesses the format from the first line: RGW ops logs are
+// JSON, access log lines have a bracketed time and quoted fields
+func detectFormat(br *bufio.Reader) Format {
+	head, _ := br.Peek(4096)
+	head = bytes.TrimLeft(head, " \t\r\n")
+	if len(head) > 0 && (head[0] == '{' || head[0] == '[') {
+		return FormatRGW
+	}
+	if i := bytes.IndexByte(head, '\n'); i != -1 {
+		head = head[:i]
+	}
+	if bytes.Contains(head, []byte(" [")) && bytes.Contains(head, []byte(`"`)) {
+		return FormatS3Log
+	}
+	return FormatCSV
+}
+
+// truncatedReader ends a gzip stream cut short by a crash at the last
+// complete block instead of failing
+type truncatedReader struct {
+	r io.Reader
+}
+
+func (t truncatedReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Key remapping modes for replay
+const (
+	RemapPrefix = "prefix" // prepend the bench prefix
+	RemapHash   = "hash"   // replace the key with a hash of it under the bench prefix
+)
+
+// RemapKey maps a traced key into the keyspace under prefix. Hashing keeps
+// distinct keys distinct without carrying production key names over.
+func RemapKey(mode, prefix, key string) string {
+	key = strings.TrimPrefix(key, "/")
+	if mode == RemapHash {
+		sum := sha256.Sum256([]byte(key))
+		return prefix + hex.EncodeToString(sum[:8])
+	}
+	return prefix + key
+}
+
+// unixTime returns t as an offset that sorts with other events
+func unixTime(t time.Time) time.Duration {
+	return time.Duration(t.UnixNano())
+}
@@ -0,0 +1,137 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+func TestReadCSV(t *testing.T) {
+	input := `time,op,key,size,worker,result,latency
+0.5,get,"dir/a,b",100,3,ok,0.012
+0,put,dir/a,100,,,
+1.25,list,,0
+`
+	tr, err := Read(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+
+	want := []Event{
+		{Time: 0, Op: workload.OpPut, Key: "dir/a", Size: 100, Worker: -1},
+		{Time: 500 * time.Millisecond, Op: workload.OpGet, Key: "dir/a,b", Size: 100, Worker: 3, Result: "ok", Latency: 12 * time.Millisecond},
+		{Time: 1250 * time.Millisecond, Op: workload.OpList, Worker: -1},
+	}
+	if len(tr.Events) != len(want) {
+		t.Fatalf("Read() = %+v, want %+v", tr.Events, want)
+	}
+	for i := range want {
+		if tr.Events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, tr.Events[i], want[i])
+		}
+	}
+	if tr.Duration() != 1250*time.Millisecond {
+		t.Errorf("Duration() = %v, want 1.25s", tr.Duration())
+	}
+}
+
+func TestReadCSVTimestamps(t *testing.T) {
+	input := "2024-05-17T09:30:02Z,get,a,1\n2024-05-17T09:30:00Z,put,a,1\n"
+	tr, err := Read(strings.NewReader(input), FormatAuto)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if len(tr.Events) != 2 || tr.Events[0].Op != workload.OpPut || tr.Events[1].Time != 2*time.Second {
+		t.Errorf("Read() = %+v, want put at 0s then get at 2s", tr.Events)
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"torn last line", "0,put,a,1\n1,get,a", false},
+		{"bad line in middle", "0,put,a,1\nx,get,a,1\n1,get,a,1\n", true},
+		{"too few fields", "0,put,a\n1,get,a,1\n", true},
+		{"negative size", "0,put,a,-1\n1,get,a,1\n", true},
+		{"missing key", "0,put,,1\n1,get,a,1\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.input), FormatCSV)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("0,put,a,1\n1,get,a,1\n"))
+	gz.Close()
+
+	tr, err := Read(bytes.NewReader(buf.Bytes()), FormatAuto)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if len(tr.Events) != 2 {
+		t.Errorf("Read() = %+v, want 2 events", tr.Events)
+	}
+
+	// A stream cut short by a crash still yields its complete records
+	if _, err := Read(bytes.NewReader(buf.Bytes()[:buf.Len()-8]), FormatAuto); err != nil {
+		t.Errorf("Read() of truncated gzip failed: %v", err)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Format
+	}{
+		{`  [{"object":"a"}]`, FormatRGW},
+		{`{"object":"a"}`, FormatRGW},
+		{`owner bucket [06/Feb/2019:00:00:38 +0000] 192.0.2.3 - ID REST.GET.OBJECT key "GET /bucket/key HTTP/1.1" 200`, FormatS3Log},
+		{"time,op,key,size\n", FormatCSV},
+	}
+
+	for _, tt := range tests {
+		if got := detectFormat(newBufReader(tt.input)); got != tt.want {
+			t.Errorf("detectFormat(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	tr, err := Read(strings.NewReader(""), FormatAuto)
+	if err != nil || len(tr.Events) != 0 {
+		t.Errorf("Read() of empty input = %+v, %v", tr, err)
+	}
+}
+
+func TestRemapKey(t *testing.T) {
+	if got := RemapKey(RemapPrefix, "bench/", "/photos/a.jpg"); got != "bench/photos/a.jpg" {
+		t.Errorf("RemapKey(prefix) = %q", got)
+	}
+
+	a := RemapKey(RemapHash, "bench/", "photos/a.jpg")
+	b := RemapKey(RemapHash, "bench/", "photos/b.jpg")
+	if a == b || !strings.HasPrefix(a, "bench/") || len(a) != len("bench/")+16 {
+		t.Errorf("RemapKey(hash) = %q, %q", a, b)
+	}
+	if a != RemapKey(RemapHash, "bench/", "photos/a.jpg") {
+		t.Error("RemapKey(hash) is not deterministic")
+	}
+}
+
+func newBufReader(s string) *bufio.Reader {
+	return bufio.NewReader(strings.NewReader(s))
+}
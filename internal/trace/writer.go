@@ -0,0 +1,100 @@
+package trace
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Results recorded for operations
+const (
+	ResultOK       = "ok"
+	ResultNotFound = "not_found"
+	ResultError    = "error"
+)
+
+// Writer records operations to a gzipped CSV trace that Read can parse
+type Writer struct {
+	file  *os.File
+	buf   *bufio.Writer
+	gz    *gzip.Writer
+	w     *csv.Writer
+	start time.Time
+	mu    sync.Mutex
+}
+
+// NewWriter creates a trace file, truncating any existing one. Event times
+// are recorded relative to now.
+func NewWriter(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace file: %w", err)
+	}
+
+	buf := bufio.NewWriterSize(f, 256*1024)
+	gz := gzip.NewWriter(buf)
+	w := csv.NewWriter(gz)
+	if err := w.Write(csvHeader); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write trace header: %w", err)
+	}
+
+	return &Writer{
+		file:  f,
+		buf:   buf,
+		gz:    gz,
+		w:     w,
+		start: time.Now(),
+	}, nil
+}
+
+// Record appends an operation that started at invoke. The event's Time is
+// ignored.
+func (w *Writer) Record(invoke time.Time, ev Event) {
+	rec := []string{
+		formatSeconds(invoke.Sub(w.start)),
+		string(ev.Op),
+		ev.Key,
+		strconv.FormatInt(ev.Size, 10),
+		strconv.Itoa(ev.Worker),
+		ev.Result,
+		formatSeconds(ev.Latency),
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Write errors surface on Close through the csv writer
+	_ = w.w.Write(rec)
+}
+
+// Close flushes and closes the trace file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.w.Flush()
+	if err := w.w.Error(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	if err := w.gz.Close(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to compress trace: %w", err)
+	}
+	if err := w.buf.Flush(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to flush trace: %w", err)
+	}
+	return w.file.Close()
+}
+
+// formatSeconds formats a duration as seconds with microsecond precision
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 6, 64)
+}
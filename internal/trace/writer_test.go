@@ -0,0 +1,44 @@
+package trace
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paragkamble/s3bench/internal/workload"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.csv.gz")
+	w, err := NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+
+	start := w.start
+	w.Record(start.Add(2*time.Second), Event{Op: workload.OpGet, Key: "bench/a,b", Size: 100, Worker: 1, Result: ResultNotFound, Latency: 3 * time.Millisecond})
+	w.Record(start.Add(time.Second), Event{Op: workload.OpPut, Key: "bench/a,b", Size: 100, Worker: 0, Result: ResultOK, Latency: 15 * time.Millisecond})
+	w.Record(start.Add(3*time.Second), Event{Op: workload.OpList, Worker: 2, Result: ResultError, Latency: time.Second})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	tr, err := Load(path, FormatAuto)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	want := []Event{
+		{Time: 0, Op: workload.OpPut, Key: "bench/a,b", Size: 100, Worker: 0, Result: ResultOK, Latency: 15 * time.Millisecond},
+		{Time: time.Second, Op: workload.OpGet, Key: "bench/a,b", Size: 100, Worker: 1, Result: ResultNotFound, Latency: 3 * time.Millisecond},
+		{Time: 2 * time.Second, Op: workload.OpList, Worker: 2, Result: ResultError, Latency: time.Second},
+	}
+	if len(tr.Events) != len(want) {
+		t.Fatalf("Load() = %+v, want %+v", tr.Events, want)
+	}
+	for i := range want {
+		if tr.Events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, tr.Events[i], want[i])
+		}
+	}
+}
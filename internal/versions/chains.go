@@ -0,0 +1,150 @@
+package versions
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DefaultLimit is the default number of versions tracked per key
+const DefaultLimit = 256
+
+// Version is one tracked version of a key
+type Version struct {
+	ID     string
+	SHA256 string
+	Size   int64
+
+	start time.Time // PUT issued
+	end   time.Time // PUT acknowledged
+}
+
+// Chains tracks the version chain of every key written with a version ID.
+// Versions are kept in acknowledgement order, which for concurrent writes
+// to the same key may differ from the order the server applied them.
+type Chains struct {
+	mu     sync.Mutex
+	chains map[string][]*Version
+	keys   []string       // keys with a chain, for random selection
+	index  map[string]int // position of each key in keys
+	limit  int
+}
+
+// NewChains creates a tracker that keeps up to limit versions per key,
+// forgetting the oldest beyond that
+func NewChains(limit int) *Chains {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return &Chains{
+		chains: make(map[string][]*Version),
+		index:  make(map[string]int),
+		limit:  limit,
+	}
+}
+
+// Add records a version written by a PUT issued at start and acknowledged at end
+func (c *Chains) Add(key, id, hash string, size int64, start, end time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chain, ok := c.chains[key]
+	if !ok {
+		c.index[key] = len(c.keys)
+		c.keys = append(c.keys, key)
+	}
+	chain = append(chain, &Version{ID: id, SHA256: hash, Size: size, start: start, end: end})
+	if len(chain) > c.limit {
+		chain = chain[len(chain)-c.limit:]
+	}
+	c.chains[key] = chain
+}
+
+// Len returns the number of tracked versions of key
+func (c *Chains) Len(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.chains[key])
+}
+
+// RandomKey returns a random key with at least one tracked version. It
+// draws once from rng whether or not any key has one.
+func (c *Chains) RandomKey(rng *rand.Rand) (string, bool) {
+	n := rng.Int63()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.keys) == 0 {
+		return "", false
+	}
+	return c.keys[n%int64(len(c.keys))], true
+}
+
+// maxAttempts bounds the keys tried when looking for a noncurrent version
+const maxAttempts = 8
+
+// RandomNoncurrent returns a random version that is known to be noncurrent:
+// another version of the same key was written after it was acknowledged.
+// Versions that may still be current are never returned, so deleting the
+// result never changes what a plain GET of the key returns.
+func (c *Chains) RandomNoncurrent(rng *rand.Rand) (string, Version, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for attempt := 0; attempt < maxAttempts && len(c.keys) > 0; attempt++ {
+		key := c.keys[rng.Intn(len(c.keys))]
+		chain := c.chains[key]
+
+		// A version is superseded once a later write started after it ended
+		var latestStart time.Time
+		var candidates []*Version
+		for i := len(chain) - 1; i >= 0; i-- {
+			v := chain[i]
+			if v.end.Before(latestStart) {
+				candidates = append(candidates, v)
+			}
+			if v.start.After(latestStart) {
+				latestStart = v.start
+			}
+		}
+
+		if len(candidates) > 0 {
+			return key, *candidates[rng.Intn(len(candidates))], true
+		}
+	}
+
+	return "", Version{}, false
+}
+
+// Remove forgets a version, after it was deleted
+func (c *Chains) Remove(key, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chain, ok := c.chains[key]
+	if !ok {
+		return
+	}
+	for i, v := range chain {
+		if v.ID == id {
+			chain = append(chain[:i], chain[i+1:]...)
+			break
+		}
+	}
+
+	if len(chain) > 0 {
+		c.chains[key] = chain
+		return
+	}
+
+	// Swap-remove the key from the selection list
+	delete(c.chains, key)
+	i := c.index[key]
+	last := c.keys[len(c.keys)-1]
+	c.keys[i] = last
+	c.index[last] = i
+	c.keys = c.keys[:len(c.keys)-1]
+	delete(c.index, key)
+}
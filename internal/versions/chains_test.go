@@ -0,0 +1,119 @@
+package versions
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestChainsRandomNoncurrent(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	at := func(s int) time.Time { return base.Add(time.Duration(s) * time.Second) }
+
+	tests := []struct {
+		name   string
+		writes [][2]int // start, end in seconds, in acknowledgement order
+		want   []string // IDs that may be returned
+	}{
+		{
+			name:   "single version is current",
+			writes: [][2]int{{0, 1}},
+			want:   nil,
+		},
+		{
+			name:   "sequential writes",
+			writes: [][2]int{{0, 1}, {2, 3}, {4, 5}},
+			want:   []string{"v0", "v1"},
+		},
+		{
+			name:   "overlapping latest writes",
+			writes: [][2]int{{0, 1}, {2, 5}, {3, 6}},
+			want:   []string{"v0"},
+		},
+		{
+			name:   "all overlapping",
+			writes: [][2]int{{0, 4}, {1, 5}, {2, 6}},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChains(0)
+			for i, w := range tt.writes {
+				c.Add("k", "v"+string(rune('0'+i)), "h", 1, at(w[0]), at(w[1]))
+			}
+
+			allowed := make(map[string]bool)
+			for _, id := range tt.want {
+				allowed[id] = true
+			}
+
+			rng := rand.New(rand.NewSource(1))
+			seen := make(map[string]bool)
+			for i := 0; i < 100; i++ {
+				key, v, ok := c.RandomNoncurrent(rng)
+				if ok != (len(tt.want) > 0) {
+					t.Fatalf("RandomNoncurrent() ok = %v, want %v", ok, len(tt.want) > 0)
+				}
+				if !ok {
+					break
+				}
+				if key != "k" || !allowed[v.ID] {
+					t.Fatalf("RandomNoncurrent() = %q %q, want one of %v", key, v.ID, tt.want)
+				}
+				seen[v.ID] = true
+			}
+			if len(seen) != len(tt.want) {
+				t.Errorf("RandomNoncurrent() returned %v, want all of %v", seen, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainsLimitAndRemove(t *testing.T) {
+	c := NewChains(2)
+	now := time.Now()
+	c.Add("a", "v1", "h1", 1, now, now)
+	c.Add("a", "v2", "h2", 1, now, now)
+	c.Add("a", "v3", "h3", 1, now, now)
+	c.Add("b", "v1", "h1", 1, now, now)
+
+	if got := c.Len("a"); got != 2 {
+		t.Errorf("Len(a) = %d, want 2", got)
+	}
+
+	c.Remove("a", "v2")
+	if got := c.Len("a"); got != 1 {
+		t.Errorf("Len(a) after remove = %d, want 1", got)
+	}
+
+	c.Remove("a", "v3")
+	c.Remove("missing", "v1")
+	if got := c.Len("a"); got != 0 {
+		t.Errorf("Len(a) after removing all = %d, want 0", got)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		if key, ok := c.RandomKey(rng); !ok || key != "b" {
+			t.Fatalf("RandomKey() = %q, %v, want b", key, ok)
+		}
+	}
+}
+
+func TestRandomKeyDraws(t *testing.T) {
+	c := NewChains(2)
+	want := rand.New(rand.NewSource(1))
+	want.Int63()
+
+	// The draw is the same with no keys, so later picks do not depend on
+	// whether other workers have written versions yet
+	rng := rand.New(rand.NewSource(1))
+	if _, ok := c.RandomKey(rng); ok {
+		t.Fatal("RandomKey() found a key in empty chains")
+	}
+	if got, next := rng.Int63(), want.Int63(); got != next {
+		t.Errorf("next draw after RandomKey() = %d, want %d", got, next)
+	}
+}
@@ -2,86 +2,331 @@ package workload
 
 import (
 	"fmt"
+	"math/rand"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
-// KeyGenerator generates object keys
+// placeholderKind identifies a part of a key template
+type placeholderKind int
+
+const (
+	partLiteral placeholderKind = iota
+	partSeq
+	partHash
+	partMod
+	partWorker
+	partRand
+	partDate
+	partRun
+)
+
+// templatePart is a literal or a placeholder of a parsed key template
+type templatePart struct {
+	kind   placeholderKind
+	text   string // literal text or date layout
+	n      int    // width, digits or modulus
+	padded bool
+}
+
+const randChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// maxHashDigits is the number of hex digits in the 64-bit sequence hash
+const maxHashDigits = 16
+
+// runIDPattern matches the run IDs accepted in {run}
+var runIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// KeyGenerator generates object keys from a template with placeholders:
+//
+//	{seq}, {seq:NN}  sequence number, optionally zero-padded to NN digits
+//	{hash:N}         first N hex digits of a hash of the sequence number
+//	{mod:N}          sequence number modulo N, zero-padded, for directory fan-out
+//	{worker}         ID of the worker generating the key
+//	{rand:N}         N random lowercase letters and digits
+//	{date:LAYOUT}    current UTC time in a Go time layout, e.g. {date:2006/01/02}
+//	{run}            run ID
+//
+// Keys using {worker}, {rand:N} or {date:...} are not a function of the
+// sequence number, so a read only finds an object written with the same
+// values. A generator whose template uses {rand:N} is not safe for
+// concurrent use; give each worker its own with ForWorker.
 type KeyGenerator struct {
 	prefix   string
 	template string
 	keys     int
+	runID    string
+
+	parts      []templatePart
+	matcher    *regexp.Regexp
+	runMatcher *regexp.Regexp // like matcher, with {run} pinned to runID
+
+	worker int
+	rng    *rand.Rand
 }
 
-// NewKeyGenerator creates a new key generator
-func NewKeyGenerator(prefix, template string, keys int) *KeyGenerator {
-	return &KeyGenerator{
-		prefix:   prefix,
-		template: template,
-		keys:     keys,
+// NewKeyGenerator parses template and creates a key generator for keys
+// keys under prefix
+func NewKeyGenerator(prefix, template string, keys int, runID string) (*KeyGenerator, error) {
+	parts, err := parseTemplate(template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key template %q: %w", template, err)
 	}
-}
 
-// Generate generates a key for a given sequence number
-func (kg *KeyGenerator) Generate(seq int) string {
-	key := kg.template
+	for _, p := range parts {
+		if p.kind == partRun && !runIDPattern.MatchString(runID) {
+			return nil, fmt.Errorf("key template uses {run} but run ID %q is empty or has characters other than letters, digits, '.', '_' and '-'", runID)
+		}
+	}
 
-	// Replace {seq} or {seq:format} placeholders
-	// Example: obj-{seq:08}.bin -> obj-00000042.bin
-	key = replacePlaceholder(key, seq)
+	matcher, err := regexp.Compile(templateRegexp(prefix, parts, ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key template %q: %w", template, err)
+	}
+	runMatcher, err := regexp.Compile(templateRegexp(prefix, parts, runID))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key template %q: %w", template, err)
+	}
 
-	return kg.prefix + key
+	return &KeyGenerator{
+		prefix:     prefix,
+		template:   template,
+		keys:       keys,
+		runID:      runID,
+		parts:      parts,
+		matcher:    matcher,
+		runMatcher: runMatcher,
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
 }
 
-// Count returns the total number of keys
-func (kg *KeyGenerator) Count() int {
-	return kg.keys
+// ForWorker returns a generator for the same keys that fills {worker} with
+// worker and draws {rand:N} from rng
+func (kg *KeyGenerator) ForWorker(worker int, rng *rand.Rand) *KeyGenerator {
+	w := *kg
+	w.worker = worker
+	w.rng = rng
+	return &w
 }
 
-func replacePlaceholder(template string, seq int) string {
-	// Find {seq...} pattern
-	start := -1
-	for i := 0; i < len(template); i++ {
-		if i+4 <= len(template) && template[i:i+4] == "{seq" {
-			start = i
+// parseTemplate splits a template into literals and placeholders
+func parseTemplate(template string) ([]templatePart, error) {
+	var parts []templatePart
+	rest := template
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		if open == -1 {
+			parts = append(parts, templatePart{kind: partLiteral, text: rest})
 			break
 		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("unmatched '}'")
+		}
+		if open > 0 {
+			parts = append(parts, templatePart{kind: partLiteral, text: rest[:open]})
+		}
+
+		end := strings.IndexByte(rest[open:], '}')
+		if end == -1 {
+			return nil, fmt.Errorf("unclosed placeholder %q", rest[open:])
+		}
+		p, err := parsePlaceholder(rest[open+1 : open+end])
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+		rest = rest[open+end+1:]
+	}
+	return parts, nil
+}
+
+// parsePlaceholder parses the inside of a {...} placeholder
+func parsePlaceholder(s string) (templatePart, error) {
+	name, arg, hasArg := strings.Cut(s, ":")
+
+	// number parses the argument as a positive integer up to max
+	number := func(max int) (int, error) {
+		n, err := strconv.Atoi(arg)
+		if !hasArg || err != nil || n < 1 || n > max {
+			return 0, fmt.Errorf("{%s} needs a number between 1 and %d", s, max)
+		}
+		return n, nil
 	}
 
-	if start == -1 {
-		return template
+	switch name {
+	case "seq":
+		if !hasArg {
+			return templatePart{kind: partSeq}, nil
+		}
+		n, err := number(20)
+		return templatePart{kind: partSeq, n: n, padded: true}, err
+	case "hash":
+		n, err := number(maxHashDigits)
+		return templatePart{kind: partHash, n: n}, err
+	case "mod":
+		n, err := number(1 << 30)
+		return templatePart{kind: partMod, n: n}, err
+	case "rand":
+		n, err := number(64)
+		return templatePart{kind: partRand, n: n}, err
+	case "date":
+		if arg == "" || strings.ContainsAny(arg, "{}") {
+			return templatePart{}, fmt.Errorf("{%s} needs a Go time layout, e.g. {date:2006/01/02}", s)
+		}
+		return templatePart{kind: partDate, text: arg}, nil
+	case "worker", "run":
+		if hasArg {
+			return templatePart{}, fmt.Errorf("{%s} takes no argument", name)
+		}
+		if name == "worker" {
+			return templatePart{kind: partWorker}, nil
+		}
+		return templatePart{kind: partRun}, nil
+	default:
+		return templatePart{}, fmt.Errorf("unknown placeholder {%s}", s)
 	}
+}
 
-	// Find closing brace
-	end := -1
-	for i := start; i < len(template); i++ {
-		if template[i] == '}' {
-			end = i
-			break
+// modDigits returns the width {mod:n} values are padded to
+func modDigits(n int) int {
+	return len(strconv.Itoa(n - 1))
+}
+
+// templateRegexp returns a regular expression matching every key a
+// template can produce, for any sequence number, worker and time, and for
+// runID or, if empty, any run
+func templateRegexp(prefix string, parts []templatePart, runID string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	b.WriteString(regexp.QuoteMeta(prefix))
+	for _, p := range parts {
+		switch p.kind {
+		case partLiteral:
+			b.WriteString(regexp.QuoteMeta(p.text))
+		case partSeq:
+			// Padded sequence numbers are never shorter than the width
+			if p.padded {
+				fmt.Fprintf(&b, `[0-9]{%d,}`, p.n)
+			} else {
+				b.WriteString(`[0-9]+`)
+			}
+		case partHash:
+			fmt.Fprintf(&b, `[0-9a-f]{%d}`, p.n)
+		case partMod:
+			fmt.Fprintf(&b, `[0-9]{%d}`, modDigits(p.n))
+		case partWorker:
+			b.WriteString(`[0-9]+`)
+		case partRand:
+			fmt.Fprintf(&b, `[a-z0-9]{%d}`, p.n)
+		case partDate:
+			b.WriteString(dateRegexp(p.text))
+		case partRun:
+			if runID != "" {
+				b.WriteString(regexp.QuoteMeta(runID))
+			} else {
+				b.WriteString(`[A-Za-z0-9._-]+`)
+			}
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// dateRegexp approximates the strings a time layout produces: numbers and
+// names may vary in length, and padding spaces may be absent
+func dateRegexp(layout string) string {
+	sample := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC).Format(layout)
+
+	var b strings.Builder
+	for i := 0; i < len(sample); {
+		c := sample[i]
+		j := i + 1
+		switch {
+		case c >= '0' && c <= '9':
+			for j < len(sample) && sample[j] >= '0' && sample[j] <= '9' {
+				j++
+			}
+			b.WriteString(`[0-9]+`)
+		case c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			for j < len(sample) && (sample[j] >= 'A' && sample[j] <= 'Z' || sample[j] >= 'a' && sample[j] <= 'z') {
+				j++
+			}
+			b.WriteString(`[A-Za-z]+`)
+		case c == ' ':
+			b.WriteString(` *`)
+		default:
+			b.WriteString(regexp.QuoteMeta(sample[i:j]))
 		}
+		i = j
 	}
+	return b.String()
+}
+
+// seqHash mixes a sequence number so that its hex digits are evenly spread
+func seqHash(seq int) uint64 {
+	return splitmix64(uint64(seq))
+}
 
-	if end == -1 {
-		return template
+// Generate generates a key for a given sequence number
+// Example: obj-{seq:08}.bin -> obj-00000042.bin
+func (kg *KeyGenerator) Generate(seq int) string {
+	var b strings.Builder
+	b.WriteString(kg.prefix)
+	for _, p := range kg.parts {
+		switch p.kind {
+		case partLiteral:
+			b.WriteString(p.text)
+		case partSeq:
+			if p.padded {
+				fmt.Fprintf(&b, "%0*d", p.n, seq)
+			} else {
+				b.WriteString(strconv.Itoa(seq))
+			}
+		case partHash:
+			b.WriteString(fmt.Sprintf("%016x", seqHash(seq))[:p.n])
+		case partMod:
+			fmt.Fprintf(&b, "%0*d", modDigits(p.n), seq%p.n)
+		case partWorker:
+			b.WriteString(strconv.Itoa(kg.worker))
+		case partRand:
+			for i := 0; i < p.n; i++ {
+				b.WriteByte(randChars[kg.rng.Intn(len(randChars))])
+			}
+		case partDate:
+			b.WriteString(time.Now().UTC().Format(p.text))
+		case partRun:
+			b.WriteString(kg.runID)
+		}
 	}
+	return b.String()
+}
 
-	// Extract format if present
-	placeholder := template[start : end+1]
-	var formatted string
-
-	if placeholder == "{seq}" {
-		formatted = strconv.Itoa(seq)
-	} else if strings.HasPrefix(placeholder, "{seq:") {
-		// Extract format like "08" from "{seq:08}"
-		format := placeholder[5 : len(placeholder)-1]
-		if width, err := strconv.Atoi(format); err == nil && width > 0 {
-			formatted = fmt.Sprintf("%0*d", width, seq)
-		} else {
-			formatted = strconv.Itoa(seq)
+// Matches reports whether key could have been produced by Generate. Keys
+// outside the keyspace, from other workers, times and runs still match, so
+// objects from earlier runs are recognized.
+func (kg *KeyGenerator) Matches(key string) bool {
+	return kg.matcher.MatchString(key)
+}
+
+// MatchesRun is like Matches, but {run} must be this generator's run ID
+func (kg *KeyGenerator) MatchesRun(key string) bool {
+	return kg.runMatcher.MatchString(key)
+}
+
+// UsesRun reports whether the template contains {run}, which ties its keys
+// to a single run
+func (kg *KeyGenerator) UsesRun() bool {
+	for _, p := range kg.parts {
+		if p.kind == partRun {
+			return true
 		}
-	} else {
-		formatted = strconv.Itoa(seq)
 	}
+	return false
+}
 
-	return template[:start] + formatted + template[end+1:]
+// Count returns the total number of keys
+func (kg *KeyGenerator) Count() int {
+	return kg.keys
 }
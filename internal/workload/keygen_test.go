@@ -1,7 +1,11 @@
 package workload
 
 import (
+	"fmt"
+	"math/rand"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestKeyGenerator(t *testing.T) {
@@ -33,6 +37,27 @@ func TestKeyGenerator(t *testing.T) {
 			seq:      42,
 			want:     "data/fixed.bin",
 		},
+		{
+			name:     "hash and mod fan-out",
+			prefix:   "bench/",
+			template: "{hash:4}/{mod:100}/obj-{seq}",
+			seq:      42,
+			want:     "bench/" + fmt.Sprintf("%016x", seqHash(42))[:4] + "/42/obj-42",
+		},
+		{
+			name:     "mod padding",
+			prefix:   "",
+			template: "d{mod:1000}/{seq}",
+			seq:      5,
+			want:     "d005/5",
+		},
+		{
+			name:     "multiple placeholders",
+			prefix:   "",
+			template: "{run}/{seq}-{seq:03}",
+			seq:      7,
+			want:     "run-1/7-007",
+		},
 		{
 			name:     "empty prefix",
 			prefix:   "",
@@ -44,7 +69,10 @@ func TestKeyGenerator(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			kg := NewKeyGenerator(tt.prefix, tt.template, 1000)
+			kg, err := NewKeyGenerator(tt.prefix, tt.template, 1000, "run-1")
+			if err != nil {
+				t.Fatalf("NewKeyGenerator() failed: %v", err)
+			}
 			got := kg.Generate(tt.seq)
 			if got != tt.want {
 				t.Errorf("Generate() = %q, want %q", got, tt.want)
@@ -54,8 +82,159 @@ func TestKeyGenerator(t *testing.T) {
 }
 
 func TestKeyGeneratorCount(t *testing.T) {
-	kg := NewKeyGenerator("prefix/", "obj-{seq}.bin", 12345)
+	kg, err := NewKeyGenerator("prefix/", "obj-{seq}.bin", 12345, "")
+	if err != nil {
+		t.Fatalf("NewKeyGenerator() failed: %v", err)
+	}
 	if kg.Count() != 12345 {
 		t.Errorf("Count() = %d, want 12345", kg.Count())
 	}
 }
+
+func TestKeyGeneratorMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		template string
+		key      string
+		want     bool
+	}{
+		{"generated", "bench/", "obj-{seq:08}.bin", "bench/obj-00000042.bin", true},
+		{"beyond keyspace", "bench/", "obj-{seq:08}.bin", "bench/obj-123456789.bin", true},
+		{"short padding", "bench/", "obj-{seq:08}.bin", "bench/obj-42.bin", false},
+		{"wrong prefix", "bench/", "obj-{seq:08}.bin", "other/obj-00000042.bin", false},
+		{"wrong suffix", "bench/", "obj-{seq:08}.bin", "bench/obj-00000042.dat", false},
+		{"non digits", "bench/", "obj-{seq}.bin", "bench/obj-4x2.bin", false},
+		{"empty seq", "bench/", "obj-{seq}.bin", "bench/obj-.bin", false},
+		{"unpadded", "", "obj-{seq}.bin", "obj-7.bin", true},
+		{"no placeholder", "data/", "fixed.bin", "data/fixed.bin", true},
+		{"no placeholder mismatch", "data/", "fixed.bin", "data/other.bin", false},
+		{"hash", "", "{hash:4}/{seq}", "0a9f/12", true},
+		{"hash wrong length", "", "{hash:4}/{seq}", "0a9/12", false},
+		{"hash not hex", "", "{hash:4}/{seq}", "0a9g/12", false},
+		{"mod", "", "{mod:16}/{seq}", "07/12", true},
+		{"mod unpadded", "", "{mod:16}/{seq}", "7/12", false},
+		{"worker", "", "w{worker}/{seq}", "w12/3", true},
+		{"rand", "", "{seq}-{rand:4}", "3-a0z9", true},
+		{"rand wrong length", "", "{seq}-{rand:4}", "3-a0z", false},
+		{"other run", "", "{run}/{seq}", "20240101-000000/3", true},
+		{"date", "", "{date:2006/01/02}/{seq}", "2031/12/09/3", true},
+		{"date wrong shape", "", "{date:2006/01/02}/{seq}", "2031-12-09/3", false},
+		{"regexp characters literal", "a.b/", "obj+{seq}", "a.b/obj+1", true},
+		{"regexp characters not special", "a.b/", "obj+{seq}", "axb/obj+1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kg, err := NewKeyGenerator(tt.prefix, tt.template, 1000, "run-1")
+			if err != nil {
+				t.Fatalf("NewKeyGenerator() failed: %v", err)
+			}
+			if got := kg.Matches(tt.key); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyGeneratorInvalidTemplates(t *testing.T) {
+	for _, template := range []string{
+		"obj-{seq",
+		"obj-}{seq}",
+		"obj-{unknown}",
+		"obj-{seq:abc}",
+		"obj-{seq:0}",
+		"obj-{hash}",
+		"obj-{hash:17}",
+		"obj-{mod:0}",
+		"obj-{rand:x}",
+		"obj-{date:}",
+		"obj-{worker:2}",
+	} {
+		if _, err := NewKeyGenerator("", template, 10, "run"); err == nil {
+			t.Errorf("NewKeyGenerator(%q) succeeded", template)
+		}
+	}
+
+	if _, err := NewKeyGenerator("", "{run}/{seq}", 10, "bad run"); err == nil {
+		t.Error("NewKeyGenerator() with invalid run ID succeeded")
+	}
+	if _, err := NewKeyGenerator("", "{seq}", 10, "bad run"); err != nil {
+		t.Errorf("NewKeyGenerator() without {run} failed: %v", err)
+	}
+}
+
+func TestKeyGeneratorForWorker(t *testing.T) {
+	kg, err := NewKeyGenerator("bench/", "{worker}/{date:2006-01-02}/{rand:8}-{seq}", 10, "run")
+	if err != nil {
+		t.Fatalf("NewKeyGenerator() failed: %v", err)
+	}
+	w := kg.ForWorker(3, rand.New(rand.NewSource(42)))
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		key := w.Generate(1)
+		wantPrefix := "bench/3/" + time.Now().UTC().Format("2006-01-02") + "/"
+		if !strings.HasPrefix(key, wantPrefix) {
+			t.Fatalf("Generate() = %q, want prefix %q", key, wantPrefix)
+		}
+		if !kg.Matches(key) {
+			t.Errorf("Matches(%q) = false for a generated key", key)
+		}
+		seen[key] = true
+	}
+	if len(seen) < 90 {
+		t.Errorf("{rand:8} produced only %d distinct keys in 100", len(seen))
+	}
+}
+
+func TestSeqHashSpread(t *testing.T) {
+	counts := make(map[byte]int)
+	for seq := 0; seq < 16000; seq++ {
+		counts[fmt.Sprintf("%016x", seqHash(seq))[0]]++
+	}
+	for digit, n := range counts {
+		if n < 800 || n > 1200 {
+			t.Errorf("leading digit %c appears %d times, want ~1000", digit, n)
+		}
+	}
+	if len(counts) != 16 {
+		t.Errorf("leading digits cover %d values, want 16", len(counts))
+	}
+}
+
+func TestKeyGeneratorMatchesRun(t *testing.T) {
+	kg, err := NewKeyGenerator("bench/", "{run}/obj-{seq:08}.bin", 100, "run-1")
+	if err != nil {
+		t.Fatalf("NewKeyGenerator() failed: %v", err)
+	}
+	if !kg.UsesRun() {
+		t.Error("UsesRun() = false for a template with {run}")
+	}
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"bench/run-1/obj-00000042.bin", true},
+		{"bench/run-2/obj-00000042.bin", false},
+		{"bench/run-1x/obj-00000042.bin", false},
+		{"bench/run.1/obj-00000042.bin", false},
+	}
+	for _, tt := range tests {
+		if got := kg.MatchesRun(tt.key); got != tt.want {
+			t.Errorf("MatchesRun(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+		if !kg.Matches(tt.key) {
+			t.Errorf("Matches(%q) = false, want any run", tt.key)
+		}
+	}
+
+	plain, err := NewKeyGenerator("bench/", "obj-{seq:08}.bin", 100, "run-1")
+	if err != nil {
+		t.Fatalf("NewKeyGenerator() failed: %v", err)
+	}
+	if plain.UsesRun() {
+		t.Error("UsesRun() = true for a template without {run}")
+	}
+}
@@ -0,0 +1,36 @@
+package workload
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// SubPrefix returns a random prefix of key that is at least prefix. With a
+// delimiter it ends at a delimiter, like a directory of key; without one it
+// cuts key at a random length short of the whole key.
+func SubPrefix(prefix, key, delimiter string, rng *rand.Rand) string {
+	if !strings.HasPrefix(key, prefix) || len(key) == len(prefix) {
+		return prefix
+	}
+	rest := key[len(prefix):]
+
+	if delimiter == "" {
+		return prefix + rest[:rng.Intn(len(rest))]
+	}
+
+	var ends []int
+	for i := 0; ; {
+		j := strings.Index(rest[i:], delimiter)
+		if j == -1 {
+			break
+		}
+		i += j + len(delimiter)
+		ends = append(ends, i)
+	}
+
+	n := rng.Intn(len(ends) + 1)
+	if n == 0 {
+		return prefix
+	}
+	return prefix + rest[:ends[n-1]]
+}
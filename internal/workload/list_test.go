@@ -0,0 +1,54 @@
+package workload
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestSubPrefix(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+
+	tests := []struct {
+		name      string
+		prefix    string
+		key       string
+		delimiter string
+		want      []string
+	}{
+		{"directories", "bench/", "bench/a/b/obj-1", "/", []string{"bench/", "bench/a/", "bench/a/b/"}},
+		{"multi-byte delimiter", "", "x--y--z", "--", []string{"", "x--", "x--y--"}},
+		{"no directories", "bench/", "bench/obj-1", "/", []string{"bench/"}},
+		{"key outside prefix", "bench/", "other/obj-1", "/", []string{"bench/"}},
+		{"key equals prefix", "bench/", "bench/", "", []string{"bench/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := make(map[string]bool)
+			for i := 0; i < 200; i++ {
+				seen[SubPrefix(tt.prefix, tt.key, tt.delimiter, rng)] = true
+			}
+			if len(seen) != len(tt.want) {
+				t.Errorf("SubPrefix() returned %v, want %v", seen, tt.want)
+			}
+			for _, w := range tt.want {
+				if !seen[w] {
+					t.Errorf("SubPrefix() never returned %q", w)
+				}
+			}
+		})
+	}
+}
+
+func TestSubPrefixWithoutDelimiter(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	key := "bench/obj-00000042"
+
+	for i := 0; i < 200; i++ {
+		got := SubPrefix("bench/", key, "", rng)
+		if !strings.HasPrefix(got, "bench/") || !strings.HasPrefix(key, got) || got == key {
+			t.Fatalf("SubPrefix() = %q, want a proper prefix of %q starting with bench/", got, key)
+		}
+	}
+}
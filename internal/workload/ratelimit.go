@@ -4,7 +4,6 @@ import (
 	"context"
 	"math"
 	"math/rand"
-	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -48,11 +47,12 @@ func (f *FixedRateLimiter) Tokens() float64 {
 	return float64(f.limiter.Burst())
 }
 
-// PoissonRateLimiter implements Poisson arrival process rate limiting
+// PoissonRateLimiter implements Poisson arrival process rate limiting. It
+// is not safe for concurrent use; each worker waits on its own limiter at
+// its share of the rate, which adds up to a Poisson process at the full rate.
 type PoissonRateLimiter struct {
 	lambda float64 // mean rate (events per second)
 	rng    *rand.Rand
-	mu     sync.Mutex
 }
 
 // NewPoissonRateLimiter creates a new Poisson rate limiter
@@ -69,15 +69,11 @@ func NewPoissonRateLimiter(lambda float64, seed int64) *PoissonRateLimiter {
 
 // Wait waits for the next event based on Poisson distribution
 func (p *PoissonRateLimiter) Wait(ctx context.Context) error {
-	p.mu.Lock()
-
 	// Generate inter-arrival time from exponential distribution
 	// For Poisson process with rate λ, inter-arrival times follow Exp(λ)
 	u := p.rng.Float64()
 	interArrival := -math.Log(u) / p.lambda
 
-	p.mu.Unlock()
-
 	if math.IsInf(interArrival, 0) || interArrival <= 0 {
 		return nil
 	}
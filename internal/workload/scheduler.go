@@ -3,7 +3,7 @@ package workload
 import (
 	"fmt"
 	"math/rand"
-	"sync"
+	"sort"
 )
 
 // OpType represents an operation type
@@ -17,20 +17,58 @@ const (
 	OpList         OpType = "list"
 	OpHead         OpType = "head"
 	OpMultipartPut OpType = "multipart_put"
+	OpRangeGet     OpType = "range_get"
+	OpMultiDelete  OpType = "multi_delete"
+
+	// OpMultipartCopy builds an object from ranges of others with UploadPartCopy
+	OpMultipartCopy OpType = "multipart_copy"
+
+	// Versioning operations
+	OpOverwrite     OpType = "overwrite"
+	OpGetVersion    OpType = "get_version"
+	OpListVersions  OpType = "list_versions"
+	OpDeleteVersion OpType = "delete_version"
+
+	// Tagging and metadata operations
+	OpPutTagging          OpType = "put_tagging"
+	OpGetTagging          OpType = "get_tagging"
+	OpDeleteTagging       OpType = "delete_tagging"
+	OpCopyReplaceMetadata OpType = "copy_replace_metadata"
+
+	// OpTransition changes an object's storage class with an in-place copy
+	OpTransition OpType = "transition"
 )
 
-// Scheduler schedules operations based on the configured mix
-type Scheduler struct {
+// Ops lists every operation a mix can include
+var Ops = []OpType{
+	OpPut, OpGet, OpDelete, OpCopy, OpList, OpHead, OpMultipartPut, OpRangeGet, OpMultiDelete,
+	OpMultipartCopy,
+	OpOverwrite, OpGetVersion, OpListVersions, OpDeleteVersion,
+	OpPutTagging, OpGetTagging, OpDeleteTagging, OpCopyReplaceMetadata,
+	OpTransition,
+}
+
+// Known reports whether op is one of Ops
+func (op OpType) Known() bool {
+	for _, o := range Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Mix is a parsed operation mix. Workers share a mix and each schedules
+// from it with its own Scheduler.
+type Mix struct {
 	mix       map[OpType]int // percentage for each op
 	weights   []int          // cumulative weights
 	ops       []OpType       // operations in order
 	totalKeys int
-	rng       *rand.Rand
-	mu        sync.Mutex
 }
 
-// NewScheduler creates a new operation scheduler
-func NewScheduler(mix map[string]int, totalKeys int, seed int64) (*Scheduler, error) {
+// NewMix parses an operation mix over a keyspace of totalKeys keys
+func NewMix(mix map[string]int, totalKeys int) (*Mix, error) {
 	if len(mix) == 0 {
 		return nil, fmt.Errorf("operation mix cannot be empty")
 	}
@@ -44,34 +82,72 @@ func NewScheduler(mix map[string]int, totalKeys int, seed int64) (*Scheduler, er
 		return nil, fmt.Errorf("operation mix percentages sum to zero")
 	}
 
-	s := &Scheduler{
+	m := &Mix{
 		mix:       make(map[OpType]int),
 		totalKeys: totalKeys,
-		rng:       rand.New(rand.NewSource(seed)),
 	}
 
-	// Convert string keys to OpType and build cumulative weights
+	// Convert string keys to OpType and build cumulative weights, in a fixed
+	// order so that schedulers with the same seed pick the same operations
+	names := make([]string, 0, len(mix))
+	for opStr := range mix {
+		names = append(names, opStr)
+	}
+	sort.Strings(names)
+
 	var cumulative int
-	for opStr, pct := range mix {
+	for _, opStr := range names {
+		pct := mix[opStr]
 		if pct == 0 {
 			continue
 		}
 
 		op := OpType(opStr)
-		s.mix[op] = pct
-		s.ops = append(s.ops, op)
+		m.mix[op] = pct
+		m.ops = append(m.ops, op)
 		cumulative += pct
-		s.weights = append(s.weights, cumulative)
+		m.weights = append(m.weights, cumulative)
 	}
 
-	return s, nil
+	return m, nil
+}
+
+// Includes reports whether any of ops is part of the mix
+func (m *Mix) Includes(ops ...OpType) bool {
+	for _, op := range ops {
+		if m.mix[op] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Scheduler returns a scheduler of the mix seeded with seed
+func (m *Mix) Scheduler(seed int64) *Scheduler {
+	return &Scheduler{
+		Mix: m,
+		rng: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Scheduler picks operations and keys from a mix. It is not safe for
+// concurrent use; each worker has its own.
+type Scheduler struct {
+	*Mix
+	rng *rand.Rand
+}
+
+// NewScheduler creates a new operation scheduler
+func NewScheduler(mix map[string]int, totalKeys int, seed int64) (*Scheduler, error) {
+	m, err := NewMix(mix, totalKeys)
+	if err != nil {
+		return nil, err
+	}
+	return m.Scheduler(seed), nil
 }
 
 // Next returns the next operation to execute
 func (s *Scheduler) Next() OpType {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if len(s.ops) == 0 {
 		return OpGet // fallback
 	}
@@ -94,9 +170,6 @@ func (s *Scheduler) Next() OpType {
 
 // NextKey returns a random key from the keyspace
 func (s *Scheduler) NextKey() int {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	return s.rng.Intn(s.totalKeys)
 }
 
@@ -110,3 +183,23 @@ func ShouldVerify(verifyRate float64, rng *rand.Rand) bool {
 	}
 	return rng.Float64() < verifyRate
 }
+
+// RangeOffset picks the start of a range of length bytes within an object of
+// size bytes. "random" picks any valid offset; "aligned" picks a multiple of
+// length. Other policies start at zero.
+func RangeOffset(policy string, size, length int64, rng *rand.Rand) int64 {
+	if length <= 0 || length >= size {
+		return 0
+	}
+
+	switch policy {
+	case "random":
+		return rng.Int63n(size - length + 1)
+	case "aligned":
+		return rng.Int63n(size/length) * length
+	case "tail":
+		return size - length
+	default:
+		return 0
+	}
+}
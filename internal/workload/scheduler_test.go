@@ -2,6 +2,7 @@ package workload
 
 import (
 	"math/rand"
+	"sync/atomic"
 	"testing"
 )
 
@@ -26,6 +27,30 @@ func TestNewScheduler(t *testing.T) {
 	}
 }
 
+func TestSchedulerIncludes(t *testing.T) {
+	scheduler, err := NewScheduler(map[string]int{"put": 90, "overwrite": 10, "get_version": 0}, 1000, 42)
+	if err != nil {
+		t.Fatalf("NewScheduler() failed: %v", err)
+	}
+
+	tests := []struct {
+		ops  []OpType
+		want bool
+	}{
+		{[]OpType{OpOverwrite}, true},
+		{[]OpType{OpGetVersion}, false},
+		{[]OpType{OpGetVersion, OpPut}, true},
+		{[]OpType{OpDelete}, false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := scheduler.Includes(tt.ops...); got != tt.want {
+			t.Errorf("Includes(%v) = %v, want %v", tt.ops, got, tt.want)
+		}
+	}
+}
+
 func TestSchedulerNext(t *testing.T) {
 	mix := map[string]int{
 		"put": 50,
@@ -79,6 +104,70 @@ func TestSchedulerNextKey(t *testing.T) {
 	}
 }
 
+func TestSchedulerDeterministic(t *testing.T) {
+	mix := map[string]int{"put": 30, "get": 30, "delete": 20, "list": 10, "head": 10}
+
+	// Map iteration order differs between the schedulers; the picks must not
+	for run := 0; run < 5; run++ {
+		a, err := NewScheduler(mix, 1000, 7)
+		if err != nil {
+			t.Fatalf("NewScheduler() failed: %v", err)
+		}
+		b, err := NewScheduler(mix, 1000, 7)
+		if err != nil {
+			t.Fatalf("NewScheduler() failed: %v", err)
+		}
+
+		for i := 0; i < 200; i++ {
+			if opA, opB := a.Next(), b.Next(); opA != opB {
+				t.Fatalf("pick %d: Next() = %s and %s with the same seed", i, opA, opB)
+			}
+			if keyA, keyB := a.NextKey(), b.NextKey(); keyA != keyB {
+				t.Fatalf("pick %d: NextKey() = %d and %d with the same seed", i, keyA, keyB)
+			}
+		}
+	}
+}
+
+func TestMixSchedulersIndependent(t *testing.T) {
+	mix, err := NewMix(map[string]int{"put": 50, "get": 50}, 1000)
+	if err != nil {
+		t.Fatalf("NewMix() failed: %v", err)
+	}
+
+	// Draws from one scheduler must not advance another of the same mix
+	a, b := mix.Scheduler(3), mix.Scheduler(3)
+	for i := 0; i < 100; i++ {
+		a.Next()
+	}
+	ref := mix.Scheduler(3)
+	for i := 0; i < 100; i++ {
+		if got, want := b.NextKey(), ref.NextKey(); got != want {
+			t.Fatalf("pick %d: NextKey() = %d, want %d", i, got, want)
+		}
+	}
+}
+
+// BenchmarkSchedulerParallel picks operations and keys from a scheduler per
+// goroutine. Run with -cpu 1,2,4,8: as schedulers share nothing mutable,
+// throughput scales with the number of cores.
+func BenchmarkSchedulerParallel(b *testing.B) {
+	mix, err := NewMix(map[string]int{"put": 40, "get": 40, "delete": 10, "list": 10}, 100000)
+	if err != nil {
+		b.Fatalf("NewMix() failed: %v", err)
+	}
+
+	var workers int64
+	b.RunParallel(func(pb *testing.PB) {
+		worker := int(atomic.AddInt64(&workers, 1))
+		s := mix.Scheduler(DeriveSeed(1, worker, StreamOps))
+		for pb.Next() {
+			s.Next()
+			s.NextKey()
+		}
+	})
+}
+
 func TestShouldVerify(t *testing.T) {
 	rng := rand.New(rand.NewSource(42))
 
@@ -113,3 +202,45 @@ func TestShouldVerify(t *testing.T) {
 		})
 	}
 }
+
+func TestRangeOffset(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+
+	size := int64(10 * 1024)
+	length := int64(1024)
+
+	for i := 0; i < 1000; i++ {
+		offset := RangeOffset("random", size, length, rng)
+		if offset < 0 || offset+length > size {
+			t.Fatalf("random offset %d out of bounds", offset)
+		}
+
+		offset = RangeOffset("aligned", size, length, rng)
+		if offset%length != 0 || offset+length > size {
+			t.Fatalf("aligned offset %d not aligned or out of bounds", offset)
+		}
+	}
+
+	if got := RangeOffset("head", size, length, rng); got != 0 {
+		t.Errorf("head offset = %d, want 0", got)
+	}
+	if got := RangeOffset("tail", size, length, rng); got != size-length {
+		t.Errorf("tail offset = %d, want %d", got, size-length)
+	}
+	if got := RangeOffset("random", 100, 200, rng); got != 0 {
+		t.Errorf("offset for range longer than object = %d, want 0", got)
+	}
+}
+
+func TestOpTypeKnown(t *testing.T) {
+	for _, op := range Ops {
+		if !op.Known() {
+			t.Errorf("%s is not known", op)
+		}
+	}
+	for _, op := range []OpType{"", "PUT", "upload_part", "get-tagging"} {
+		if op.Known() {
+			t.Errorf("%q is known", op)
+		}
+	}
+}
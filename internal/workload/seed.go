@@ -0,0 +1,35 @@
+package workload
+
+import "hash/fnv"
+
+// Random streams each worker draws from. Every kind of random choice has
+// its own stream so that it repeats however many draws the others make.
+const (
+	StreamOps      = "ops"      // operation and key picks
+	StreamChoices  = "choices"  // verify sampling, range offsets and other picks
+	StreamSizes    = "sizes"    // object sizes
+	StreamRanges   = "ranges"   // range GET lengths
+	StreamClasses  = "classes"  // storage classes
+	StreamArrivals = "arrivals" // Poisson inter-arrival gaps
+	StreamVersions = "versions" // picks among tracked version chains
+)
+
+// DeriveSeed derives the seed of one stream of one worker from the master
+// seed of a run
+func DeriveSeed(master int64, worker int, stream string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(stream))
+
+	z := splitmix64(uint64(master))
+	z = splitmix64(z ^ uint64(worker))
+	z = splitmix64(z ^ h.Sum64())
+	return int64(z >> 1)
+}
+
+// splitmix64 scrambles x into a well-distributed 64-bit value
+func splitmix64(x uint64) uint64 {
+	z := x + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
@@ -0,0 +1,47 @@
+package workload
+
+import "testing"
+
+func TestDeriveSeed(t *testing.T) {
+	base := DeriveSeed(42, 0, StreamOps)
+	if again := DeriveSeed(42, 0, StreamOps); again != base {
+		t.Errorf("DeriveSeed() = %d, then %d for the same inputs", base, again)
+	}
+
+	tests := []struct {
+		name   string
+		master int64
+		worker int
+		stream string
+	}{
+		{"other master", 43, 0, StreamOps},
+		{"other worker", 42, 1, StreamOps},
+		{"other stream", 42, 0, StreamSizes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeriveSeed(tt.master, tt.worker, tt.stream); got == base {
+				t.Errorf("DeriveSeed() = %d, same as the base seed", got)
+			}
+		})
+	}
+}
+
+func TestDeriveSeedDistinct(t *testing.T) {
+	streams := []string{StreamOps, StreamChoices, StreamSizes, StreamRanges, StreamClasses, StreamArrivals, StreamVersions}
+
+	seen := make(map[int64]string)
+	for worker := 0; worker < 512; worker++ {
+		for _, stream := range streams {
+			seed := DeriveSeed(1, worker, stream)
+			if seed < 0 {
+				t.Fatalf("DeriveSeed(1, %d, %s) = %d, want non-negative", worker, stream, seed)
+			}
+			if prev, ok := seen[seed]; ok {
+				t.Fatalf("DeriveSeed(1, %d, %s) collides with %s", worker, stream, prev)
+			}
+			seen[seed] = stream
+		}
+	}
+}
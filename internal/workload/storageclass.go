@@ -0,0 +1,127 @@
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// StorageClassMix picks the storage class of new objects by weight. RGW
+// maps storage classes to placement targets, so a mix spreads one run's
+// objects across pools. It is not safe for concurrent use; each worker
+// picks from its own copy made with WithSeed.
+type StorageClassMix struct {
+	classes []string
+	weights []int // cumulative weights
+	rng     *rand.Rand
+}
+
+// ParseStorageClassMix parses a single storage class ("STANDARD") or a
+// weighted mix ("STANDARD=70,COLD=30"). An empty spec returns nil, which
+// leaves objects in the bucket's default class.
+func ParseStorageClassMix(spec string, seed int64) (*StorageClassMix, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	m := &StorageClassMix{rng: rand.New(rand.NewSource(seed))}
+	seen := make(map[string]bool)
+	cumulative := 0
+
+	for _, part := range strings.Split(spec, ",") {
+		class, weightStr, hasWeight := strings.Cut(strings.TrimSpace(part), "=")
+		class = strings.TrimSpace(class)
+		if class == "" {
+			return nil, fmt.Errorf("invalid storage class %q", part)
+		}
+		if seen[class] {
+			return nil, fmt.Errorf("duplicate storage class %q", class)
+		}
+		seen[class] = true
+
+		weight := 1
+		if hasWeight {
+			w, err := strconv.Atoi(strings.TrimSpace(weightStr))
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight for storage class %q", class)
+			}
+			weight = w
+		}
+		if weight == 0 {
+			continue
+		}
+
+		cumulative += weight
+		m.classes = append(m.classes, class)
+		m.weights = append(m.weights, cumulative)
+	}
+
+	if len(m.classes) == 0 {
+		return nil, fmt.Errorf("storage class weights sum to zero")
+	}
+
+	return m, nil
+}
+
+// WithSeed returns a copy of the mix that picks with its own generator
+func (m *StorageClassMix) WithSeed(seed int64) *StorageClassMix {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	c.rng = rand.New(rand.NewSource(seed))
+	return &c
+}
+
+// Classes returns the storage classes with a non-zero weight
+func (m *StorageClassMix) Classes() []string {
+	if m == nil {
+		return nil
+	}
+	return m.classes
+}
+
+// Next returns the storage class for a new object, or "" when no mix is
+// configured
+func (m *StorageClassMix) Next() string {
+	class, _ := m.NextExcept("")
+	return class
+}
+
+// NextExcept picks a storage class other than current, weighted among the
+// remaining classes. It returns false if there is none.
+func (m *StorageClassMix) NextExcept(current string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+
+	total, prev := 0, 0
+	for i, class := range m.classes {
+		if class != current {
+			total += m.weights[i] - prev
+		}
+		prev = m.weights[i]
+	}
+	if total == 0 {
+		return "", false
+	}
+
+	r := m.rng.Intn(total)
+
+	prev = 0
+	for i, class := range m.classes {
+		weight := m.weights[i] - prev
+		prev = m.weights[i]
+		if class == current {
+			continue
+		}
+		if r < weight {
+			return class, true
+		}
+		r -= weight
+	}
+
+	return "", false
+}
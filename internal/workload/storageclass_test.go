@@ -0,0 +1,107 @@
+package workload
+
+import "testing"
+
+func TestParseStorageClassMix(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"single", "STANDARD", []string{"STANDARD"}, false},
+		{"weighted", "STANDARD=70, COLD=30", []string{"STANDARD", "COLD"}, false},
+		{"zero weight dropped", "STANDARD=1,COLD=0", []string{"STANDARD"}, false},
+		{"all zero", "STANDARD=0", nil, true},
+		{"bad weight", "STANDARD=x", nil, true},
+		{"negative weight", "STANDARD=-1", nil, true},
+		{"duplicate", "COLD,COLD", nil, true},
+		{"empty class", "STANDARD,,COLD", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mix, err := ParseStorageClassMix(tt.spec, 42)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStorageClassMix() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got := mix.Classes()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Classes() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Classes()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStorageClassMixNext(t *testing.T) {
+	var none *StorageClassMix
+	if got := none.Next(); got != "" {
+		t.Errorf("nil mix Next() = %q, want empty", got)
+	}
+
+	mix, err := ParseStorageClassMix("STANDARD=80,COLD=20", 42)
+	if err != nil {
+		t.Fatalf("ParseStorageClassMix() failed: %v", err)
+	}
+
+	counts := make(map[string]int)
+	samples := 10000
+	for i := 0; i < samples; i++ {
+		counts[mix.Next()]++
+	}
+
+	coldPct := float64(counts["COLD"]) / float64(samples) * 100
+	if coldPct < 15 || coldPct > 25 {
+		t.Errorf("COLD percentage = %.1f%%, want ~20%%", coldPct)
+	}
+	if counts["STANDARD"]+counts["COLD"] != samples {
+		t.Errorf("Next() returned unexpected classes: %v", counts)
+	}
+}
+
+func TestStorageClassMixNextExcept(t *testing.T) {
+	mix, err := ParseStorageClassMix("STANDARD=50,COLD=30,ARCHIVE=20", 42)
+	if err != nil {
+		t.Fatalf("ParseStorageClassMix() failed: %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		class, ok := mix.NextExcept("STANDARD")
+		if !ok || class == "STANDARD" {
+			t.Fatalf("NextExcept(STANDARD) = %q, %v", class, ok)
+		}
+	}
+
+	single, _ := ParseStorageClassMix("COLD", 42)
+	if class, ok := single.NextExcept("COLD"); ok {
+		t.Errorf("NextExcept(COLD) on single class = %q, want none", class)
+	}
+	if class, ok := single.NextExcept(""); !ok || class != "COLD" {
+		t.Errorf("NextExcept(\"\") on single class = %q, %v", class, ok)
+	}
+}
+
+func TestStorageClassMixWithSeed(t *testing.T) {
+	var none *StorageClassMix
+	if none.WithSeed(1) != nil {
+		t.Error("nil mix WithSeed() is not nil")
+	}
+
+	mix, err := ParseStorageClassMix("STANDARD=50,COLD=30,ARCHIVE=20", 0)
+	if err != nil {
+		t.Fatalf("ParseStorageClassMix() failed: %v", err)
+	}
+
+	a, b := mix.WithSeed(7), mix.WithSeed(7)
+	for i := 0; i < 100; i++ {
+		if classA, classB := a.Next(), b.Next(); classA != classB {
+			t.Fatalf("pick %d: Next() = %q and %q with the same seed", i, classA, classB)
+		}
+	}
+}